@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// floatingAliases moves the major and major.minor alias tags of every
+	// created version, such as app/prod/v1 and app/prod/v1.4
+	floatingAliases bool
+	// aliasChannels are the release channels configured with aliases, set
+	// when the configuration is loaded
+	aliasChannels map[string]bool
+	// stableChannel is the release channel whose latest release the stable
+	// pointer of every module follows, set from stable_channel
+	stableChannel string
+)
+
+// Function to name the stable pointer of a module, such as app/stable
+func stableTag(module string) string {
+	if !layout.hasModule {
+		return "stable"
+	}
+	return module + "/stable"
+}
+
+// Function to name the floating aliases of a version: one for its major
+// version and one for its minor version
+func aliasTags(module, channel string, v Version) []string {
+	return []string{
+		layout.format(module, channel, fmt.Sprint(v.Major)),
+		layout.format(module, channel, fmt.Sprintf("%d.%d", v.Major, v.Minor)),
+	}
+}
+
+// Function to tell whether a tag is a floating alias, a version tag with
+// only the major or major.minor of its version
+func isAliasTag(tag string) bool {
+	if _, _, _, ok := parseTag(tag); ok {
+		return false
+	}
+	for _, suffix := range []string{".0", ".0.0"} {
+		if _, _, v, ok := parseTag(tag + suffix); ok && v.Prerelease == "" && v.Build == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Function to move the floating aliases of created versions to their
+// commit, on channels with aliases or with --aliases. An alias only moves to
+// the highest version it covers, so a 1.3.5 hotfix moves v1.3 but leaves v1
+// on 1.4.3. Prereleases, counters and the versions of modules using calendar
+// versions have no aliases. Releases on the stable channel also move the
+// stable pointer of their module when they are its highest version there.
+func moveAliases(results []tagResult) {
+	for i, r := range results {
+		floating := (floatingAliases || aliasChannels[r.Channel]) && schemeOf(r.Module) != schemeCalver
+		stable := r.Channel == stableChannel
+		if !floating && !stable || r.New.Prerelease != "" || r.New.Counter {
+			continue
+		}
+		versions, err := channelVersions(r.Module, r.Channel)
+		if err != nil {
+			log.Warn().Err(err).Str("tag", r.Tag).Msg("unable to move aliases")
+			continue
+		}
+		var aliases []string
+		if floating {
+			names := aliasTags(r.Module, r.Channel, r.New)
+			sameMajor := slices.DeleteFunc(slices.Clone(versions), func(v Version) bool { return v.Major != r.New.Major })
+			if isNewest(sameMajor, r.New) {
+				aliases = append(aliases, names[0])
+			}
+			sameMinor := slices.DeleteFunc(sameMajor, func(v Version) bool { return v.Minor != r.New.Minor })
+			if isNewest(sameMinor, r.New) {
+				aliases = append(aliases, names[1])
+			}
+		}
+		if stable && isNewest(versions, r.New) {
+			aliases = append(aliases, stableTag(r.Module))
+		}
+		for _, alias := range aliases {
+			// Aliases are lightweight tags, replaced in one step with --force
+			if _, err := gitOutput("tag", "--force", alias, r.Commit); err != nil {
+				log.Error().Err(err).Str("alias", alias).Msg("unable to move alias")
+				continue
+			}
+			results[i].Aliases = append(results[i].Aliases, alias)
+			log.Info().Str("alias", alias).Str("tag", r.Tag).Msg("Alias moved")
+		}
+	}
+}
+
+// Function to tell whether a version is the highest release among versions
+func isNewest(versions []Version, v Version) bool {
+	for _, other := range versions {
+		if other.Prerelease == "" && compareVersions(other, v) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Function to force-push the aliases of pushed tags, which moved since they
+// were last pushed, reporting whether every one was pushed
+func pushAliases(target pushTarget, aliases []string) bool {
+	if len(aliases) == 0 {
+		return true
+	}
+	var refspecs []string
+	for _, alias := range aliases {
+		refspecs = append(refspecs, "+refs/tags/"+alias+":refs/tags/"+alias)
+	}
+	if err := pushRefspec(target, refspecs...); err != nil {
+		log.Error().Err(err).Str("remote", target.Remote).Msg("Pushing aliases failed")
+		return false
+	}
+	log.Info().Str("remote", target.Remote).Str("aliases", strings.Join(aliases, ", ")).Msg("Aliases pushed")
+	return true
+}
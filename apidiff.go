@@ -0,0 +1,476 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Modes of the Go API check, set with api_check or --api-check
+const (
+	apiCheckOff    = "off"
+	apiCheckWarn   = "warn"
+	apiCheckRefuse = "refuse"
+)
+
+// apiCheckMode overrides the api_check setting for a run
+var apiCheckMode string
+
+// goAPI is the exported API of the packages of a Go module: for every
+// package directory, a description of each exported declaration keyed by
+// its kind and name, such as "func New" or "field Client.Timeout"
+type goAPI map[string]map[string]string
+
+// apiChange is a difference between two versions of the API of a package
+type apiChange struct {
+	Package  string
+	Key      string
+	Detail   string
+	Breaking bool
+}
+
+func (c apiChange) String() string {
+	return c.Package + ": " + c.Key + " " + c.Detail
+}
+
+// Function to check an api_check mode
+func validateAPICheck(mode string) error {
+	switch mode {
+	case "", apiCheckOff, apiCheckWarn, apiCheckRefuse:
+		return nil
+	}
+	return fmt.Errorf("unknown api check %q, expected %s, %s or %s", mode, apiCheckOff, apiCheckWarn, apiCheckRefuse)
+}
+
+// Function to tell whether a file of a commit is part of the public API of
+// a Go module: non-test Go files outside internal, testdata and vendor
+// directories
+func isAPIFile(name string) bool {
+	if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+		return false
+	}
+	for _, dir := range strings.Split(path.Dir(name), "/") {
+		if dir == "internal" || dir == "testdata" || dir == "vendor" || strings.HasPrefix(dir, ".") || strings.HasPrefix(dir, "_") {
+			return false
+		}
+	}
+	return true
+}
+
+// Function to list the files of a commit within the given paths
+func listCommitFiles(commit string, paths []string) ([]string, error) {
+	args := []string{"ls-tree", "-r", "--name-only", commit}
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+	out, err := gitOutput(args...)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// Function to tell whether the paths of a module hold a Go module at a
+// commit
+func isGoModule(commit string, paths []string) bool {
+	files, err := listCommitFiles(commit, paths)
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(files, func(name string) bool { return path.Base(name) == "go.mod" })
+}
+
+// Function to print a type expression the same way whatever its layout
+func exprString(fset *token.FileSet, expr ast.Expr) string {
+	if expr == nil {
+		return ""
+	}
+	var b bytes.Buffer
+	printer.Fprint(&b, fset, expr)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// Function to describe the types of a parameter or result list, leaving out
+// the names, which callers do not depend on
+func fieldTypes(fset *token.FileSet, fields *ast.FieldList) string {
+	if fields == nil {
+		return "()"
+	}
+	var types []string
+	for _, f := range fields.List {
+		n := max(len(f.Names), 1)
+		for i := 0; i < n; i++ {
+			types = append(types, exprString(fset, f.Type))
+		}
+	}
+	return "(" + strings.Join(types, ", ") + ")"
+}
+
+// Function to describe a function signature by its parameter and result
+// types
+func signature(fset *token.FileSet, fn *ast.FuncType) string {
+	sig := fieldTypes(fset, fn.Params)
+	if fn.Results != nil {
+		sig += " " + fieldTypes(fset, fn.Results)
+	}
+	if fn.TypeParams != nil {
+		sig = "[" + strings.Trim(fieldTypes(fset, fn.TypeParams), "()") + "]" + sig
+	}
+	return sig
+}
+
+// Function to name the type a method is declared on, without pointer or
+// type parameters
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// Function to record the exported declarations of a Go file
+func collectAPI(fset *token.FileSet, file *ast.File, api map[string]string) {
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() {
+				continue
+			}
+			if d.Recv == nil {
+				api["func "+d.Name.Name] = signature(fset, d.Type)
+				continue
+			}
+			recv := d.Recv.List[0].Type
+			if name := receiverName(recv); ast.IsExported(name) {
+				_, pointer := recv.(*ast.StarExpr)
+				sig := signature(fset, d.Type)
+				if pointer {
+					sig = "pointer receiver " + sig
+				}
+				api["method "+name+"."+d.Name.Name] = sig
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.IsExported() {
+						collectType(fset, s, api)
+					}
+				case *ast.ValueSpec:
+					kind := "var"
+					if d.Tok == token.CONST {
+						kind = "const"
+					}
+					for _, name := range s.Names {
+						if name.IsExported() {
+							api[kind+" "+name.Name] = exprString(fset, s.Type)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// Function to record an exported type, with the exported fields of structs
+// and the methods of interfaces as declarations of their own
+func collectType(fset *token.FileSet, s *ast.TypeSpec, api map[string]string) {
+	name := s.Name.Name
+	prefix := ""
+	if s.TypeParams != nil {
+		prefix = "[" + strings.Trim(fieldTypes(fset, s.TypeParams), "()") + "] "
+	}
+	if s.Assign.IsValid() {
+		api["type "+name] = prefix + "= " + exprString(fset, s.Type)
+		return
+	}
+	switch t := s.Type.(type) {
+	case *ast.StructType:
+		api["type "+name] = prefix + "struct"
+		for _, f := range t.Fields.List {
+			names := f.Names
+			if len(names) == 0 {
+				// Embedded fields are named after their type
+				names = []*ast.Ident{ast.NewIdent(receiverName(f.Type))}
+			}
+			for _, n := range names {
+				if n.IsExported() {
+					api["field "+name+"."+n.Name] = exprString(fset, f.Type)
+				}
+			}
+		}
+	case *ast.InterfaceType:
+		api["type "+name] = prefix + "interface"
+		for _, m := range t.Methods.List {
+			if fn, ok := m.Type.(*ast.FuncType); ok {
+				for _, n := range m.Names {
+					api["interface "+name+"."+n.Name] = signature(fset, fn)
+				}
+				continue
+			}
+			api["interface "+name+".embeds "+exprString(fset, m.Type)] = ""
+		}
+	default:
+		api["type "+name] = prefix + exprString(fset, s.Type)
+	}
+}
+
+// Function to read the exported API of the Go packages under the given
+// paths at a commit. Main packages cannot be imported and are left out.
+func readGoAPI(commit string, paths []string) (goAPI, error) {
+	files, err := listCommitFiles(commit, paths)
+	if err != nil {
+		return nil, err
+	}
+	files = slices.DeleteFunc(files, func(name string) bool { return !isAPIFile(name) })
+	objects := make([]string, len(files))
+	for i, name := range files {
+		objects[i] = commit + ":" + name
+	}
+	sources, err := readObjects(objects)
+	if err != nil {
+		return nil, err
+	}
+	api := make(goAPI)
+	fset := token.NewFileSet()
+	for i, name := range files {
+		file, err := parser.ParseFile(fset, name, sources[i], parser.SkipObjectResolution)
+		if err != nil {
+			log.Debug().Err(err).Str("file", name).Msg("skipping unparsable Go file")
+			continue
+		}
+		if file.Name.Name == "main" {
+			continue
+		}
+		dir := path.Dir(name)
+		if api[dir] == nil {
+			api[dir] = make(map[string]string)
+		}
+		collectAPI(fset, file, api[dir])
+	}
+	return api, nil
+}
+
+// Function to compare two versions of an API. Removed and changed
+// declarations break callers, and so do methods added to an interface,
+// which existing implementations lack; anything else added is compatible.
+func compareAPI(old, new goAPI) []apiChange {
+	var changes []apiChange
+	for pkg, decls := range old {
+		now, ok := new[pkg]
+		if !ok {
+			changes = append(changes, apiChange{Package: pkg, Key: "package", Detail: "removed", Breaking: true})
+			continue
+		}
+		for key, was := range decls {
+			is, ok := now[key]
+			switch {
+			case !ok:
+				changes = append(changes, apiChange{Package: pkg, Key: key, Detail: "removed", Breaking: true})
+			case was != is && was != "" && is != "":
+				changes = append(changes, apiChange{Package: pkg, Key: key, Detail: "changed from " + was + " to " + is, Breaking: true})
+			}
+		}
+	}
+	for pkg, decls := range new {
+		for key := range decls {
+			if _, ok := old[pkg][key]; ok {
+				continue
+			}
+			change := apiChange{Package: pkg, Key: key, Detail: "added"}
+			if kind, name, ok := strings.Cut(key, " "); ok && kind == "interface" {
+				iface, _, _ := strings.Cut(name, ".")
+				change.Breaking = strings.HasSuffix(old[pkg]["type "+iface], "interface")
+			}
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Package != changes[j].Package {
+			return changes[i].Package < changes[j].Package
+		}
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// Function to compare the Go API of a module between two commits, reporting
+// false when the module is not a Go module
+func diffGoAPI(config *Config, module, from, to string) ([]apiChange, bool, error) {
+	paths := modulePaths(config, module, to)
+	if !isGoModule(to, paths) {
+		return nil, false, nil
+	}
+	old, err := readGoAPI(from, paths)
+	if err != nil {
+		return nil, true, err
+	}
+	current, err := readGoAPI(to, paths)
+	if err != nil {
+		return nil, true, err
+	}
+	return compareAPI(old, current), true, nil
+}
+
+// Function to keep the breaking changes of an API diff
+func breakingChanges(changes []apiChange) []apiChange {
+	return slices.DeleteFunc(slices.Clone(changes), func(c apiChange) bool { return !c.Breaking })
+}
+
+// Function to tell the bump a breaking API change needs: major, or minor
+// before 1.0.0, when Go promises no compatibility
+func breakingBump(current Version) string {
+	if current.Major == 0 {
+		return "minor"
+	}
+	return "major"
+}
+
+// Function to tell whether a bump is at least as large as another
+func bumpCovers(part, needed string) bool {
+	rank := map[string]int{"build": 0, "patch": 1, "minor": 2, "major": 3}
+	return rank[part] >= rank[needed]
+}
+
+// Function to compare the Go API of every target with its latest version
+// before tagging. With --bump auto a breaking change raises the bump;
+// otherwise a bump too small for it is reported, and refused in refuse mode.
+func reviewGoAPI(idx *tagIndex, config *Config, targets, channels []string, commit string) error {
+	mode := config.APICheck
+	if apiCheckMode != "" {
+		mode = apiCheckMode
+	}
+	auto := bumpPart == bumpAuto && explicitVersion == nil
+	if (mode == "" || mode == apiCheckOff) && !auto {
+		return nil
+	}
+	versioned := slices.DeleteFunc(slices.Clone(channels), isCounterChannel)
+	for _, m := range targets {
+		if schemeOf(m) == schemeCalver {
+			continue
+		}
+		current := parseCurrentVersion(idx, m, versioned)
+		base := baseTag(m, versioned, current)
+		if base == "" {
+			// Nothing released yet, so there is no API to break
+			continue
+		}
+		changes, isGo, err := diffGoAPI(config, m, "refs/tags/"+base, commit)
+		if err != nil {
+			return fmt.Errorf("%s: comparing the Go API with %s: %w", m, base, err)
+		}
+		breaking := breakingChanges(changes)
+		if !isGo || len(breaking) == 0 {
+			continue
+		}
+		needed := breakingBump(current)
+		part := bumpPartOf(m)
+		if explicitVersion != nil {
+			part = "patch"
+			switch {
+			case explicitVersion.Major > current.Major:
+				part = "major"
+			case explicitVersion.Major == current.Major && explicitVersion.Minor > current.Minor:
+				part = "minor"
+			}
+		}
+		if bumpCovers(part, needed) {
+			continue
+		}
+		for _, c := range breaking {
+			log.Warn().Str("module", m).Str("since", base).Msg("Breaking API change: " + c.String())
+		}
+		if auto {
+			autoParts[m] = needed
+			log.Info().Str("module", m).Int("breaking", len(breaking)).Str("bump", needed).Msg("Bump raised for breaking API changes")
+			continue
+		}
+		if mode == apiCheckRefuse {
+			return fmt.Errorf("%s has %d breaking API changes since %s, which need a %s bump, not %s", m, len(breaking), base, needed, part)
+		}
+		log.Warn().Str("module", m).Int("breaking", len(breaking)).Str("bump", part).Msgf("breaking API changes usually need a %s bump", needed)
+	}
+	return nil
+}
+
+// Function to handle `version api-diff`, printing the changes to the Go API
+// of a module since a tag
+func runAPIDiff(args []string) int {
+	fs := flag.NewFlagSet("api-diff", flag.ExitOnError)
+	fs.StringVar(&moduleName, "m", "", "module name")
+	fs.StringVar(&releaseChannel, "r", "", "release channel of the tag to compare from, or a comma separated list")
+	fromArg := fs.String("from", "", "tag or commit to compare from (default the latest version on the channels)")
+	toArg := fs.String("to", "HEAD", "commit to compare to")
+	all := fs.Bool("all", false, "also list the compatible additions")
+	fs.Parse(args)
+	moduleName, releaseChannel = impliedNames(moduleName, releaseChannel)
+
+	if moduleName == "" || (*fromArg == "" && releaseChannel == "") {
+		log.Error().Msg("-m is required, with -r or --from")
+		return 2
+	}
+	idx, err := scanTagIndex()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	config, err := loadConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read configuration")
+		return 1
+	}
+	from := *fromArg
+	if from == "" {
+		version, channel, found := idx.latestTag(moduleName, strings.Split(releaseChannel, ","))
+		if !found {
+			log.Error().Str("module", moduleName).Msg("no version to compare from")
+			return 1
+		}
+		from = formatTag(moduleName, channel, version)
+	}
+	to, err := resolveCommit(*toArg)
+	if err != nil {
+		log.Error().Err(err).Str("commit", *toArg).Msg("unable to resolve commit")
+		return 1
+	}
+	changes, isGo, err := diffGoAPI(config, moduleName, from, to)
+	if err != nil {
+		log.Error().Err(err).Msg("unable to compare the Go API")
+		return 1
+	}
+	if !isGo {
+		log.Error().Str("module", moduleName).Msg("no go.mod in the paths of the module")
+		return 1
+	}
+	breaking := 0
+	for _, c := range changes {
+		if c.Breaking {
+			breaking++
+			fmt.Println("breaking  " + c.String())
+		} else if *all {
+			fmt.Println("added     " + c.String())
+		}
+	}
+	log.Info().Str("from", from).Int("breaking", breaking).Int("added", len(changes)-breaking).Msg("Go API compared")
+	if breaking > 0 {
+		return 1
+	}
+	return 0
+}
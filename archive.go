@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Function to report whether a module has been archived
+func isArchived(config *Config, module string) bool {
+	return config.Modules[module].Archived
+}
+
+// Function to drop archived modules from a list of names offered to pick from
+func activeModules(config *Config, modules []string) []string {
+	var active []string
+	for _, module := range modules {
+		if !isArchived(config, module) {
+			active = append(active, module)
+		}
+	}
+	return active
+}
+
+// Function to drop archived modules that were only matched by a glob from the
+// targets of a run, leaving modules that were named explicitly in place
+func dropArchived(config *Config, moduleArg string, targets []string) []string {
+	named := strings.Split(moduleArg, ",")
+	var kept []string
+	for _, module := range targets {
+		if isArchived(config, module) && !slices.Contains(named, module) {
+			log.Info().Str("module", module).Msg("Skipping archived module")
+			continue
+		}
+		kept = append(kept, module)
+	}
+	return kept
+}
+
+// Function to refuse tagging an archived module
+func checkArchived(config *Config, module string) error {
+	if isArchived(config, module) {
+		return fmt.Errorf("module %s is archived, run 'version unarchive %s' to tag it again", module, module)
+	}
+	return nil
+}
+
+// Function to handle `version archive` and `version unarchive`, which mark
+// modules as retired or active in the configuration
+func setArchived(name string, archived bool, args []string) int {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: version %s <module>...\n", name)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read configuration")
+		return 1
+	}
+	for _, module := range fs.Args() {
+		if err := validateName("module", module); err != nil {
+			log.Error().Err(err).Msg("invalid module name entered")
+			return 1
+		}
+		if isArchived(config, module) == archived {
+			log.Info().Str("module", module).Bool("archived", archived).Msg("Module unchanged")
+			continue
+		}
+		if err := setConfigValue([]string{"modules", module, "archived"}, archived); err != nil {
+			log.Error().Err(err).Str("module", module).Msg("unable to update configuration")
+			return 1
+		}
+		log.Info().Str("module", module).Bool("archived", archived).Msg("Module updated in " + configFileName)
+	}
+	return 0
+}
+
+func runArchive(args []string) int {
+	return setArchived("archive", true, args)
+}
+
+func runUnarchive(args []string) int {
+	return setArchived("unarchive", false, args)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Function to parse the moment a question is asked about: a date meaning the
+// end of that day in local time, or an RFC 3339 timestamp
+func parseMoment(s string) (time.Time, error) {
+	if day, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	moment, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or an RFC 3339 timestamp", s)
+	}
+	return moment, nil
+}
+
+// Function to handle `version at`, printing the version that was the latest
+// of a module on a release channel at a given date, going by the creation
+// date of the tags
+func runAt(args []string) int {
+	fs := flag.NewFlagSet("at", flag.ExitOnError)
+	fs.StringVar(&moduleName, "m", "", "module name")
+	fs.StringVar(&releaseChannel, "r", "", "release channel")
+	date := fs.String("date", "", "date (YYYY-MM-DD, up to the end of that day) or RFC 3339 timestamp")
+	full := fs.Bool("full", false, "print the full tag name instead of the version")
+	fs.Parse(args)
+	moduleName, releaseChannel = impliedNames(moduleName, releaseChannel)
+
+	if moduleName == "" || releaseChannel == "" || *date == "" {
+		log.Error().Msg("-m, -r and --date are required")
+		return 2
+	}
+	moment, err := parseMoment(*date)
+	if err != nil {
+		log.Error().Err(err).Msg("invalid --date")
+		return 2
+	}
+
+	entries, err := readTagEntries(func(module, channel string) bool {
+		return module == moduleName && channel == releaseChannel
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	var latest *tagEntry
+	for i, e := range entries {
+		if e.Date.After(moment) {
+			continue
+		}
+		if latest == nil || compareVersions(latest.Version, e.Version) < 0 {
+			latest = &entries[i]
+		}
+	}
+	if latest == nil {
+		log.Error().Str("module", moduleName).Str("channel", releaseChannel).Str("date", moment.Format(time.RFC3339)).Msg("no version tagged by that date")
+		return 1
+	}
+
+	log.Info().Str("tag", latest.Tag).Str("created", latest.Date.Format(time.RFC3339)).Str("commit", latest.Commit).Msg("Latest release at that date")
+	if *full {
+		fmt.Println(latest.Tag)
+	} else {
+		fmt.Println(latest.Version)
+	}
+	return 0
+}
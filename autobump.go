@@ -0,0 +1,151 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// bumpAuto picks the bump of every module from the conventional commits
+// since its latest version
+const bumpAuto = "auto"
+
+// breakingFooter matches the BREAKING CHANGE footer of a conventional commit
+var breakingFooter = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE:`)
+
+// autoParts are the bumps chosen for each module with --bump auto
+var autoParts map[string]string
+
+// Function to tell the bump of a module: the one chosen from its commits
+// with --bump auto, else the one selected on the command line
+func bumpPartOf(module string) string {
+	if bumpPart != bumpAuto {
+		return bumpPart
+	}
+	if part, ok := autoParts[module]; ok {
+		return part
+	}
+	return "patch"
+}
+
+// conventionalCommit is a commit read for its conventional commit type
+type conventionalCommit struct {
+	Hash     string
+	Subject  string
+	Type     string
+	Breaking bool
+}
+
+// Function to read the commits reachable from to but not from, limited to
+// the given paths when there are any, with their conventional commit type
+// and whether they break compatibility, marked by ! or a BREAKING CHANGE
+// footer
+func readConventionalCommits(from, to string, paths []string) ([]conventionalCommit, error) {
+	args := []string{"log", "--no-merges", "--format=%H%x1f%s%x1f%b%x1e"}
+	if from == "" {
+		args = append(args, to)
+	} else {
+		args = append(args, from+".."+to)
+	}
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+	out, err := gitOutput(args...)
+	if err != nil {
+		return nil, err
+	}
+	var commits []conventionalCommit
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.SplitN(strings.TrimLeft(record, "\n"), "\x1f", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		c := conventionalCommit{Hash: fields[0], Subject: fields[1]}
+		if match := conventionalType.FindStringSubmatch(c.Subject); match != nil {
+			c.Type = strings.ToLower(match[1])
+			c.Breaking = strings.HasSuffix(match[0], "!:")
+		}
+		if breakingFooter.MatchString(fields[2]) {
+			c.Breaking = true
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
+
+// Function to find the tag of the version a module is bumped from, among
+// the given channels
+func baseTag(module string, channels []string, version Version) string {
+	for _, channel := range channels {
+		tag := formatTag(module, channel, version)
+		if _, err := gitOutput("rev-parse", "--verify", "--quiet", "refs/tags/"+tag); err == nil {
+			return tag
+		}
+	}
+	return ""
+}
+
+// Function to settle the bump of every target: chosen from its commits with
+// --bump auto, then checked against the changes to its Go API
+func resolveBumps(idx *tagIndex, config *Config, targets, channels []string, commit string) error {
+	if err := resolveAutoBumps(idx, config, targets, channels, commit); err != nil {
+		return err
+	}
+	return reviewGoAPI(idx, config, targets, channels, commit)
+}
+
+// Function to choose the bump of every target from the conventional commits
+// since its latest version, logging the reasoning: a breaking change bumps
+// the major version, a feature the minor version, anything else the patch
+func resolveAutoBumps(idx *tagIndex, config *Config, targets, channels []string, commit string) error {
+	autoParts = make(map[string]string)
+	if bumpPart != bumpAuto || explicitVersion != nil {
+		return nil
+	}
+	versioned := slices.DeleteFunc(slices.Clone(channels), isCounterChannel)
+	for _, m := range targets {
+		if len(versioned) == 0 || schemeOf(m) == schemeCalver {
+			// Counters and calendar versions only ever take a patch bump
+			autoParts[m] = "patch"
+			continue
+		}
+		current := parseCurrentVersion(idx, m, versioned)
+		since := baseTag(m, versioned, current)
+		commits, err := readConventionalCommits(since, commit, config.Modules[m].Paths)
+		if err != nil {
+			return err
+		}
+
+		part := "patch"
+		var breaking, features, fixes int
+		for _, c := range commits {
+			switch {
+			case c.Breaking:
+				breaking++
+				part = "major"
+			case c.Type == "feat":
+				features++
+				if part == "patch" {
+					part = "minor"
+				}
+			case c.Type == "fix" || c.Type == "perf":
+				fixes++
+			}
+		}
+		for _, c := range commits {
+			if part == "major" && c.Breaking || part == "minor" && c.Type == "feat" {
+				log.Info().Str("module", m).Str("commit", c.Hash[:min(7, len(c.Hash))]).Str("bump", part).Msg(c.Subject)
+			}
+		}
+		if since == "" {
+			since = "the first commit"
+		}
+		log.Info().Str("module", m).Str("since", since).Int("commits", len(commits)).
+			Int("breaking", breaking).Int("features", features).Int("fixes", fixes).
+			Str("bump", part).Msg("Bump chosen from conventional commits")
+		autoParts[m] = part
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const backupFormatVersion = 1
+
+// backupFile is the document written by `version backup`
+type backupFile struct {
+	Version int         `json:"version"`
+	Tags    []backupTag `json:"tags"`
+}
+
+// backupTag records everything needed to recreate a tag exactly. Annotated
+// tags keep their raw object so the signature survives a restore unchanged.
+type backupTag struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Object    string `json:"object"`
+	Commit    string `json:"commit"`
+	Message   string `json:"message,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Raw       string `json:"raw,omitempty"`
+	// TaggedRaw is the raw tag object an annotated tag points at when it
+	// tags another tag, as yank markers and tombstones of annotated tags
+	// do. Once a tag is deleted only its tombstone keeps that object.
+	TaggedRaw string `json:"tagged_raw,omitempty"`
+}
+
+// Function to tell whether a backed up tag is a yank marker or tombstone,
+// which are restored after the tags they point at
+func (t backupTag) isMarker() bool {
+	return isYankMarker(t.Name) || isTombstone(t.Name)
+}
+
+// Function to handle `version backup`
+func runBackup(args []string) int {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	output := fs.String("o", "version-tags.json", "file to write the backup to, - for stdout")
+	modulePattern := fs.String("m", "*", "glob of modules to back up")
+	channelPattern := fs.String("r", "*", "glob of release channels to back up")
+	fs.Parse(args)
+
+	tags, err := collectBackupTags(*modulePattern, *channelPattern)
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+
+	data, err := json.MarshalIndent(backupFile{Version: backupFormatVersion, Tags: tags}, "", "  ")
+	if err != nil {
+		log.Error().Err(err).Msg("unable to encode backup")
+		return 1
+	}
+	data = append(data, '\n')
+	if *output == "-" {
+		os.Stdout.Write(data)
+	} else if err := writeFile(*output, data, 0o644); err != nil {
+		log.Error().Err(err).Str("file", *output).Msg("unable to write backup")
+		return 1
+	}
+
+	log.Info().Int("tags", len(tags)).Str("file", *output).Msg("Tags backed up")
+	return 0
+}
+
+// Function to handle `version restore`
+func runRestore(args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	input := fs.String("f", "version-tags.json", "backup file to restore from, - for stdin")
+	force := fs.Bool("force", false, "replace tags that already exist with a different target")
+	fs.Parse(args)
+
+	var data []byte
+	var err error
+	if *input == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(*input)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("file", *input).Msg("unable to read backup")
+		return 1
+	}
+
+	var backup backupFile
+	if err := json.Unmarshal(data, &backup); err != nil {
+		log.Error().Err(err).Str("file", *input).Msg("invalid backup file")
+		return 1
+	}
+	if backup.Version != backupFormatVersion {
+		log.Error().Int("version", backup.Version).Msg("unsupported backup file version")
+		return 1
+	}
+
+	// Markers point at the tags they mark, which must be restored first
+	var tags, markers []backupTag
+	tombstones := make(map[string]bool)
+	for _, tag := range backup.Tags {
+		if !tag.isMarker() {
+			tags = append(tags, tag)
+			continue
+		}
+		markers = append(markers, tag)
+		if isTombstone(tag.Name) {
+			tombstones[strings.TrimPrefix(tag.Name, tombstonePrefix)] = true
+		}
+	}
+
+	restored, skipped, failed := 0, 0, 0
+	for _, tag := range append(tags, markers...) {
+		existing, _ := gitOutput("rev-parse", "--verify", "--quiet", "refs/tags/"+tag.Name)
+		if existing == tag.Object {
+			skipped++
+			continue
+		}
+		if !tag.isMarker() {
+			_, err := gitOutput("rev-parse", "--verify", "--quiet", "refs/tags/"+tombstonePrefix+tag.Name)
+			if err == nil || tombstones[tag.Name] {
+				log.Error().Str("tag", tag.Name).Msg("tag was deleted, remove its tombstone to restore it")
+				failed++
+				continue
+			}
+		}
+		if existing != "" && !*force {
+			log.Warn().Str("tag", tag.Name).Str("current", existing).Str("backup", tag.Object).Msg("tag exists with a different target, use --force to replace it")
+			failed++
+			continue
+		}
+		if err := restoreTag(tag, existing); err != nil {
+			log.Error().Err(err).Str("tag", tag.Name).Msg("unable to restore tag")
+			failed++
+			continue
+		}
+		restored++
+	}
+
+	log.Info().Int("restored", restored).Int("unchanged", skipped).Int("failed", failed).Msg("Restore finished")
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Function to list every tag in the module/channel scheme matching the given
+// globs, with the yank markers and tombstones of those tags, including the
+// raw object of annotated tags
+func collectBackupTags(modulePattern, channelPattern string) ([]backupTag, error) {
+	out, err := gitOutput("for-each-ref", "--format=%(refname:strip=2) %(objecttype) %(objectname) %(*objecttype) %(*objectname)", "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []backupTag
+	var annotated, nested []int
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		// Yank markers and tombstones are backed up with the tag they
+		// mark, so a restore keeps yanked versions out of reach and the
+		// record of deletions
+		name := strings.TrimPrefix(strings.TrimPrefix(fields[0], yankPrefix), tombstonePrefix)
+		module, channel, _, ok := parseTag(name)
+		if !ok {
+			continue
+		}
+		if ok, _ := path.Match(modulePattern, module); !ok {
+			continue
+		}
+		if ok, _ := path.Match(channelPattern, channel); !ok {
+			continue
+		}
+		tag := backupTag{Name: fields[0], Type: fields[1], Object: fields[2], Commit: fields[2]}
+		if tag.Type == "tag" && len(fields) == 5 {
+			tag.Commit = fields[4]
+			annotated = append(annotated, len(tags))
+			if fields[3] == "tag" {
+				nested = append(nested, len(tags))
+			}
+		}
+		tags = append(tags, tag)
+	}
+
+	if len(annotated) == 0 {
+		return tags, nil
+	}
+	objects := make([]string, len(annotated))
+	for i, n := range annotated {
+		objects[i] = tags[n].Object
+	}
+	raws, err := readObjects(objects)
+	if err != nil {
+		return nil, err
+	}
+	for i, n := range annotated {
+		tags[n].Raw = raws[i]
+		tags[n].Message, tags[n].Signature = splitTagObject(raws[i])
+	}
+	if len(nested) == 0 {
+		return tags, nil
+	}
+	// Markers of annotated tags tag the tag object they mark, so the
+	// commit is one step further
+	objects = make([]string, len(nested))
+	for i, n := range nested {
+		objects[i] = tags[n].Commit
+	}
+	if raws, err = readObjects(objects); err != nil {
+		return nil, err
+	}
+	for i, n := range nested {
+		tags[n].TaggedRaw = raws[i]
+		if object, ok := strings.CutPrefix(strings.SplitN(raws[i], "\n", 2)[0], "object "); ok {
+			tags[n].Commit = object
+		}
+	}
+	return tags, nil
+}
+
+// Function to read the raw contents of several objects with a single
+// git cat-file process
+func readObjects(objects []string) ([]string, error) {
+	cmd := gitCommand("cat-file", "--batch")
+	cmd.Stdin = strings.NewReader(strings.Join(objects, "\n") + "\n")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(stdout)
+	raws := make([]string, 0, len(objects))
+	for range objects {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			cmd.Wait()
+			return nil, err
+		}
+		fields := strings.Fields(header)
+		if len(fields) == 2 && fields[1] == "missing" {
+			cmd.Wait()
+			return nil, fmt.Errorf("object %s is missing from the repository", fields[0])
+		}
+		if len(fields) != 3 {
+			cmd.Wait()
+			return nil, fmt.Errorf("unexpected cat-file output: %q", header)
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			cmd.Wait()
+			return nil, err
+		}
+		buf := make([]byte, size+1)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			cmd.Wait()
+			return nil, err
+		}
+		raws = append(raws, string(buf[:size]))
+	}
+	return raws, cmd.Wait()
+}
+
+// Function to split a raw tag object into its message and signature
+func splitTagObject(raw string) (string, string) {
+	_, body, found := strings.Cut(raw, "\n\n")
+	if !found {
+		return "", ""
+	}
+	for _, marker := range []string{"-----BEGIN PGP SIGNATURE-----", "-----BEGIN SSH SIGNATURE-----", "-----BEGIN SIGNED MESSAGE-----"} {
+		if i := strings.Index(body, marker); i >= 0 {
+			return body[:i], body[i:]
+		}
+	}
+	return body, ""
+}
+
+// Function to recreate a tag from its backup. Annotated tags are rebuilt
+// from their raw object so the result is byte-for-byte identical, and so is
+// the tag object a marker points at.
+func restoreTag(tag backupTag, existing string) error {
+	object := tag.Object
+	if tag.TaggedRaw != "" {
+		if _, err := gitOutputWithInput(tag.TaggedRaw, "mktag"); err != nil {
+			return err
+		}
+	}
+	if tag.Type == "tag" {
+		created, err := gitOutputWithInput(tag.Raw, "mktag")
+		if err != nil {
+			return err
+		}
+		object = created
+	}
+	_, err := gitOutput("update-ref", "refs/tags/"+tag.Name, object, existing)
+	return err
+}
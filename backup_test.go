@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Function to back up every tag of the test repository to a file
+func writeBackup(t *testing.T) string {
+	t.Helper()
+	tags, err := collectBackupTags("*", "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(backupFile{Version: backupFormatVersion, Tags: tags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "tags.json")
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// Function to read the object a tag points at, empty when it does not exist
+func tagObject(name string) string {
+	object, _ := gitOutput("rev-parse", "--verify", "--quiet", "refs/tags/"+name)
+	return object
+}
+
+func TestBackupRestoresMarkersAndTombstones(t *testing.T) {
+	_, commit := newTestRepo(t)
+	runGit(t, "tag", "-a", "-m", "first release", "app/prod/v1.0.0", commit)
+	runGit(t, "tag", "-a", "-m", "second release", "app/prod/v1.1.0", commit)
+	runGit(t, "tag", "app/prod/v1.2.0", commit)
+	if _, err := createYankMarker("app/prod/v1.1.0", "bad migration"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createYankMarker("app/prod/v1.2.0", "broken build"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createTombstone("app/prod/v1.0.0", "leaked secret"); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, "tag", "--delete", "app/prod/v1.0.0")
+
+	names := []string{"app/prod/v1.1.0", "app/prod/v1.2.0", "yanked/app/prod/v1.1.0", "yanked/app/prod/v1.2.0", "deleted/app/prod/v1.0.0"}
+	want := make(map[string]string)
+	for _, name := range names {
+		want[name] = tagObject(name)
+	}
+	file := writeBackup(t)
+
+	// Start from a repository without tags, whose tag objects are gone
+	for _, name := range names {
+		runGit(t, "tag", "--delete", name)
+	}
+	runGit(t, "reflog", "expire", "--expire=now", "--all")
+	runGit(t, "gc", "-q", "--prune=now")
+
+	if code := runRestore([]string{"-f", file}); code != 0 {
+		t.Fatalf("restore exited with %d", code)
+	}
+	for _, name := range names {
+		if got := tagObject(name); got != want[name] {
+			t.Errorf("%s restored as %q, want %q", name, got, want[name])
+		}
+	}
+	if got := tagObject("app/prod/v1.0.0"); got != "" {
+		t.Errorf("deleted tag app/prod/v1.0.0 restored as %s", got)
+	}
+	yanked, err := loadYanked()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if yanked["app/prod/v1.1.0"].Reason != "bad migration" {
+		t.Errorf("yanked app/prod/v1.1.0 = %+v, want reason bad migration", yanked["app/prod/v1.1.0"])
+	}
+}
+
+func TestRestoreRejectsDeletedTag(t *testing.T) {
+	_, commit := newTestRepo(t)
+	runGit(t, "tag", "-a", "-m", "first release", "app/prod/v1.0.0", commit)
+	file := writeBackup(t)
+
+	if _, err := createTombstone("app/prod/v1.0.0", "leaked secret"); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, "tag", "--delete", "app/prod/v1.0.0")
+
+	if code := runRestore([]string{"-f", file}); code == 0 {
+		t.Fatal("restore of a deleted tag succeeded")
+	}
+	if got := tagObject("app/prod/v1.0.0"); got != "" {
+		t.Fatalf("deleted tag app/prod/v1.0.0 restored as %s", got)
+	}
+}
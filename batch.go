@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// batchFile lists the releases of a coordinated release day
+type batchFile struct {
+	// Commit is tagged by every release that does not name its own
+	Commit   string         `yaml:"commit,omitempty"`
+	Releases []batchRelease `yaml:"releases"`
+}
+
+// batchRelease is one module released on one channel in a batch
+type batchRelease struct {
+	Module  string `yaml:"module"`
+	Channel string `yaml:"channel"`
+	Bump    string `yaml:"bump,omitempty"`
+	Version string `yaml:"version,omitempty"`
+	Commit  string `yaml:"commit,omitempty"`
+}
+
+// Function to read a batch file, rejecting unknown keys
+func readBatch(path string) (batchFile, error) {
+	var batch batchFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return batch, err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&batch); err != nil {
+		return batch, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(batch.Releases) == 0 {
+		return batch, fmt.Errorf("%s lists no releases", path)
+	}
+	return batch, nil
+}
+
+// Function to compute the tags of every release in a batch
+func planBatch(idx *tagIndex, batch batchFile) ([]tagResult, error) {
+	var tags []tagResult
+	seen := make(map[string]bool)
+	for i, release := range batch.Releases {
+		if err := validateModule(release.Module); err != nil {
+			return nil, fmt.Errorf("release %d: %w", i+1, err)
+		}
+		if err := validateChannel(release.Channel); err != nil {
+			return nil, fmt.Errorf("release %d: %w", i+1, err)
+		}
+		key := release.Module + "/" + release.Channel
+		if seen[key] {
+			return nil, fmt.Errorf("release %d: %s is released more than once", i+1, key)
+		}
+		seen[key] = true
+
+		rev := release.Commit
+		if rev == "" {
+			rev = batch.Commit
+		}
+		if rev == "" {
+			rev = "HEAD"
+		}
+		commit, err := resolveCommit(rev)
+		if err != nil {
+			return nil, fmt.Errorf("release %d: unable to resolve commit %s: %w", i+1, rev, err)
+		}
+
+		current := parseCurrentVersion(idx, release.Module, []string{release.Channel})
+		if isCounterChannel(release.Channel) {
+			current = currentCounter(idx, release.Module, release.Channel)
+		}
+		var next Version
+		if release.Version != "" {
+			next, err = parseVersion(release.Version)
+		} else if release.Bump != "" {
+			next, err = bumpVersion(current, release.Bump, schemeOf(release.Module))
+		} else {
+			next, err = bumpVersion(current, "patch", schemeOf(release.Module))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("release %d: %w", i+1, err)
+		}
+
+		var previous string
+		if version, ok := idx.latest[release.Module][release.Channel]; ok {
+			previous = formatTag(release.Module, release.Channel, version)
+		}
+		tags = append(tags, tagResult{
+			Module:   release.Module,
+			Channel:  release.Channel,
+			Old:      current,
+			New:      next,
+			Tag:      formatTag(release.Module, release.Channel, next),
+			Previous: previous,
+			Commit:   commit,
+		})
+	}
+	return tags, nil
+}
+
+// Function to create several tags in a single ref transaction, so either
+// all of them are created or none is
+func createTagsAtomically(tags []tagResult) error {
+	var input strings.Builder
+	input.WriteString("start\n")
+	for _, t := range tags {
+		fmt.Fprintf(&input, "create refs/tags/%s %s\n", t.Tag, t.Commit)
+	}
+	input.WriteString("prepare\ncommit\n")
+	_, err := gitOutputWithInput(input.String(), "update-ref", "--stdin")
+	return err
+}
+
+// Function to handle `version apply-batch <releases.yaml>`, tagging every
+// release listed in a batch file after checking the whole batch
+func runApplyBatch(args []string) int {
+	fs := flag.NewFlagSet("apply-batch", flag.ExitOnError)
+	atomic := fs.Bool("atomic", false, "create either every tag of the batch or none")
+	dryRun := fs.Bool("dry-run", false, "only print the consolidated plan")
+	fs.BoolVar(&pushCreated, "push", false, "push the created tags to the remote")
+	fs.StringVar(&pushRemote, "remote", "origin", "remote to push created tags to")
+	fs.StringVar(&mirrorRemote, "mirror", "", "secondary remote (name or URL) to mirror created tags to")
+	registerSummaryFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: version apply-batch [flags] <releases.yaml>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if err := validateSummaryFormat(); err != nil {
+		log.Error().Err(err).Msg("invalid summary format")
+		return 2
+	}
+	return applyBatch(fs.Arg(0), *atomic, *dryRun)
+}
+
+// Function to plan, check and tag every release of a batch file, printing
+// the consolidated plan first
+func applyBatch(path string, atomic, dryRun bool) int {
+	batch, err := readBatch(path)
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read batch")
+		return 1
+	}
+	idx, err := scanTagIndex()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	config, err := loadConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read configuration")
+		return 1
+	}
+	tags, err := planBatch(idx, batch)
+	if err != nil {
+		log.Error().Err(err).Msg("invalid batch")
+		return 1
+	}
+	explicit := func(i int) bool { return batch.Releases[i].Version != "" }
+	if err := assignMonotonic(config, tags, explicit); err != nil {
+		log.Error().Err(err).Msg("invalid batch")
+		return 1
+	}
+	if err := applyBuildMetadata(config, tags); err != nil {
+		log.Error().Err(err).Msg("invalid batch")
+		return 1
+	}
+
+	log.Info().Int("tags", len(tags)).Msg("Batch plan")
+	for _, t := range tags {
+		fmt.Fprintf(os.Stderr, "  %s -> %s at %s\n", t.Old, t.Tag, shortHash(t.Commit))
+	}
+	if err := checkPlan(tags); err != nil {
+		log.Error().Err(err).Msg("batch cannot be applied")
+		return 1
+	}
+	for _, t := range tags {
+		if err := checkTemporaryCommit(config, t.Commit); err != nil {
+			log.Error().Err(err).Msg("not allowed to tag")
+			return 1
+		}
+	}
+	if dryRun {
+		return 0
+	}
+
+	var results []tagResult
+	defer func() { writeSummary(results) }()
+	if atomic {
+		if err := createTagsAtomically(tags); err != nil {
+			log.Error().Err(err).Msg("Error creating git tags, none were created")
+			return 1
+		}
+		results = tags
+	} else {
+		for _, result := range tags {
+			if err := createGitTag(result.Tag, result.Commit); err != nil {
+				log.Error().Err(err).Int("remaining", len(tags)-len(results)).Msg("Error creating git tag. Exiting.")
+				return 1
+			}
+			results = append(results, result)
+		}
+	}
+	if !publishResults(results) {
+		return 1
+	}
+	log.Info().Int("tags", len(results)).Msg("Batch applied")
+	return 0
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// blocker is a failed check keeping a release from being tagged
+type blocker struct {
+	Check string
+	// Detail is what the check found
+	Detail string
+	// Source is where the rule comes from, such as
+	// .version.yaml:12 (channels.prod.protected)
+	Source string
+	// Remedy is how to get past the check
+	Remedy string
+}
+
+// Function to locate a key of the configuration file, as file:line followed
+// by the key, or the file alone when the key cannot be found
+func configLine(keys ...string) string {
+	path, err := configPath()
+	if err != nil {
+		return configFileName
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return configFileName
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return configFileName
+	}
+	node, line := doc.Content[0], 0
+	for _, key := range keys {
+		if node.Kind != yaml.MappingNode {
+			return configFileName
+		}
+		var child *yaml.Node
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value == key {
+				child, line = node.Content[j+1], node.Content[j].Line
+				break
+			}
+		}
+		if child == nil {
+			return configFileName
+		}
+		node = child
+	}
+	return fmt.Sprintf("%s:%d (%s)", configFileName, line, strings.Join(keys, "."))
+}
+
+// Function to tell where the version scheme of a module is chosen
+func schemeSource(module string) string {
+	if schemeOverride != "" {
+		return "--scheme flag"
+	}
+	if _, ok := moduleSchemes[module]; ok {
+		return configLine("modules", module, "scheme")
+	}
+	if line := configLine("scheme"); line != configFileName {
+		return line
+	}
+	return "the default " + schemeSemver + " scheme"
+}
+
+// Function to tell where the owners of a module are listed
+func ownersSource(config *Config, module string) string {
+	if len(config.Modules[module].Owners) > 0 {
+		return configLine("modules", module, "owners")
+	}
+	root, err := gitOutput("rev-parse", "--show-toplevel")
+	if err == nil {
+		for _, location := range codeownersLocations {
+			if _, err := os.Stat(root + "/" + location); err == nil {
+				return location
+			}
+		}
+	}
+	return "CODEOWNERS"
+}
+
+// Function to run every check a release of the targets on the channels
+// must pass, returning all those that fail instead of stopping at the first.
+// Ownership of protected channels is only checked when owners is set.
+func releaseBlockers(idx *tagIndex, config *Config, targets, channels []string, commit string, owners bool) []blocker {
+	var blockers []blocker
+	for _, m := range targets {
+		if err := checkArchived(config, m); err != nil {
+			blockers = append(blockers, blocker{
+				Check:  "archived",
+				Detail: err.Error(),
+				Source: configLine("modules", m, "archived"),
+				Remedy: fmt.Sprintf("run 'version unarchive %s' if the module is maintained again", m),
+			})
+		}
+		for _, r := range channels {
+			if !owners {
+				break
+			}
+			if err := checkOwnership(config, m, r); err != nil {
+				blockers = append(blockers, blocker{
+					Check:  "protected channel",
+					Detail: err.Error(),
+					Source: configLine("channels", r, "protected") + ", owners from " + ownersSource(config, m),
+					Remedy: fmt.Sprintf("ask an owner of %s to tag it, or set git user.email to an owner's address if you are one", m),
+				})
+			}
+		}
+	}
+	if err := checkBump(idx, targets, channels); err != nil {
+		blockers = append(blockers, bumpBlocker(err, targets, channels))
+	}
+	if err := checkTemporaryCommit(config, commit); err != nil {
+		blockers = append(blockers, blocker{
+			Check:  "temporary commit",
+			Detail: err.Error(),
+			Source: configLine("temporary_commits", "refuse"),
+			Remedy: "tag the commit once it lands on its branch, or pass --allow-temporary",
+		})
+	}
+	return blockers
+}
+
+// Function to explain a bump refused by checkBump, naming the rule it breaks
+func bumpBlocker(err error, targets, channels []string) blocker {
+	b := blocker{Detail: err.Error()}
+	for _, r := range channels {
+		if isCounterChannel(r) && checkCounterBump([]string{r}) != nil {
+			b.Check = "counter channel"
+			b.Source = configLine("channels", r, "type")
+			b.Remedy = "tag " + r + " in a run of its own without --bump, --minor, --major or --prerelease, giving a counter such as b42 to --set"
+			return b
+		}
+		if isSnapshotChannel(r) && checkSnapshotBump([]string{r}) != nil {
+			b.Check = "snapshot channel"
+			b.Source = configLine("channels", r, "type")
+			b.Remedy = "tag " + r + " without --set or --prerelease, its version follows the next release"
+			return b
+		}
+	}
+	if branchLine != nil && checkBranchLine(targets, channels) != nil {
+		b.Check = "release line"
+		b.Source = "--branch-line flag"
+		b.Remedy = "bump the patch of a version already tagged on the " + branchLine.String() + " line, or leave out --branch-line"
+		return b
+	}
+	for _, m := range targets {
+		part := bumpPartOf(m)
+		if explicitVersion == nil && part != "patch" && schemeOf(m) == schemeCalver {
+			b.Check = "calendar version bump"
+			b.Source = schemeSource(m)
+			b.Remedy = "drop --" + part + " since calendar versions follow the date, or give the version with --set"
+			return b
+		}
+		if part == "build" && schemeOf(m) != schemeFourPart {
+			b.Check = "build bump"
+			b.Source = schemeSource(m)
+			b.Remedy = "bump the patch instead, or set the scheme of " + m + " to " + schemeFourPart
+			return b
+		}
+	}
+	b.Check = "prerelease bump"
+	b.Source = "--prerelease flag"
+	b.Remedy = "pick a prerelease name that sorts after the current one, such as rc after beta, or release the current prerelease first"
+	return b
+}
+
+// Function to report the checks blocking a release: a line each, then, when
+// someone can answer, the offer of a detailed view with the source of every
+// rule and how to get past it
+func reportBlockers(blockers []blocker) {
+	for _, b := range blockers {
+		log.Error().Str("check", b.Check).Str("rule", b.Source).Str("fix", b.Remedy).Msg(b.Detail)
+	}
+	if nonInteractive || !isTerminal(os.Stdin) || !confirm("Show what is blocking the release") {
+		return
+	}
+	var view bytes.Buffer
+	if len(blockers) == 1 {
+		fmt.Fprintf(&view, "\n1 check blocks the release:\n")
+	} else {
+		fmt.Fprintf(&view, "\n%d checks block the release:\n", len(blockers))
+	}
+	for i, b := range blockers {
+		fmt.Fprintf(&view, "\n%d. %s\n", i+1, b.Check)
+		fmt.Fprintf(&view, "   %s\n", b.Detail)
+		fmt.Fprintf(&view, "   rule: %s\n", b.Source)
+		fmt.Fprintf(&view, "   fix:  %s\n", b.Remedy)
+	}
+	fmt.Fprintln(os.Stderr, strings.TrimRight(view.String(), "\n")+"\n")
+}
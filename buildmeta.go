@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// buildMetadata is the template of the build metadata appended to created
+// versions, given with --build-metadata
+var buildMetadata string
+
+// buildIdentifier matches one dot separated build metadata identifier
+var buildIdentifier = regexp.MustCompile(`^[0-9A-Za-z-]+$`)
+
+// buildNumberVariables are the environment variables CI systems put their
+// build number in, in the order they are looked up
+var buildNumberVariables = []string{"VERSION_BUILD_NUMBER", "BUILD_NUMBER", "GITHUB_RUN_NUMBER", "CI_PIPELINE_IID", "BUILDKITE_BUILD_NUMBER", "CIRCLE_BUILD_NUM"}
+
+// buildContext is what build metadata templates can refer to
+type buildContext struct {
+	Module      string
+	Channel     string
+	Commit      string
+	ShortCommit string
+	BuildNumber string
+}
+
+// Function to check build metadata against the semver rules: alphanumerics
+// and hyphens separated by dots, leading zeros being allowed
+func validateBuild(build string) error {
+	for _, identifier := range strings.Split(build, ".") {
+		if !buildIdentifier.MatchString(identifier) {
+			return fmt.Errorf("invalid build metadata %q, identifiers are alphanumerics and hyphens separated by dots", build)
+		}
+	}
+	return nil
+}
+
+// Function to find the build number of the CI run, empty outside CI
+func ciBuildNumber() string {
+	for _, name := range buildNumberVariables {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Function to render the build metadata template for a planned tag. An
+// empty result means no build metadata.
+func renderBuildMetadata(text string, result tagResult) (string, error) {
+	tmpl, err := template.New("build").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid build metadata template: %w", err)
+	}
+	var out strings.Builder
+	err = tmpl.Execute(&out, buildContext{
+		Module:      result.Module,
+		Channel:     result.Channel,
+		Commit:      result.Commit,
+		ShortCommit: shortHash(result.Commit),
+		BuildNumber: ciBuildNumber(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("invalid build metadata template: %w", err)
+	}
+	build := strings.TrimSpace(out.String())
+	if build == "" {
+		return "", nil
+	}
+	return build, validateBuild(build)
+}
+
+// Function to append build metadata to planned tags, whose commits must be
+// set, from --build-metadata or the configured template. Versions given with
+// their own build metadata keep it.
+func applyBuildMetadata(config *Config, results []tagResult) error {
+	text := buildMetadata
+	if text == "" {
+		text = config.BuildMetadata
+	}
+	if text == "" {
+		return nil
+	}
+	for i := range results {
+		if results[i].New.Build != "" {
+			continue
+		}
+		build, err := renderBuildMetadata(text, results[i])
+		if err != nil {
+			return err
+		}
+		results[i].New.Build = build
+		results[i].Tag = formatTag(results[i].Module, results[i].Channel, results[i].New)
+	}
+	return nil
+}
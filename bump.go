@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rs/zerolog/log"
+)
+
+// bumpRequest is one release requested on stdin by `version bump --stdin`
+type bumpRequest struct {
+	Module  string `json:"module"`
+	Channel string `json:"channel"`
+	Commit  string `json:"commit,omitempty"`
+	Bump    string `json:"bump,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// Function to read release requests from JSON given as single objects, one
+// after the other, or as arrays of objects
+func readBumpRequests(r io.Reader) ([]bumpRequest, error) {
+	var requests []bumpRequest
+	decoder := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); errors.Is(err, io.EOF) {
+			return requests, nil
+		} else if err != nil {
+			return nil, err
+		}
+		var batch []bumpRequest
+		if raw[0] != '[' {
+			batch = make([]bumpRequest, 1)
+			raw = append(append(json.RawMessage{'['}, raw...), ']')
+		}
+		if err := json.Unmarshal(raw, &batch); err != nil {
+			return nil, err
+		}
+		requests = append(requests, batch...)
+	}
+}
+
+// Function to translate a release request into the flags of the tagging flow
+func (req bumpRequest) apply(fs *flag.FlagSet) error {
+	req.Module, req.Channel = impliedNames(req.Module, req.Channel)
+	if req.Module == "" || req.Channel == "" {
+		return fmt.Errorf("request %+v needs both module and channel", req)
+	}
+	values := map[string]string{"m": req.Module, "r": req.Channel, "c": req.Commit, "bump": req.Bump, "set": req.Version}
+	for name, value := range values {
+		if value == "" {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", name, value, err)
+		}
+	}
+	return nil
+}
+
+// Function to handle `version bump`, tagging without any prompts. With
+// --stdin the modules and channels come from JSON release requests, each
+// tagged in turn with the flags of the command line as defaults.
+func runBump(args []string) int {
+	newFlagSet := func() (*flag.FlagSet, *bool) {
+		fs := flag.NewFlagSet("bump", flag.ExitOnError)
+		registerTagFlags(fs)
+		fromStdin := fs.Bool("stdin", false, `read {"module", "channel", "commit", "bump", "version"} requests, or arrays of them, as JSON from stdin`)
+		fs.Parse(args)
+		moduleName, releaseChannel = impliedNames(moduleName, releaseChannel)
+		nonInteractive = true
+		return fs, fromStdin
+	}
+	fs, fromStdin := newFlagSet()
+	if !*fromStdin {
+		if moduleName == "" || releaseChannel == "" {
+			log.Error().Msg("both -m and -r are required, or --stdin")
+			return 2
+		}
+		return run(fs)
+	}
+
+	requests, err := readBumpRequests(os.Stdin)
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read release requests")
+		return 2
+	}
+	if len(requests) == 0 {
+		log.Error().Msg("no release requests on stdin")
+		return 2
+	}
+	for i, req := range requests {
+		// Every request starts again from the command line flags
+		fs, _ := newFlagSet()
+		if err := req.apply(fs); err != nil {
+			log.Error().Err(err).Int("request", i+1).Msg("invalid release request")
+			return 2
+		}
+		if code := run(fs); code != 0 {
+			log.Error().Int("request", i+1).Int("remaining", len(requests)-i-1).Msg("release request failed, stopping")
+			return code
+		}
+	}
+	return 0
+}
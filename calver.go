@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Function to compute the calendar version following v on the given date.
+// The sequence goes on within the month of the current version, and starts
+// over at 1 once the month has passed. A prerelease is released instead.
+func bumpCalver(v Version, part string, now time.Time) (Version, error) {
+	if part != "patch" {
+		return v, fmt.Errorf("calendar versions follow the date and take no %s bump", part)
+	}
+	if v.Prerelease != "" {
+		return v.Release(), nil
+	}
+	year, month := now.Year(), int(now.Month())
+	if year > v.Major || year == v.Major && month > v.Minor {
+		return Version{Major: year, Minor: month, Patch: 1, ZeroPadded: true}, nil
+	}
+	// Versions from a later month, tagged by hand or with a skewed clock,
+	// are continued rather than going back in time
+	next := v.WithoutBuild()
+	next.Patch++
+	next.ZeroPadded = true
+	return next, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBumpCalver(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		now     time.Time
+		want    string
+	}{
+		{"same month", "2026.10.1", time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC), "2026.10.2"},
+		{"month rollover", "2026.09.7", time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC), "2026.10.1"},
+		{"year rollover", "2025.12.4", time.Date(2026, time.January, 2, 0, 0, 0, 0, time.UTC), "2026.01.1"},
+		{"padded month", "2026.02.9", time.Date(2026, time.February, 28, 0, 0, 0, 0, time.UTC), "2026.02.10"},
+		{"first of a later year", "0.0.0", time.Date(2026, time.March, 5, 0, 0, 0, 0, time.UTC), "2026.03.1"},
+		{"later month kept", "2026.11.2", time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC), "2026.11.3"},
+		{"prerelease released", "2026.10.3-rc.1", time.Date(2026, time.December, 1, 0, 0, 0, 0, time.UTC), "2026.10.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, err := parseVersion(tt.current)
+			if err != nil {
+				t.Fatal(err)
+			}
+			next, err := bumpCalver(current, "patch", tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := next.String(); got != tt.want {
+				t.Fatalf("bumpCalver(%s) on %s = %s, want %s", tt.current, tt.now.Format(time.DateOnly), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBumpCalverRejectsParts(t *testing.T) {
+	now := time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+	for _, part := range []string{"major", "minor", "build"} {
+		if _, err := bumpCalver(Version{Major: 2026, Minor: 10, Patch: 1, ZeroPadded: true}, part, now); err == nil {
+			t.Errorf("bumpCalver accepted a %s bump", part)
+		}
+	}
+}
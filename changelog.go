@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// changelogEntry is a single commit in a release range
+type changelogEntry struct {
+	Hash    string
+	Subject string
+	Author  string
+}
+
+// Function to resolve a --from/--to argument, given as a version or a full
+// tag name, to a tag of the module on the channel
+func resolveVersionTag(module, channel, arg string) (string, error) {
+	if _, _, _, ok := parseTag(arg); ok {
+		return arg, nil
+	}
+	version, err := parseVersion(arg)
+	if err != nil {
+		return "", err
+	}
+	return formatTag(module, channel, version), nil
+}
+
+// Function to list the versions tagged on a channel of a module, lowest first
+func channelVersions(module, channel string) ([]Version, error) {
+	entries, err := readTagEntries(func(m, c string) bool { return m == module && c == channel })
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]Version, len(entries))
+	for i, e := range entries {
+		versions[i] = e.Version
+	}
+	slices.SortFunc(versions, compareVersions)
+	return versions, nil
+}
+
+// Function to read the commits reachable from to but not from, limited to
+// the given paths when there are any
+func readChangelog(from, to string, paths []string) ([]changelogEntry, error) {
+	args := []string{"log", "--no-merges", "--format=%H%x09%an%x09%s"}
+	if from == "" {
+		args = append(args, to)
+	} else {
+		args = append(args, from+".."+to)
+	}
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+	out, err := gitOutput(args...)
+	if err != nil {
+		return nil, err
+	}
+	var entries []changelogEntry
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) == 3 {
+			entries = append(entries, changelogEntry{Hash: fields[0], Author: fields[1], Subject: fields[2]})
+		}
+	}
+	return entries, nil
+}
+
+// Function to handle `version changelog`, printing the commits between two
+// versions of a module on a release channel
+func runChangelog(args []string) int {
+	fs := flag.NewFlagSet("changelog", flag.ExitOnError)
+	fs.StringVar(&moduleName, "m", "", "module name")
+	fs.StringVar(&releaseChannel, "r", "", "release channel")
+	fromArg := fs.String("from", "", "version or tag to start after (default the version before --to)")
+	toArg := fs.String("to", "", "version or tag to end at (default the latest version)")
+	remote := fs.String("remote", "origin", "remote used to build the compare link")
+	fs.Parse(args)
+	moduleName, releaseChannel = impliedNames(moduleName, releaseChannel)
+
+	if moduleName == "" || releaseChannel == "" {
+		log.Error().Msg("both -m and -r are required")
+		return 2
+	}
+	config, err := loadConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read configuration")
+		return 1
+	}
+	versions, err := channelVersions(moduleName, releaseChannel)
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+
+	var to, from string
+	if *toArg != "" {
+		if to, err = resolveVersionTag(moduleName, releaseChannel, *toArg); err != nil {
+			log.Error().Err(err).Msg("invalid --to")
+			return 1
+		}
+	} else if len(versions) > 0 {
+		to = formatTag(moduleName, releaseChannel, versions[len(versions)-1])
+	} else {
+		log.Error().Str("module", moduleName).Str("channel", releaseChannel).Msg("no version tags found")
+		return 1
+	}
+	if *fromArg != "" {
+		if from, err = resolveVersionTag(moduleName, releaseChannel, *fromArg); err != nil {
+			log.Error().Err(err).Msg("invalid --from")
+			return 1
+		}
+	} else {
+		_, _, toVersion, _ := parseTag(to)
+		for _, v := range versions {
+			if compareVersions(v, toVersion) < 0 {
+				from = formatTag(moduleName, releaseChannel, v)
+			}
+		}
+	}
+
+	for _, tag := range []string{from, to} {
+		if tag == "" {
+			continue
+		}
+		if _, err := resolveCommit("refs/tags/" + tag); err != nil {
+			log.Error().Str("tag", tag).Msg("no such tag")
+			return 1
+		}
+	}
+
+	// Scoped like status, to the module directory when no paths are
+	// configured
+	paths := modulePaths(config, moduleName, "refs/tags/"+to)
+	entries, err := readChangelog(from, to, paths)
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read commits")
+		return 1
+	}
+
+	if from == "" {
+		fmt.Printf("Changes up to %s\n", to)
+	} else {
+		fmt.Printf("Changes from %s to %s\n", from, to)
+	}
+	if len(paths) > 0 {
+		fmt.Printf("Limited to %s\n", strings.Join(paths, ", "))
+	}
+	fmt.Println()
+	for _, e := range entries {
+		fmt.Printf("- %s (%s, %s)\n", e.Subject, shortHash(e.Hash), e.Author)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No changes")
+	}
+	if link := compareURL(*remote, from, to); link != "" {
+		fmt.Printf("\nCompare: %s\n", link)
+	}
+	return 0
+}
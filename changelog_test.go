@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestChangelogScopedToModuleDirectory(t *testing.T) {
+	_, first := newTestRepo(t)
+	runGit(t, "tag", "api/prod/v1.0.0", first)
+	commitFile(t, "api/main.go", "package main\n")
+	commitFile(t, "web/index.html", "<html></html>\n")
+	runGit(t, "tag", "api/prod/v1.1.0")
+
+	stdout := capture(t, &os.Stdout)
+	code := runChangelog([]string{"-m", "api", "-r", "prod"})
+	out := stdout()
+	if code != 0 {
+		t.Fatalf("changelog exited with %d", code)
+	}
+	if !strings.Contains(out, "change api/main.go") || strings.Contains(out, "change web/index.html") {
+		t.Fatalf("changelog of api is not limited to api/:\n%s", out)
+	}
+	if !strings.Contains(out, "Limited to api") {
+		t.Fatalf("changelog does not say it is limited to api:\n%s", out)
+	}
+}
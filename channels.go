@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Function to report whether a release channel is already known, either from
+// an existing tag or from the configuration
+func knownChannel(idx *tagIndex, config *Config, channel string) bool {
+	if _, ok := config.Channels[channel]; ok {
+		return true
+	}
+	for _, channels := range idx.latest {
+		if _, ok := channels[channel]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Function to ask for the policy of a new release channel
+func channelWizard(idx *tagIndex, config *Config, channel string) ChannelConfig {
+	var policy ChannelConfig
+	policy.Protected = confirm("Should only module owners tag release channel " + channel)
+
+	for {
+		after := strings.TrimSpace(promptText("Release channel that " + channel + " graduates from (empty for none)"))
+		if after == "" || (after != channel && knownChannel(idx, config, after)) {
+			policy.After = after
+			break
+		}
+		log.Warn().Str("channel", after).Msg("unknown release channel")
+	}
+
+	for _, target := range strings.Split(promptText("Where to announce new "+channel+" tags, comma separated (empty for nowhere)"), ",") {
+		if target = strings.TrimSpace(target); target != "" {
+			policy.Notify = append(policy.Notify, target)
+		}
+	}
+	return policy
+}
+
+// Function to write a policy for every release channel created by a run,
+// from the configured defaults or by asking when the run is interactive
+func configureNewChannels(idx *tagIndex, config *Config, channels []string, interactive bool) {
+	for _, channel := range channels {
+		if knownChannel(idx, config, channel) {
+			continue
+		}
+		var policy ChannelConfig
+		switch {
+		case config.ChannelDefaults != nil:
+			policy = *config.ChannelDefaults
+		case interactive:
+			policy = channelWizard(idx, config, channel)
+		default:
+			log.Warn().Str("channel", channel).Msg("new release channel has no policy, add it under channels in " + configFileName)
+			continue
+		}
+		if err := setConfigValue([]string{"channels", channel}, policy); err != nil {
+			log.Error().Err(err).Str("channel", channel).Msg("unable to save release channel policy")
+			continue
+		}
+		if config.Channels == nil {
+			config.Channels = make(map[string]ChannelConfig)
+		}
+		config.Channels[channel] = policy
+		log.Info().Str("channel", channel).Msg("Release channel policy saved to " + configFileName)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	classifyCacheFile = "notes-cache.json"
+	otherCategory     = "Other"
+)
+
+// noNotesCache ignores cached classifications, classifying every commit
+// again and refreshing the cache
+var noNotesCache bool
+
+// conventionalType matches the type of a conventional commit subject such as
+// "feat(api)!: add paging"
+var conventionalType = regexp.MustCompile(`^(\w+)(\([^)]*\))?!?:`)
+
+// conventionalCategories are the release notes sections of conventional
+// commit types
+var conventionalCategories = map[string]string{
+	"feat": "Features",
+	"fix":  "Fixes",
+	"perf": "Performance",
+}
+
+// classifyCache holds the category the classify command gave each commit,
+// per command so changing the command starts afresh
+type classifyCache map[string]map[string]string
+
+// Function to load the classification cache of the repository
+func loadClassifyCache() classifyCache {
+	cache := make(classifyCache)
+	dir, err := stateDir()
+	if err != nil {
+		return cache
+	}
+	data, err := os.ReadFile(filepath.Join(dir, classifyCacheFile))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Warn().Err(err).Msg("unable to read the release notes cache")
+		}
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		log.Warn().Err(err).Msg("ignoring unreadable release notes cache")
+		return make(classifyCache)
+	}
+	return cache
+}
+
+// Function to save the classification cache of the repository, merged with
+// the entries other runs saved in the meantime
+func (c classifyCache) save() error {
+	if readOnly {
+		// A cache is only an optimisation, so skip it quietly
+		return nil
+	}
+	return withStateLock(func(dir string) error {
+		merged := loadClassifyCache()
+		for command, commits := range c {
+			if merged[command] == nil {
+				merged[command] = make(map[string]string)
+			}
+			for commit, category := range commits {
+				merged[command][commit] = category
+			}
+		}
+		data, err := json.MarshalIndent(merged, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeFile(filepath.Join(dir, classifyCacheFile), data, 0o644)
+	})
+}
+
+// Function to classify a commit by its conventional commit type
+func conventionalCategory(subject string) string {
+	if match := conventionalType.FindStringSubmatch(subject); match != nil {
+		if category, ok := conventionalCategories[strings.ToLower(match[1])]; ok {
+			return category
+		}
+	}
+	return otherCategory
+}
+
+// Function to classify a commit with the classify command, which reads the
+// commit message on stdin and prints the category on its first line
+func runClassifyCommand(command string, e changelogEntry) (string, error) {
+	message, err := gitOutput("log", "-1", "--format=%B", e.Hash)
+	if err != nil {
+		return "", err
+	}
+	cmd := shellCommand(command)
+	cmd.Dir = repoDir
+	cmd.Env = append(os.Environ(), "VERSION_COMMIT="+e.Hash)
+	cmd.Stdin = strings.NewReader(message)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("classify command %q: %w", command, err)
+	}
+	category, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	if category = strings.TrimSpace(category); category == "" {
+		category = otherCategory
+	}
+	return category, nil
+}
+
+// Function to classify the commits of release notes into sections. Without
+// a classify command conventional commit types are used; results of the
+// command are cached per commit, so notes for overlapping ranges, like the
+// same commits promoted from channel to channel, are quick and consistent.
+func classifyEntries(config *Config, entries []changelogEntry) (map[string][]changelogEntry, error) {
+	sections := make(map[string][]changelogEntry)
+	command := config.NotesClassifyCommand
+	if command == "" {
+		for _, e := range entries {
+			category := conventionalCategory(e.Subject)
+			sections[category] = append(sections[category], e)
+		}
+		return sections, nil
+	}
+
+	cache := loadClassifyCache()
+	cached := cache[command]
+	if cached == nil || noNotesCache {
+		cached = make(map[string]string)
+		cache[command] = cached
+	}
+	changed := false
+	for _, e := range entries {
+		category, ok := cached[e.Hash]
+		if !ok {
+			var err error
+			if category, err = runClassifyCommand(command, e); err != nil {
+				return nil, err
+			}
+			cached[e.Hash] = category
+			changed = true
+		}
+		sections[category] = append(sections[category], e)
+	}
+	if changed {
+		if err := cache.save(); err != nil {
+			log.Warn().Err(err).Msg("unable to save the release notes cache")
+		}
+	}
+	return sections, nil
+}
+
+// Function to order release notes sections: the conventional ones first,
+// then the others by name, and Other last
+func sectionOrder(sections map[string][]changelogEntry) []string {
+	rank := func(category string) int {
+		switch category {
+		case "Features":
+			return 0
+		case "Fixes":
+			return 1
+		case "Performance":
+			return 2
+		case otherCategory:
+			return 4
+		}
+		return 3
+	}
+	var order []string
+	for category := range sections {
+		order = append(order, category)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if ri, rj := rank(order[i]), rank(order[j]); ri != rj {
+			return ri < rj
+		}
+		return order[i] < order[j]
+	})
+	return order
+}
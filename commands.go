@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+)
+
+// command is a subcommand of the CLI
+type command struct {
+	run func(args []string) int
+	// summary is the one line description shown by `version help`
+	summary string
+}
+
+// commands maps subcommand names to their handlers; running without a
+// subcommand is the same as `version tag`
+var commands = map[string]command{
+	"again":          {runAgain, "repeat a recent tagging run"},
+	"api-diff":       {runAPIDiff, "list the changes to the exported Go API of a module since a tag"},
+	"apply":          {runApply, "create the tags of a plan file or a release manifest"},
+	"apply-batch":    {runApplyBatch, "create the tags of a batch file"},
+	"archive":        {runArchive, "hide a module from the pickers and refuse to tag it"},
+	"at":             {runAt, "print the latest version of a channel at a date"},
+	"backup":         {runBackup, "save every version tag to a file"},
+	"bump":           {runBump, "tag from requests given as arguments or on stdin, for other tools"},
+	"changelog":      {runChangelog, "list the commits between two versions"},
+	"completion":     {runCompletion, "print a shell completion script"},
+	"current":        {runCurrent, "print the current version of a module"},
+	"delete":         {runDelete, "delete tags locally and on the remote"},
+	"diff":           {runDiff, "compare two tagged versions of a module"},
+	"doctor":         {runDoctor, "check the tags for problems"},
+	"eol":            {runEOL, "report released versions past or nearing their end of life"},
+	"export":         {runExport, "write every release to an SQLite database"},
+	"history":        {runHistory, "list the tags of a module in creation order"},
+	"init":           {runInit, "write the repository configuration"},
+	"latest":         {runLatest, "print the latest version of every module as JSON or YAML"},
+	"list":           {runList, "print the latest versions as a module by channel matrix"},
+	"migrate":        {runMigrate, "rename legacy tags to the module/channel/vX.Y.Z layout"},
+	"migrate-scheme": {runMigrateScheme, "move a module to another version scheme, tagging the transition"},
+	"next":           {runNext, "print the tags the next run would create"},
+	"pipeline":       {runPipeline, "run a release through a configured promotion pipeline of channels"},
+	"plan":           {runPlan, "write the tags a run would create to a plan file"},
+	"promote":        {runPromote, "tag the commit of a version on other channels"},
+	"prune":          {runPrune, "delete old tags of busy channels"},
+	"push":           {runPush, "push the tags created by the last run"},
+	"query":          {runQuery, "list the tags of a module whose versions satisfy a semver constraint"},
+	"reminders":      {runReminders, "list the follow-ups scheduled after releases with --remind"},
+	"rename-module":  {runRenameModule, "copy the tags of a module to a new name"},
+	"reproduce":      {runReproduce, "rebuild released tags and compare the artifacts with the published ones"},
+	"restore":        {runRestore, "recreate tags from a backup file"},
+	"retag":          {runRetag, "move a tag to another commit"},
+	"run":            {runPreset, "run a preset from the configuration"},
+	"show":           {runShow, "show the details of a release"},
+	"simulate":       {runSimulate, "print the versions a series of bumps would produce"},
+	"sort":           {runSort, "print versions or tags in semver precedence order"},
+	"status":         {runStatus, "count the commits since the latest tag of every module"},
+	"tutorial":       {runTutorial, "walk through a release in a sandbox"},
+	"unarchive":      {runUnarchive, "make an archived module taggable again"},
+	"yank":           {runYank, "retract a released version so listings and queries skip it"},
+}
+
+func init() {
+	// Registered here since these list the commands map themselves
+	commands["help"] = command{runHelp, "list the commands or show the flags of one"}
+	commands["tag"] = command{runTag, "create the next version tags, the default command"}
+	commands["__complete"] = command{run: runComplete}
+}
+
+// Function to print the usage of the CLI with every visible command
+func printCommands(w io.Writer) {
+	fmt.Fprintln(w, "usage: version [--repo <path>] [--sandbox] [tag] [flags]")
+	fmt.Fprintln(w, "       version [--repo <path>] [--sandbox] <command> [flags]")
+	fmt.Fprintln(w, "\nCommands:")
+	var names []string
+	for name := range commands {
+		if !strings.HasPrefix(name, "_") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "  %s\t%s\n", name, commands[name].summary)
+	}
+	tw.Flush()
+	fmt.Fprintln(w, "\nRun 'version help <command>' for the flags of a command.")
+}
+
+// Function to handle `version help`, listing the commands, or printing the
+// flags of the given one
+func runHelp(args []string) int {
+	if len(args) == 0 {
+		printCommands(os.Stdout)
+		return 0
+	}
+	cmd, ok := commands[args[0]]
+	if !ok || strings.HasPrefix(args[0], "_") {
+		log.Error().Str("command", args[0]).Msg("no such command")
+		return 2
+	}
+	return cmd.run([]string{"-h"})
+}
+
+// Function to handle `version tag`, the interactive tagging flow that also
+// runs when no command is given
+func runTag(args []string) int {
+	setupLogging(os.Stdout)
+
+	fs := flag.NewFlagSet("tag", flag.ExitOnError)
+	registerTagFlags(fs)
+	fs.Usage = func() {
+		printCommands(fs.Output())
+		fmt.Fprintln(fs.Output(), "\nFlags of tag:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if summaryFormat != formatTable {
+		// Keep summaries meant for other tools free of log lines
+		setupLogging(os.Stderr)
+	}
+	if plainPrompts {
+		setupPlainLogging()
+	}
+
+	stopProfiling, err := startProfiling()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to start profiling")
+		return 1
+	}
+	code := run(fs)
+	stopProfiling()
+	return code
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// completionScripts hold the shell side of completion, which asks the hidden
+// __complete subcommand for candidates given the words before the cursor
+var completionScripts = map[string]string{
+	"bash": `_version_complete() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local IFS=$'\n'
+    COMPREPLY=($(compgen -W "$(version __complete "${COMP_WORDS[@]:1:COMP_CWORD-1}" 2>/dev/null)" -- "$cur"))
+}
+complete -o default -F _version_complete version
+`,
+	"zsh": `#compdef version
+_version() {
+    local -a candidates
+    candidates=(${(f)"$(version __complete ${words[2,CURRENT-1]} 2>/dev/null)"})
+    if (( ${#candidates} )); then
+        compadd -- $candidates
+    else
+        _files
+    fi
+}
+compdef _version version
+`,
+	"fish": `complete -c version -a '(version __complete (commandline -opc)[2..-1] 2>/dev/null)'
+`,
+}
+
+// moduleFlags and channelFlags name the flags that take a module or release
+// channel
+var (
+	moduleFlags  = []string{"m", "module"}
+	channelFlags = []string{"r", "channel", "from", "to"}
+)
+
+// Function to handle `version completion <shell>`, printing the completion
+// script for bash, zsh or fish
+func runCompletion(args []string) int {
+	if len(args) != 1 || completionScripts[args[0]] == "" {
+		fmt.Fprintln(os.Stderr, "usage: version completion bash|zsh|fish")
+		return 2
+	}
+	fmt.Print(completionScripts[args[0]])
+	return 0
+}
+
+// Function to handle `version __complete <words>...`, printing the
+// candidates for the word after the given ones, one per line
+func runComplete(args []string) int {
+	if len(args) == 0 {
+		var names []string
+		for name := range commands {
+			if !strings.HasPrefix(name, "_") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		fmt.Println(strings.Join(names, "\n"))
+		return 0
+	}
+
+	subcommand := args[0]
+	if strings.HasPrefix(subcommand, "-") {
+		subcommand = ""
+	}
+	if !strings.HasPrefix(args[len(args)-1], "-") {
+		return 0
+	}
+	previous := strings.TrimLeft(args[len(args)-1], "-")
+
+	idx, err := scanTagIndex()
+	if err != nil {
+		return 1
+	}
+	config, err := loadConfig()
+	if err != nil {
+		config = &Config{}
+	}
+	switch {
+	case slices.Contains(moduleFlags, previous), subcommand == "rename-module" && (previous == "from" || previous == "to"):
+		for _, module := range activeModules(config, mergeNames(idx.modules(), config.Modules)) {
+			fmt.Println(module)
+		}
+	case slices.Contains(channelFlags, previous):
+		for _, channel := range mergeNames(idx.channels(), config.Channels) {
+			fmt.Println(channel)
+		}
+	}
+	return 0
+}
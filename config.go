@@ -0,0 +1,463 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const configFileName = ".version.yaml"
+
+// Version schemes decide how the next version is computed
+const (
+	// schemeSemver increments the patch version without any rollover
+	schemeSemver = "semver"
+	// schemeRollover increments the patch version and rolls over into the
+	// minor and major versions after 9
+	schemeRollover = "rollover"
+	// schemeCalver numbers versions year.month.sequence, the sequence
+	// starting over every month
+	schemeCalver = "calver"
+	// schemeFourPart numbers versions major.minor.patch.build, the build
+	// number going up with every release
+	schemeFourPart = "fourpart"
+)
+
+var (
+	// versionScheme is the scheme of the current repository, set when the
+	// configuration is loaded
+	versionScheme = schemeSemver
+	// schemeOverride, when set, replaces the configured scheme
+	schemeOverride string
+	// moduleSchemes are the schemes configured for single modules, set when
+	// the configuration is loaded
+	moduleSchemes map[string]string
+	// versionSourceOverride, when set, replaces the configured version
+	// source of every module
+	versionSourceOverride string
+	// defaultVersionSource is the version source of modules configuring
+	// none, set when the configuration is loaded
+	defaultVersionSource = sourceModule
+	// moduleVersionSources are the version sources configured for single
+	// modules, set when the configuration is loaded
+	moduleVersionSources map[string]string
+)
+
+// Version sources decide which tags the next version of a module follows
+const (
+	// sourceModule follows the highest version across the channels tagged
+	// together, which all get the same version
+	sourceModule = "module"
+	// sourceChannel follows the latest version of each channel on its own
+	sourceChannel = "channel"
+	// sourceFrom prefixes a channel whose latest version every channel
+	// tagged together follows, as in from:dev
+	sourceFrom = "from:"
+)
+
+// Function to find the version source of a module: the one given with
+// --version-source, else the one configured for the module, else the
+// repository one
+func versionSourceOf(module string) string {
+	if versionSourceOverride != "" {
+		return versionSourceOverride
+	}
+	if source, ok := moduleVersionSources[module]; ok {
+		return source
+	}
+	return defaultVersionSource
+}
+
+// Function to check that a version source is known
+func validateVersionSource(source string) error {
+	switch source {
+	case sourceModule, sourceChannel:
+		return nil
+	}
+	if channel, ok := strings.CutPrefix(source, sourceFrom); ok {
+		return validateChannel(channel)
+	}
+	return fmt.Errorf("unknown version source %q, expected %s, %s or %s<channel>", source, sourceModule, sourceChannel, sourceFrom)
+}
+
+// Function to describe how the channels tagged together get their versions
+// under a version source
+func describeVersionSource(source string) string {
+	if channel, ok := strings.CutPrefix(source, sourceFrom); ok {
+		return "every channel gets the version following the latest one of " + channel
+	}
+	if source == sourceChannel {
+		return "each channel gets the version following its own latest one"
+	}
+	return "every channel gets the version following the highest one among them"
+}
+
+// Function to find the version scheme of a module: the one given with
+// --scheme, else the one configured for the module, else the repository one
+func schemeOf(module string) string {
+	if schemeOverride != "" {
+		return schemeOverride
+	}
+	if scheme, ok := moduleSchemes[module]; ok {
+		return scheme
+	}
+	return versionScheme
+}
+
+// Function to check that a version scheme is known
+func validateScheme(scheme string) error {
+	switch scheme {
+	case schemeSemver, schemeRollover, schemeCalver, schemeFourPart:
+		return nil
+	}
+	return fmt.Errorf("unknown scheme %q, expected %s, %s, %s or %s", scheme, schemeSemver, schemeRollover, schemeCalver, schemeFourPart)
+}
+
+// Config is the repository configuration read from .version.yaml at the root
+// of the working tree
+type Config struct {
+	// Scheme is the version scheme, semver when empty
+	Scheme   string                   `yaml:"scheme,omitempty"`
+	Modules  map[string]ModuleConfig  `yaml:"modules,omitempty"`
+	Channels map[string]ChannelConfig `yaml:"channels,omitempty"`
+	// ChannelDefaults, when set, is written for every new release channel
+	// instead of asking for its policy
+	ChannelDefaults *ChannelConfig `yaml:"channel_defaults,omitempty"`
+	// VersionSource is where the next version of modules configuring none
+	// comes from when several channels are tagged at once, module when empty
+	VersionSource string `yaml:"version_source,omitempty"`
+	// TagTemplate names tags from {{.Module}}, {{.Channel}} and
+	// {{.Version}}, {{.Module}}/{{.Channel}}/v{{.Version}} when empty
+	TagTemplate string `yaml:"tag_template,omitempty"`
+	// TagPattern is a regular expression discovering tags in place of the
+	// one derived from TagTemplate, capturing module, channel and version
+	TagPattern string `yaml:"tag_pattern,omitempty"`
+	// IgnoreTags are patterns of tags left out of module and channel
+	// discovery, in addition to those of .versionignore
+	IgnoreTags []string `yaml:"ignore_tags,omitempty"`
+	// NotesCommand rewrites generated release notes, reading them on stdin
+	// and printing the result
+	NotesCommand string `yaml:"notes_command,omitempty"`
+	// NotesClassifyCommand classifies each commit of the release notes,
+	// reading its message on stdin and printing the section it belongs to
+	NotesClassifyCommand string `yaml:"notes_classify_command,omitempty"`
+	// Monotonic forbids reusing a version of a module with different content
+	// on another channel: new versions skip past versions tagged on other
+	// commits
+	Monotonic bool `yaml:"monotonic,omitempty"`
+	// BuildMetadata is a template of the build metadata appended to created
+	// versions, such as {{.ShortCommit}} for v1.4.2+abc1234
+	BuildMetadata string `yaml:"build_metadata,omitempty"`
+	// PluginsDir holds the plugins receiving release events, relative to
+	// the repository root; .version/plugins when empty
+	PluginsDir string `yaml:"plugins_dir,omitempty"`
+	// TemporaryCommits recognise merge queue and bot commits that should not
+	// be tagged; common merge queues are recognised when it is not set
+	TemporaryCommits *TemporaryCommits `yaml:"temporary_commits,omitempty"`
+	Presets          map[string]Preset `yaml:"presets,omitempty"`
+	// Reproduce rebuilds released commits to check the published artifacts
+	// can be reproduced from them
+	Reproduce *ReproduceConfig `yaml:"reproduce,omitempty"`
+	// Pipelines are sequences of release channels a version is promoted
+	// along by `version pipeline run`
+	Pipelines map[string]Pipeline `yaml:"pipelines,omitempty"`
+	// Outputs receive the summary of every run that tags, in addition to
+	// stdout
+	Outputs []OutputConfig `yaml:"outputs,omitempty"`
+	// APICheck compares the exported API of Go modules with their latest
+	// version: warn or refuse when a breaking change lacks a major bump,
+	// off when empty
+	APICheck string `yaml:"api_check,omitempty"`
+	// StableChannel is the release channel whose latest release the
+	// <module>/stable tag of every module points at, none when empty
+	StableChannel string `yaml:"stable_channel,omitempty"`
+}
+
+// ModuleConfig holds per-module settings
+type ModuleConfig struct {
+	// Owners are git user emails or names (or CODEOWNERS handles) responsible
+	// for the module
+	Owners []string `yaml:"owners,omitempty"`
+	// Paths are the directories of the module in the repository
+	Paths []string `yaml:"paths,omitempty"`
+	// Archived modules are hidden from the pickers and cannot be tagged
+	Archived bool `yaml:"archived,omitempty"`
+	// Scheme is the version scheme of the module, the repository one when
+	// empty
+	Scheme string `yaml:"scheme,omitempty"`
+	// VersionSource is where the next version of the module comes from when
+	// several channels are tagged at once, module when empty
+	VersionSource string `yaml:"version_source,omitempty"`
+	// Support records the end of life of released versions, keyed by the
+	// constraint of the versions, such as 1.x
+	Support map[string]SupportWindow `yaml:"support,omitempty"`
+	// GoTags also tags the versions of a channel the way Go tooling expects
+	// of a module in a subdirectory, such as services/api/v1.4.2
+	GoTags *GoTags `yaml:"go_tags,omitempty"`
+}
+
+// ChannelConfig holds per-release-channel settings
+type ChannelConfig struct {
+	// Type is counter for channels numbering builds, such as b1042, instead
+	// of versioning releases, or snapshot for channels tagging timestamped
+	// prereleases of the next version, such as v1.5.0-20240610T0300Z
+	Type string `yaml:"type,omitempty"`
+	// Protected channels may only be tagged by the owners of a module
+	Protected bool `yaml:"protected,omitempty"`
+	// After is the channel releases graduate from before reaching this one
+	After string `yaml:"after,omitempty"`
+	// Notify lists where new tags on the channel are announced
+	Notify []string `yaml:"notify,omitempty"`
+	// Aliases moves floating major and major.minor alias tags, such as
+	// app/prod/v1 and app/prod/v1.4, to every new release
+	Aliases bool `yaml:"aliases,omitempty"`
+}
+
+// OutputConfig is a destination of run summaries
+type OutputConfig struct {
+	// To is a file path, an http(s):// URL the summary is posted to, or an
+	// s3:// or gs:// object
+	To string `yaml:"to"`
+	// Format is the summary format, json when empty
+	Format string `yaml:"format,omitempty"`
+	// Headers are sent with http outputs, ${VARIABLES} expanded from the
+	// environment
+	Headers map[string]string `yaml:"headers,omitempty"`
+}
+
+// TemporaryCommits describes commits created by merge queues or bots
+type TemporaryCommits struct {
+	// Authors are globs matched against the author name and email
+	Authors []string `yaml:"authors,omitempty"`
+	// Messages are regular expressions matched against the commit message
+	Messages []string `yaml:"messages,omitempty"`
+	// Refuse such commits instead of only warning about them
+	Refuse bool `yaml:"refuse,omitempty"`
+}
+
+// ReproduceConfig describes how to rebuild a release and which artifacts to
+// compare
+type ReproduceConfig struct {
+	// Command builds the artifacts in a clean worktree of the tagged commit
+	Command string `yaml:"command"`
+	// Artifacts are globs of the built files, relative to the worktree
+	Artifacts []string `yaml:"artifacts"`
+	// Published is the directory holding the published artifacts under the
+	// same paths, the repository root when empty
+	Published string `yaml:"published,omitempty"`
+}
+
+// Pipeline promotes a version along release channels, the first stage
+// tagging a new version and the others promoting it
+type Pipeline struct {
+	Stages []PipelineStage `yaml:"stages"`
+}
+
+// PipelineStage is a release channel of a pipeline and the gates a version
+// passes before reaching it
+type PipelineStage struct {
+	Channel string `yaml:"channel"`
+	// Command must succeed before the version is tagged on the channel
+	Command string `yaml:"command,omitempty"`
+	// Approval pauses the pipeline until someone approves the stage
+	Approval bool `yaml:"approval,omitempty"`
+}
+
+// Preset captures a recurring release as a named set of tagging options
+type Preset struct {
+	Modules []string `yaml:"modules"`
+	Channel string   `yaml:"channel"`
+	Commit  string   `yaml:"commit,omitempty"`
+	Bump    string   `yaml:"bump,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+	Verify  string   `yaml:"verify,omitempty"`
+	Mirror  string   `yaml:"mirror,omitempty"`
+	Push    bool     `yaml:"push,omitempty"`
+}
+
+// Function to locate the configuration file of the current repository
+func configPath() (string, error) {
+	root, err := gitOutput("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, configFileName), nil
+}
+
+// Function to read the repository configuration, returning an empty one when
+// the file does not exist. Unknown keys are rejected so a typo never silently
+// changes what a release does.
+func loadConfig() (*Config, error) {
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if _, err := configuredLayout(config.TagTemplate, config.TagPattern); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := validateIgnorePatterns(config.IgnoreTags); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if config.Scheme != "" {
+		if err := validateScheme(config.Scheme); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		versionScheme = config.Scheme
+	}
+	if config.VersionSource != "" {
+		if err := validateVersionSource(config.VersionSource); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		defaultVersionSource = config.VersionSource
+	}
+	for _, o := range config.Outputs {
+		if err := validateOutput(o); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	configuredOutputs = config.Outputs
+	if err := validateAPICheck(config.APICheck); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for name, module := range config.Modules {
+		for versions, window := range module.Support {
+			if err := validateSupportWindow(versions, window); err != nil {
+				return nil, fmt.Errorf("%s: module %s: %w", path, name, err)
+			}
+		}
+	}
+	counterChannels = make(map[string]bool)
+	snapshotChannels = make(map[string]bool)
+	aliasChannels = make(map[string]bool)
+	if config.StableChannel != "" {
+		if err := validateName("release channel", config.StableChannel); err != nil {
+			return nil, fmt.Errorf("%s: stable_channel: %w", path, err)
+		}
+	}
+	stableChannel = config.StableChannel
+	for name, channel := range config.Channels {
+		aliasChannels[name] = channel.Aliases
+		switch channel.Type {
+		case "":
+		case channelCounter:
+			counterChannels[name] = true
+		case channelSnapshot:
+			snapshotChannels[name] = true
+		default:
+			return nil, fmt.Errorf("%s: channel %s: unknown type %q, expected %s or %s", path, name, channel.Type, channelCounter, channelSnapshot)
+		}
+	}
+	moduleSchemes = make(map[string]string)
+	moduleVersionSources = make(map[string]string)
+	goTagModules = make(map[string]GoTags)
+	for name, module := range config.Modules {
+		if module.GoTags != nil {
+			if err := validateGoTags(*module.GoTags); err != nil {
+				return nil, fmt.Errorf("%s: module %s: %w", path, name, err)
+			}
+			goTagModules[name] = *module.GoTags
+		}
+		if module.Scheme != "" {
+			if err := validateScheme(module.Scheme); err != nil {
+				return nil, fmt.Errorf("%s: module %s: %w", path, name, err)
+			}
+			moduleSchemes[name] = module.Scheme
+		}
+		if module.VersionSource != "" {
+			if err := validateVersionSource(module.VersionSource); err != nil {
+				return nil, fmt.Errorf("%s: module %s: %w", path, name, err)
+			}
+			moduleVersionSources[name] = module.VersionSource
+		}
+	}
+	if versionSourceOverride != "" {
+		if err := validateVersionSource(versionSourceOverride); err != nil {
+			return nil, err
+		}
+	}
+	if schemeOverride != "" {
+		if err := validateScheme(schemeOverride); err != nil {
+			return nil, err
+		}
+		versionScheme = schemeOverride
+	}
+	return &config, nil
+}
+
+// Function to set a value at the given key path in the configuration file,
+// creating the file and intermediate mappings as needed. The file is edited
+// as a YAML node tree so comments and ordering are preserved.
+func setConfigValue(keys []string, value any) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	var valueNode yaml.Node
+	if err := valueNode.Encode(value); err != nil {
+		return err
+	}
+
+	node := doc.Content[0]
+	for i, key := range keys {
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s: %s is not a mapping", path, strings.Join(keys[:i], "."))
+		}
+		var child *yaml.Node
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value == key {
+				child = node.Content[j+1]
+				break
+			}
+		}
+		last := i == len(keys)-1
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode}
+			if last {
+				child = &valueNode
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, child)
+		} else if last {
+			*child = valueNode
+		}
+		node = child
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return err
+	}
+	return writeFile(path, out.Bytes(), 0o644)
+}
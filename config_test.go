@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigPathFromSubdirectory(t *testing.T) {
+	root, _ := newTestRepo(t)
+	commitFile(t, "services/api/main.go", "package main\n")
+	want := filepath.Join(root, configFileName)
+	if err := os.WriteFile(want, []byte("scheme: semver\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repoDir = filepath.Join(root, "services", "api")
+
+	path, err := configPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The temporary directory may be reached through a symbolic link, as
+	// on macOS, or a short name, as on Windows, so compare the files
+	got, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("configPath() = %s: %v", path, err)
+	}
+	wantInfo, err := os.Stat(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(got, wantInfo) {
+		t.Fatalf("configPath() = %s, want %s", path, want)
+	}
+	if filepath.Base(path) != configFileName || !filepath.IsAbs(path) {
+		t.Fatalf("configPath() = %s, want an absolute path to %s", path, configFileName)
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Scheme != "semver" {
+		t.Fatalf("scheme = %q, want semver", config.Scheme)
+	}
+}
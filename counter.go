@@ -0,0 +1,53 @@
+package main
+
+import "fmt"
+
+// channelCounter is the type of release channels numbering builds with an
+// ever increasing counter, such as app/ci/b1042, instead of versions
+const channelCounter = "counter"
+
+// counterChannels are the release channels of the counter type, set when
+// the configuration is loaded
+var counterChannels map[string]bool
+
+// Function to tell whether a release channel numbers builds with a counter
+func isCounterChannel(channel string) bool {
+	return counterChannels[channel]
+}
+
+// Function to read the latest counter of a module on a counter channel, b0
+// when it has none yet
+func currentCounter(idx *tagIndex, module, channel string) Version {
+	if v, ok := idx.latest[module][channel]; ok && v.Counter {
+		return v
+	}
+	return Version{Counter: true}
+}
+
+// Function to compute the counter following v. Counters only go up by one,
+// so they take no minor or major bump.
+func nextCounter(v Version, part string) (Version, error) {
+	if part != "patch" {
+		return v, fmt.Errorf("build counters only go up by one and take no %s bump", part)
+	}
+	return Version{Patch: v.Patch + 1, Counter: true}, nil
+}
+
+// Function to check that the bump selected on the command line applies to
+// the counter channels among the given ones
+func checkCounterBump(channels []string) error {
+	for _, channel := range channels {
+		counter := isCounterChannel(channel)
+		switch {
+		case explicitVersion != nil && counter && !explicitVersion.Counter:
+			return fmt.Errorf("%s is a counter channel, --set needs a counter such as b42", channel)
+		case explicitVersion != nil && !counter && explicitVersion.Counter:
+			return fmt.Errorf("%s is not a counter channel, --set needs a version such as 1.4.2", channel)
+		case explicitVersion == nil && counter && bumpPart != "patch" && bumpPart != bumpAuto:
+			return fmt.Errorf("%s is a counter channel, which takes no %s bump", channel, bumpPart)
+		case explicitVersion == nil && counter && prereleaseName != "":
+			return fmt.Errorf("%s is a counter channel, which takes no prerelease", channel)
+		}
+	}
+	return nil
+}
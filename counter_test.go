@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestNextCounter(t *testing.T) {
+	next, err := nextCounter(Version{Patch: 1, Counter: true}, "patch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := next.String(); got != "b2" {
+		t.Fatalf("nextCounter(b1) = %s, want b2", got)
+	}
+}
+
+func TestNextCounterOnEmptyChannel(t *testing.T) {
+	idx := newTagIndex()
+	idx.add("app", "prod", Version{Major: 1, Minor: 4})
+
+	current := currentCounter(idx, "app", "ci")
+	if got := current.String(); got != "b0" {
+		t.Fatalf("currentCounter = %s, want b0", got)
+	}
+	next, err := nextCounter(current, "patch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := next.String(); got != "b1" {
+		t.Fatalf("first counter = %s, want b1", got)
+	}
+}
+
+func TestNextCounterRejectsParts(t *testing.T) {
+	for _, part := range []string{"minor", "major", "build"} {
+		if _, err := nextCounter(Version{Patch: 7, Counter: true}, part); err == nil {
+			t.Errorf("nextCounter accepted a %s bump", part)
+		}
+	}
+}
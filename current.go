@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Function to handle `version current`, printing the highest existing
+// version of a module on the given release channels
+func runCurrent(args []string) int {
+	fs := flag.NewFlagSet("current", flag.ExitOnError)
+	fs.StringVar(&moduleName, "m", "", "module name")
+	fs.StringVar(&releaseChannel, "r", "", "release channel, or a comma separated list")
+	fs.BoolVar(&ignoreCase, "i", false, "match module and release channel names case-insensitively, normalizing input")
+	full := fs.Bool("full", false, "print the full tag name instead of the version")
+	fs.Parse(args)
+	moduleName, releaseChannel = impliedNames(moduleName, releaseChannel)
+
+	if moduleName == "" || releaseChannel == "" {
+		log.Error().Msg("both -m and -r are required")
+		return 2
+	}
+	if isPattern(moduleName) {
+		log.Error().Str("module", moduleName).Msg("current needs a single module, not a pattern")
+		return 2
+	}
+
+	idx, err := scanTagIndex()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	moduleName = resolveName(moduleName, idx.modules())
+	targets, channels, err := resolveTargets(idx, moduleName, releaseChannel)
+	if err != nil {
+		log.Error().Err(err).Msg("invalid module or release channel entered")
+		return 1
+	}
+
+	module := targets[0]
+	version, channel, ok := idx.latestTag(module, channels)
+	if !ok {
+		log.Error().Str("module", module).Strs("channels", channels).Msg("no version tags found")
+		return 1
+	}
+	if *full {
+		fmt.Println(formatTag(module, channel, version))
+	} else {
+		fmt.Println(version)
+	}
+	return 0
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Function to handle `version delete <tag>...`, deleting tags locally and
+// optionally from the remote. Soft deletes leave a tombstone in their place.
+func runDelete(args []string) int {
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+	remote := fs.Bool("remote", false, "also delete the tags from the remote")
+	remoteName := fs.String("remote-name", "origin", "remote to delete the tags from")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	soft := fs.Bool("soft", false, "replace the tags with deleted/<tag> tombstones hidden from every listing")
+	reason := fs.String("reason", "", "why the tags are soft-deleted, recorded in their tombstones")
+	list := fs.Bool("list", false, "list the soft-deleted tags")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: version delete [flags] <tag>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *list {
+		if err := listTombstones(); err != nil {
+			log.Error().Err(err).Msg("unable to read tags")
+			return 1
+		}
+		return 0
+	}
+	if *reason != "" && !*soft {
+		log.Error().Msg("--reason needs --soft")
+		return 2
+	}
+	tags := fs.Args()
+	if len(tags) == 0 {
+		fs.Usage()
+		return 2
+	}
+	for _, tag := range tags {
+		if _, err := gitOutput("rev-parse", "--verify", "--quiet", "refs/tags/"+tag); err != nil {
+			log.Error().Str("tag", tag).Msg("no such tag")
+			return 1
+		}
+		if isTombstone(tag) && *soft {
+			log.Error().Str("tag", tag).Msg("tag is already a tombstone")
+			return 1
+		}
+	}
+
+	if !*yes {
+		where := "locally"
+		if *remote {
+			where = "locally and from " + *remoteName
+		}
+		action := "Delete"
+		if *soft {
+			action = "Soft-delete"
+		}
+		if !confirm(fmt.Sprintf("%s %s %s", action, strings.Join(tags, ", "), where)) {
+			log.Info().Msg("Nothing deleted")
+			return 1
+		}
+	}
+
+	failed := false
+	for _, tag := range tags {
+		if *soft {
+			tombstone, err := createTombstone(tag, *reason)
+			if err != nil {
+				log.Error().Err(err).Str("tag", tag).Msg("unable to create tombstone")
+				failed = true
+				continue
+			}
+			if *remote {
+				if err := pushRefspec(pushTarget{Remote: *remoteName}, "refs/tags/"+tombstone+":refs/tags/"+tombstone); err != nil {
+					log.Error().Err(err).Str("tag", tombstone).Str("remote", *remoteName).Msg("unable to push tombstone")
+					failed = true
+					continue
+				}
+			}
+			log.Info().Str("tag", tag).Str("tombstone", tombstone).Msg("Tombstone created")
+		}
+		if *remote {
+			if err := pushRefspec(pushTarget{Remote: *remoteName}, ":refs/tags/"+tag); err != nil {
+				log.Error().Err(err).Str("tag", tag).Str("remote", *remoteName).Msg("unable to delete remote tag")
+				failed = true
+				continue
+			}
+			log.Info().Str("tag", tag).Str("remote", *remoteName).Msg("Remote tag deleted")
+		}
+		if _, err := gitOutput("tag", "--delete", tag); err != nil {
+			log.Error().Err(err).Str("tag", tag).Msg("unable to delete tag")
+			failed = true
+			continue
+		}
+		log.Info().Str("tag", tag).Msg("Tag deleted")
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+)
+
+// fileChange is the line count of a file changed between two tags
+type fileChange struct {
+	Path       string
+	Insertions string
+	Deletions  string
+}
+
+// Function to resolve a tag of a module given as a full tag, as
+// channel/vX.Y.Z, or as a version on the channel given with -r
+func resolveModuleTag(module, channel, arg string) (string, error) {
+	_, _, _, isTag := parseTag(arg)
+	if c, v, found := strings.Cut(arg, "/"); !isTag && found {
+		version, err := parseVersion(v)
+		if err != nil {
+			return "", err
+		}
+		arg = formatTag(module, c, version)
+	} else if !isTag && channel == "" {
+		return "", fmt.Errorf("%q is neither a tag nor channel/vX.Y.Z, and no -r was given", arg)
+	}
+	tag, err := resolveVersionTag(module, channel, arg)
+	if err != nil {
+		return "", err
+	}
+	if _, err := resolveCommit("refs/tags/" + tag); err != nil {
+		return "", fmt.Errorf("no such tag %s", tag)
+	}
+	return tag, nil
+}
+
+// Function to list the files changed between two tags, limited to paths when
+// there are any. Binary files have - as line counts.
+func readFileChanges(from, to string, paths []string) ([]fileChange, error) {
+	args := []string{"diff", "--numstat", from, to}
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+	out, err := gitOutput(args...)
+	if err != nil {
+		return nil, err
+	}
+	var changes []fileChange
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) == 3 {
+			changes = append(changes, fileChange{Path: fields[2], Insertions: fields[0], Deletions: fields[1]})
+		}
+	}
+	return changes, nil
+}
+
+// Function to add up the lines inserted and deleted by file changes, binary
+// files counting for none
+func changeTotals(changes []fileChange) (int, int) {
+	insertions, deletions := 0, 0
+	for _, c := range changes {
+		var n int
+		if _, err := fmt.Sscan(c.Insertions, &n); err == nil {
+			insertions += n
+		}
+		if _, err := fmt.Sscan(c.Deletions, &n); err == nil {
+			deletions += n
+		}
+	}
+	return insertions, deletions
+}
+
+// Function to handle `version diff`, summarizing the files and commits that
+// changed between two tagged versions of a module
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.StringVar(&moduleName, "m", "", "module name")
+	fs.StringVar(&releaseChannel, "r", "", "release channel of --from and --to when they are bare versions")
+	fromArg := fs.String("from", "", "tag to compare from: a full tag, channel/vX.Y.Z, or a version with -r")
+	toArg := fs.String("to", "", "tag to compare to, in the same forms as --from")
+	fs.Parse(args)
+	moduleName, releaseChannel = impliedNames(moduleName, releaseChannel)
+
+	if moduleName == "" || *fromArg == "" || *toArg == "" {
+		log.Error().Msg("-m, --from and --to are required")
+		return 2
+	}
+	config, err := loadConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read configuration")
+		return 1
+	}
+	from, err := resolveModuleTag(moduleName, releaseChannel, *fromArg)
+	if err != nil {
+		log.Error().Err(err).Msg("invalid --from")
+		return 1
+	}
+	to, err := resolveModuleTag(moduleName, releaseChannel, *toArg)
+	if err != nil {
+		log.Error().Err(err).Msg("invalid --to")
+		return 1
+	}
+
+	paths := config.Modules[moduleName].Paths
+	changes, err := readFileChanges(from, to, paths)
+	if err != nil {
+		log.Error().Err(err).Msg("unable to compare tags")
+		return 1
+	}
+	entries, err := readChangelog(from, to, paths)
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read commits")
+		return 1
+	}
+
+	insertions, deletions := changeTotals(changes)
+	fmt.Printf("Changes from %s to %s\n", from, to)
+	if len(paths) > 0 {
+		fmt.Printf("Limited to %s\n", strings.Join(paths, ", "))
+	}
+	fmt.Printf("\n%d files changed, %d insertions(+), %d deletions(-)\n\n", len(changes), insertions, deletions)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for _, c := range changes {
+		fmt.Fprintf(tw, "+%s\t-%s\t\t%s\n", c.Insertions, c.Deletions, c.Path)
+	}
+	tw.Flush()
+
+	fmt.Printf("\n%d commits\n", len(entries))
+	for _, e := range entries {
+		fmt.Printf("- %s (%s, %s)\n", e.Subject, shortHash(e.Hash), e.Author)
+	}
+	return 0
+}
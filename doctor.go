@@ -0,0 +1,185 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+)
+
+// problem is a single finding reported by `version doctor`
+type problem struct {
+	Kind   string
+	Tag    string
+	Detail string
+}
+
+// Function to find tags that look like version tags but do not follow the
+// module/channel/vX.Y.Z layout or the naming rules
+func malformedTags() ([]problem, error) {
+	var problems []problem
+	err := streamTags(func(tag string) {
+		module, channel, _, ok := parseTag(tag)
+		switch {
+		case isIgnoredTag(tag) || isTombstone(tag) || isYankMarker(tag) || isAliasTag(tag):
+		case !ok && layout.nearMiss.MatchString(tag):
+			problems = append(problems, problem{"malformed", tag, "expected " + layout.format("module", "channel", "X.Y.Z")})
+		case ok:
+			if err := validateName("module", module); err != nil {
+				problems = append(problems, problem{"malformed", tag, err.Error()})
+			} else if err := validateName("release channel", channel); err != nil {
+				problems = append(problems, problem{"malformed", tag, err.Error()})
+			}
+		}
+	})
+	return problems, err
+}
+
+// Function to find versions of a module tagged on several channels against
+// different commits
+func duplicateVersions(entries []tagEntry) []problem {
+	type key struct {
+		module  string
+		version Version
+	}
+	groups := make(map[key][]tagEntry)
+	for _, e := range entries {
+		k := key{e.Module, e.Version}
+		groups[k] = append(groups[k], e)
+	}
+
+	var problems []problem
+	for _, group := range groups {
+		for _, e := range group[1:] {
+			if e.Commit != group[0].Commit {
+				problems = append(problems, problem{"duplicate", e.Tag,
+					fmt.Sprintf("same version as %s but commit %s instead of %s", group[0].Tag, shortHash(e.Commit), shortHash(group[0].Commit))})
+			}
+		}
+	}
+	return problems
+}
+
+// Function to find versions that do not follow from the previous version on
+// their channel by a single patch, minor or major bump
+func versionGaps(entries []tagEntry) []problem {
+	versions := make(map[string][]tagEntry)
+	for _, e := range entries {
+		versions[e.Module+"/"+e.Channel] = append(versions[e.Module+"/"+e.Channel], e)
+	}
+
+	var problems []problem
+	for _, sequence := range versions {
+		slices.SortFunc(sequence, func(a, b tagEntry) int { return compareVersions(a.Version, b.Version) })
+		for i := 1; i < len(sequence); i++ {
+			// Prereleases are judged by the version they lead to, so rc.1,
+			// rc.2 and the release of the same version follow each other
+			previous, current := sequence[i-1].Version.Release(), sequence[i].Version.Release()
+			if compareVersions(previous, current) == 0 {
+				continue
+			}
+			if current.Counter {
+				if next, _ := nextCounter(previous, "patch"); compareVersions(next, current) != 0 {
+					problems = append(problems, problem{"gap", sequence[i].Tag, fmt.Sprintf("follows %s, expected %s", previous, next)})
+				}
+				continue
+			}
+			scheme := schemeOf(sequence[i].Module)
+			if scheme == schemeCalver {
+				// Calendar versions go on within a month and start over at 1
+				// in a later one
+				next := Version{Major: current.Major, Minor: current.Minor, Patch: 1, ZeroPadded: true}
+				if previous.Major == current.Major && previous.Minor == current.Minor {
+					next = previous
+					next.Patch++
+				}
+				if compareVersions(next, current) != 0 {
+					problems = append(problems, problem{"gap", sequence[i].Tag, fmt.Sprintf("follows %s, expected %s", previous, next)})
+				}
+				continue
+			}
+			var expected []string
+			found := false
+			parts := []string{"patch", "minor", "major"}
+			if scheme == schemeFourPart {
+				parts = append(parts, "build")
+			}
+			for _, part := range parts {
+				next, _ := bumpVersion(previous, part, scheme)
+				found = found || compareVersions(next, current) == 0
+				if !slices.Contains(expected, next.String()) {
+					expected = append(expected, next.String())
+				}
+			}
+			if !found {
+				problems = append(problems, problem{"gap", sequence[i].Tag,
+					fmt.Sprintf("follows %s, expected one of %s", previous, strings.Join(expected, ", "))})
+			}
+		}
+	}
+	return problems
+}
+
+// Function to find tags whose commit is not reachable from any branch
+func unreachableTags(entries []tagEntry) []problem {
+	reachable := make(map[string]bool)
+	var problems []problem
+	for _, e := range entries {
+		ok, checked := reachable[e.Commit]
+		if !checked {
+			var err error
+			if ok, err = isReachable(e.Commit); err != nil {
+				log.Warn().Err(err).Str("tag", e.Tag).Msg("unable to check whether tag is reachable")
+				ok = true
+			}
+			reachable[e.Commit] = ok
+		}
+		if !ok {
+			problems = append(problems, problem{"unreachable", e.Tag, "commit " + shortHash(e.Commit) + " is not on any branch"})
+		}
+	}
+	return problems
+}
+
+// Function to handle `version doctor`, checking every tag for problems and
+// exiting with a failure when any is found
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	fs.Parse(args)
+
+	if _, err := loadConfig(); err != nil {
+		log.Error().Err(err).Msg("unable to read configuration")
+		return 1
+	}
+	problems, err := malformedTags()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	entries, err := readTagEntries(func(string, string) bool { return true })
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	problems = append(problems, duplicateVersions(entries)...)
+	problems = append(problems, versionGaps(entries)...)
+	problems = append(problems, unreachableTags(entries)...)
+	problems = append(problems, goTagProblems(entries)...)
+
+	if len(problems) == 0 {
+		log.Info().Int("tags", len(entries)).Msg("No problems found")
+		return 0
+	}
+	slices.SortStableFunc(problems, func(a, b problem) int { return strings.Compare(a.Tag, b.Tag) })
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROBLEM\tTAG\tDETAIL")
+	for _, p := range problems {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", p.Kind, p.Tag, p.Detail)
+	}
+	tw.Flush()
+	return 1
+}
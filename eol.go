@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Support states of a released version
+const (
+	supportActive     = "supported"
+	supportDeprecated = "deprecated"
+	supportNearing    = "nearing-eol"
+	supportEnded      = "eol"
+)
+
+// defaultEOLWithin is how close an end of life is reported as nearing
+const defaultEOLWithin = "30d"
+
+// SupportWindow is how long the versions matching a constraint are
+// supported, recorded under the support of a module keyed by the constraint,
+// such as 1.x or 1.4
+type SupportWindow struct {
+	// Deprecated is the date the versions stopped being recommended,
+	// YYYY-MM-DD
+	Deprecated string `yaml:"deprecated,omitempty"`
+	// EOL is the date support ends, YYYY-MM-DD
+	EOL string `yaml:"eol"`
+	// Note tells users what to do, such as "upgrade to 2.x"
+	Note string `yaml:"note,omitempty"`
+}
+
+// versionSupport is the support of a released version, as reported by
+// `version eol`
+type versionSupport struct {
+	Module     string   `json:"module" yaml:"module"`
+	Version    string   `json:"version" yaml:"version"`
+	Tags       []string `json:"tags" yaml:"tags"`
+	Versions   string   `json:"versions" yaml:"versions"`
+	Status     string   `json:"status" yaml:"status"`
+	Deprecated string   `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	EOL        string   `json:"eol" yaml:"eol"`
+	DaysLeft   int      `json:"days_left" yaml:"days_left"`
+	Note       string   `json:"note,omitempty" yaml:"note,omitempty"`
+}
+
+// Function to check a support window and the constraint it is recorded for
+func validateSupportWindow(versions string, w SupportWindow) error {
+	if _, err := parseConstraint(versions); err != nil {
+		return err
+	}
+	if _, err := time.Parse(time.DateOnly, w.EOL); err != nil {
+		return fmt.Errorf("support of %s: invalid eol %q, expected YYYY-MM-DD", versions, w.EOL)
+	}
+	if w.Deprecated != "" {
+		if _, err := time.Parse(time.DateOnly, w.Deprecated); err != nil {
+			return fmt.Errorf("support of %s: invalid deprecated %q, expected YYYY-MM-DD", versions, w.Deprecated)
+		}
+	}
+	return nil
+}
+
+// Function to find the support window of a version of a module. When
+// several constraints match it, the earliest end of life applies. A
+// prerelease is supported with the release it leads to, so 2.0.0-rc.1
+// belongs to 2.x rather than to 1.x.
+func supportWindowOf(config *Config, module string, v Version) (string, SupportWindow, bool) {
+	var versions string
+	var window SupportWindow
+	found := false
+	for constraint, w := range config.Modules[module].Support {
+		c, err := parseConstraint(constraint)
+		if err != nil || !c.matches(v.Release(), false) {
+			continue
+		}
+		if !found || w.EOL < window.EOL || w.EOL == window.EOL && constraint < versions {
+			versions, window, found = constraint, w, true
+		}
+	}
+	return versions, window, found
+}
+
+// Function to tell the support state of a window at a moment: ended after
+// its end of life, nearing it within the given duration, deprecated after
+// its deprecation date, else supported
+func supportStatus(w SupportWindow, now time.Time, within time.Duration) (string, int) {
+	eol, _ := time.ParseInLocation(time.DateOnly, w.EOL, time.Local)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	days := int(eol.Sub(today).Hours() / 24)
+	switch {
+	case !today.Before(eol):
+		return supportEnded, days
+	case eol.Sub(today) <= within:
+		return supportNearing, days
+	case w.Deprecated != "" && w.Deprecated <= today.Format(time.DateOnly):
+		return supportDeprecated, days
+	}
+	return supportActive, days
+}
+
+// Function to handle `version eol`, reporting the released versions that
+// are past or nearing the end of their support, or recording a support
+// window with `version eol set`
+func runEOL(args []string) int {
+	if len(args) > 0 && args[0] == "set" {
+		return runEOLSet(args[1:])
+	}
+	fs := flag.NewFlagSet("eol", flag.ExitOnError)
+	fs.StringVar(&moduleName, "m", "", "module name (default every module with support windows)")
+	fs.StringVar(&releaseChannel, "r", "", "release channel, or a comma separated list (default every channel)")
+	withinArg := fs.String("within", defaultEOLWithin, "report versions whose end of life is this close, such as 90d")
+	all := fs.Bool("all", false, "also report supported and deprecated versions")
+	output := fs.String("output", "", "print the report as json or yaml instead of a table")
+	fs.Parse(args)
+	moduleName, releaseChannel = impliedNames(moduleName, releaseChannel)
+
+	within, err := parseAge(*withinArg)
+	if err != nil {
+		log.Error().Err(err).Msg("invalid --within")
+		return 2
+	}
+	if *output != "" && *output != formatJSON && *output != formatYAML {
+		log.Error().Str("output", *output).Msg("unknown output format, expected json or yaml")
+		return 2
+	}
+	config, err := loadConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read configuration")
+		return 1
+	}
+	var channels []string
+	if releaseChannel != "" {
+		channels = strings.Split(releaseChannel, ",")
+	}
+	entries, err := readTagEntries(func(m, c string) bool {
+		return (moduleName == "" || m == moduleName) && len(config.Modules[m].Support) > 0 &&
+			(channels == nil || slices.Contains(channels, c))
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+
+	now := time.Now()
+	report := []versionSupport{}
+	for _, e := range entries {
+		if e.Version.Counter {
+			continue
+		}
+		version := e.Version
+		version.Build = ""
+		i := slices.IndexFunc(report, func(s versionSupport) bool { return s.Module == e.Module && s.Version == version.String() })
+		if i >= 0 {
+			report[i].Tags = append(report[i].Tags, e.Tag)
+			continue
+		}
+		versions, window, ok := supportWindowOf(config, e.Module, e.Version)
+		if !ok {
+			continue
+		}
+		status, days := supportStatus(window, now, within)
+		report = append(report, versionSupport{
+			Module: e.Module, Version: version.String(), Tags: []string{e.Tag}, Versions: versions,
+			Status: status, Deprecated: window.Deprecated, EOL: window.EOL, DaysLeft: days, Note: window.Note,
+		})
+	}
+	if !*all {
+		report = slices.DeleteFunc(report, func(s versionSupport) bool {
+			return s.Status != supportEnded && s.Status != supportNearing
+		})
+	}
+	slices.SortStableFunc(report, func(a, b versionSupport) int {
+		if a.Module != b.Module {
+			return strings.Compare(a.Module, b.Module)
+		}
+		va, _ := parseVersion(a.Version)
+		vb, _ := parseVersion(b.Version)
+		return compareVersions(va, vb)
+	})
+
+	switch *output {
+	case formatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	case formatYAML:
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		if err = encoder.Encode(report); err == nil {
+			err = encoder.Close()
+		}
+	default:
+		if len(report) == 0 {
+			log.Info().Str("within", *withinArg).Msg("No released version is past or nearing its end of life")
+			return 0
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "MODULE\tVERSION\tSTATUS\tEOL\tDAYS\tNOTE")
+		for _, s := range report {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n", s.Module, s.Version, s.Status, s.EOL, s.DaysLeft, s.Note)
+		}
+		tw.Flush()
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("unable to write output")
+		return 1
+	}
+	return 0
+}
+
+// Function to handle `version eol set`, recording the support window of the
+// versions of a module matching a constraint in the configuration
+func runEOLSet(args []string) int {
+	fs := flag.NewFlagSet("eol set", flag.ExitOnError)
+	fs.StringVar(&moduleName, "m", "", "module name")
+	versions := fs.String("versions", "", "versions the window applies to, as a constraint such as 1.x, 1.4 or 1.4.2")
+	eol := fs.String("date", "", "end of life date, YYYY-MM-DD")
+	deprecated := fs.String("deprecated", "", "date the versions stop being recommended, YYYY-MM-DD")
+	note := fs.String("note", "", "what users should do, such as \"upgrade to 2.x\"")
+	fs.Parse(args)
+	moduleName, _ = impliedNames(moduleName, "")
+
+	if moduleName == "" || *versions == "" || *eol == "" {
+		log.Error().Msg("-m, --versions and --date are required")
+		return 2
+	}
+	window := SupportWindow{Deprecated: *deprecated, EOL: *eol, Note: *note}
+	if err := validateSupportWindow(*versions, window); err != nil {
+		log.Error().Err(err).Msg("invalid support window")
+		return 2
+	}
+	if err := validateModule(moduleName); err != nil {
+		log.Error().Err(err).Msg("invalid module")
+		return 2
+	}
+	if err := setConfigValue([]string{"modules", moduleName, "support", *versions}, window); err != nil {
+		log.Error().Err(err).Msg("unable to update configuration")
+		return 1
+	}
+	log.Info().Str("module", moduleName).Str("versions", *versions).Str("eol", *eol).Msg("Support window recorded in " + configFileName)
+	return 0
+}
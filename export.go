@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// exportSchema creates the tables of an export
+const exportSchema = `CREATE TABLE modules (
+  name TEXT PRIMARY KEY,
+  archived INTEGER NOT NULL,
+  owners TEXT
+);
+CREATE TABLE channels (
+  name TEXT PRIMARY KEY,
+  protected INTEGER NOT NULL,
+  after TEXT
+);
+CREATE TABLE commits (
+  hash TEXT PRIMARY KEY,
+  author TEXT NOT NULL,
+  author_email TEXT NOT NULL,
+  committed_at TEXT NOT NULL,
+  subject TEXT NOT NULL
+);
+CREATE TABLE releases (
+  tag TEXT PRIMARY KEY,
+  module TEXT NOT NULL REFERENCES modules(name),
+  channel TEXT NOT NULL REFERENCES channels(name),
+  version TEXT NOT NULL,
+  major INTEGER NOT NULL,
+  minor INTEGER NOT NULL,
+  patch INTEGER NOT NULL,
+  prerelease TEXT NOT NULL,
+  commit_hash TEXT NOT NULL REFERENCES commits(hash),
+  tagger TEXT NOT NULL,
+  created_at TEXT NOT NULL
+);
+CREATE INDEX releases_module_channel ON releases(module, channel);
+`
+
+// Function to quote a value as an SQL string literal
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// Function to quote a boolean as an SQL integer
+func sqlBool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// Function to read the details of commits, keyed by hash
+func readCommits(hashes []string) (map[string][]string, error) {
+	commits := make(map[string][]string)
+	if len(hashes) == 0 {
+		return commits, nil
+	}
+	out, err := gitOutputWithInput(strings.Join(hashes, "\n")+"\n",
+		"log", "--no-walk=unsorted", "--stdin", "--format=%H%x09%an%x09%ae%x09%cI%x09%s")
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 5)
+		if len(fields) == 5 {
+			commits[fields[0]] = fields[1:]
+		}
+	}
+	return commits, nil
+}
+
+// Function to write the SQL statements materializing every release, with
+// its module, channel and commit
+func exportSQL(config *Config) (string, int, error) {
+	entries, err := readTagEntries(func(string, string) bool { return true })
+	if err != nil {
+		return "", 0, err
+	}
+	modules, channels := make(map[string]bool), make(map[string]bool)
+	var hashes []string
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		modules[e.Module], channels[e.Channel] = true, true
+		if !seen[e.Commit] {
+			seen[e.Commit] = true
+			hashes = append(hashes, e.Commit)
+		}
+	}
+	for module := range config.Modules {
+		modules[module] = true
+	}
+	for channel := range config.Channels {
+		channels[channel] = true
+	}
+	commits, err := readCommits(hashes)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var sql strings.Builder
+	sql.WriteString("BEGIN;\n")
+	sql.WriteString(exportSchema)
+	for _, module := range sortedKeys(modules) {
+		owners := strings.Join(config.Modules[module].Owners, ",")
+		fmt.Fprintf(&sql, "INSERT INTO modules VALUES (%s, %s, %s);\n", sqlString(module), sqlBool(isArchived(config, module)), sqlString(owners))
+	}
+	for _, channel := range sortedKeys(channels) {
+		policy := config.Channels[channel]
+		fmt.Fprintf(&sql, "INSERT INTO channels VALUES (%s, %s, %s);\n", sqlString(channel), sqlBool(policy.Protected), sqlString(policy.After))
+	}
+	for _, hash := range hashes {
+		c, ok := commits[hash]
+		if !ok {
+			// Commits missing from a partial or shallow clone keep their hash
+			c = []string{"", "", "", ""}
+		}
+		fmt.Fprintf(&sql, "INSERT INTO commits VALUES (%s, %s, %s, %s, %s);\n", sqlString(hash), sqlString(c[0]), sqlString(c[1]), sqlString(c[2]), sqlString(c[3]))
+	}
+	for _, e := range entries {
+		fmt.Fprintf(&sql, "INSERT INTO releases VALUES (%s, %s, %s, %s, %d, %d, %d, %s, %s, %s, %s);\n",
+			sqlString(e.Tag), sqlString(e.Module), sqlString(e.Channel), sqlString(e.Version.String()),
+			e.Version.Major, e.Version.Minor, e.Version.Patch, sqlString(e.Version.Prerelease),
+			sqlString(e.Commit), sqlString(e.Tagger), sqlString(e.Date.UTC().Format(time.RFC3339)))
+	}
+	sql.WriteString("COMMIT;\n")
+	return sql.String(), len(entries), nil
+}
+
+// Function to list the keys of a set in sorted order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Function to handle `version export`, materializing the release metadata
+// into an SQLite database for ad-hoc queries. The database is built with the
+// sqlite3 command line tool; --sql prints the statements instead.
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	database := fs.String("sqlite", "", "SQLite database file to write, replaced when it exists")
+	sqlOnly := fs.Bool("sql", false, "print the SQL statements on stdout instead of running sqlite3")
+	fs.Parse(args)
+
+	if (*database == "") == !*sqlOnly {
+		log.Error().Msg("exactly one of --sqlite and --sql is required")
+		return 2
+	}
+	config, err := loadConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read configuration")
+		return 1
+	}
+	sql, releases, err := exportSQL(config)
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read releases")
+		return 1
+	}
+	if *sqlOnly {
+		fmt.Print(sql)
+		return 0
+	}
+
+	sqlite, err := exec.LookPath("sqlite3")
+	if err != nil {
+		log.Error().Err(err).Msg("the sqlite3 command is needed, or use --sql and load the statements yourself")
+		return 1
+	}
+	if err := checkWritable(); err != nil {
+		log.Error().Err(err).Str("file", *database).Msg("unable to create database")
+		return 1
+	}
+	// Build next to the target and rename, so an existing database is only
+	// replaced by a complete one
+	temp, err := os.CreateTemp(filepath.Dir(*database), "."+filepath.Base(*database)+"-*")
+	if err != nil {
+		log.Error().Err(err).Msg("unable to create database")
+		return 1
+	}
+	temp.Close()
+	os.Remove(temp.Name())
+	defer os.Remove(temp.Name())
+
+	cmd := exec.Command(sqlite, "-bail", temp.Name())
+	cmd.Stdin = strings.NewReader(sql)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		log.Error().Err(err).Str("output", strings.TrimSpace(stderr.String())).Msg("sqlite3 failed")
+		return 1
+	}
+	if err := os.Rename(temp.Name(), *database); err != nil {
+		log.Error().Err(err).Msg("unable to write database")
+		return 1
+	}
+	log.Info().Str("database", *database).Int("releases", releases).Msg("Releases exported")
+	return 0
+}
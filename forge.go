@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var scpLikeURL = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+
+// Function to turn a git remote URL into the https base URL of the repository
+// and the forge hosting it, or empty strings for unknown hosts
+func forgeBaseURL(remoteURL string) (string, string) {
+	var host, repoPath string
+	if u, err := url.Parse(remoteURL); err == nil && u.Scheme != "" && u.Host != "" {
+		host, repoPath = u.Hostname(), u.Path
+	} else if m := scpLikeURL.FindStringSubmatch(remoteURL); m != nil {
+		host, repoPath = m[1], m[2]
+	} else {
+		return "", ""
+	}
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	if repoPath == "" {
+		return "", ""
+	}
+
+	var forge string
+	switch {
+	case strings.Contains(host, "github"):
+		forge = "github"
+	case strings.Contains(host, "gitlab"):
+		forge = "gitlab"
+	case strings.Contains(host, "bitbucket"):
+		forge = "bitbucket"
+	default:
+		return "", ""
+	}
+	return "https://" + host + "/" + repoPath, forge
+}
+
+// Function to build the compare view URL between two tags on the forge
+// hosting the given remote, or an empty string when it cannot be derived
+func compareURL(remote, oldTag, newTag string) string {
+	if oldTag == "" || newTag == "" {
+		return ""
+	}
+	remoteURL, err := gitOutput("remote", "get-url", remote)
+	if err != nil {
+		return ""
+	}
+	base, forge := forgeBaseURL(remoteURL)
+	switch forge {
+	case "github":
+		return base + "/compare/" + oldTag + "..." + newTag
+	case "gitlab":
+		return base + "/-/compare/" + oldTag + "..." + newTag
+	case "bitbucket":
+		return base + "/branches/compare/" + newTag + "%0D" + oldTag
+	}
+	return ""
+}
+
+// Function to build the release page URL of a tag on the forge hosting the
+// given remote, or an empty string when it cannot be derived
+func releaseURL(remote, tag string) string {
+	remoteURL, err := gitOutput("remote", "get-url", remote)
+	if err != nil {
+		return ""
+	}
+	base, forge := forgeBaseURL(remoteURL)
+	switch forge {
+	case "github":
+		return base + "/releases/tag/" + tag
+	case "gitlab":
+		return base + "/-/releases/" + tag
+	case "bitbucket":
+		return base + "/src/" + tag
+	}
+	return ""
+}
@@ -0,0 +1,25 @@
+package main
+
+// Function to compute the four-segment version following v. A build bump
+// increments the build number, the fourth segment, alone; the other bumps
+// apply to the first three segments as with semver and reset it to 0.
+func bumpFourPart(v Version, part string) (Version, error) {
+	var next Version
+	if part == "build" {
+		next = v.Release()
+		if v.Prerelease == "" {
+			next.BuildNumber++
+		}
+	} else {
+		var err error
+		if next, err = bumpVersion(v, part, schemeSemver); err != nil {
+			return v, err
+		}
+		// Releasing a prerelease keeps the build number it was given
+		if v.Prerelease == "" || compareVersions(next, v.Release()) != 0 {
+			next.BuildNumber = 0
+		}
+	}
+	next.FourSegment = true
+	return next, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestBumpFourPart(t *testing.T) {
+	tests := []struct {
+		current string
+		part    string
+		want    string
+	}{
+		{"1.4.9.41", "build", "1.4.9.42"},
+		{"1.4.9.41", "patch", "1.4.10.0"},
+		{"1.4.9.41", "minor", "1.5.0.0"},
+		{"1.4.9.41", "major", "2.0.0.0"},
+		// Three-segment versions read as build number 0
+		{"1.4.9", "build", "1.4.9.1"},
+		{"1.4.9", "patch", "1.4.10.0"},
+		// Releasing a prerelease keeps its build number
+		{"1.5.0.3-rc.1", "patch", "1.5.0.3"},
+		{"1.5.0.3-rc.1", "build", "1.5.0.3"},
+	}
+	for _, tt := range tests {
+		current, err := parseVersion(tt.current)
+		if err != nil {
+			t.Fatal(err)
+		}
+		next, err := bumpFourPart(current, tt.part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := next.String(); got != tt.want {
+			t.Errorf("bumpFourPart(%s, %s) = %s, want %s", tt.current, tt.part, got, tt.want)
+		}
+	}
+}
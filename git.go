@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// repoDir is the repository every git command runs in, the current
+// directory when empty
+var repoDir string
+
+// Function to prepare a git command running in the selected repository. In
+// read-only mode a command that could write fails when started.
+func gitCommand(args ...string) *exec.Cmd {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoDir
+	if readOnly && !gitReads(args) {
+		cmd.Err = errReadOnly
+	}
+	return cmd
+}
+
+// Function to take the leading global options, --repo selecting the
+// repository (or the VERSION_REPO environment variable), --sandbox and
+// --read-only (or VERSION_READ_ONLY), returning the remaining arguments
+func selectRepo(args []string) ([]string, error) {
+	repoDir = os.Getenv("VERSION_REPO")
+	readOnly = readOnlyFromEnv()
+	for len(args) > 0 {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+		if !strings.HasPrefix(args[0], "-") || (name != "repo" && name != "sandbox" && name != "read-only") {
+			break
+		}
+		args = args[1:]
+		switch {
+		case name == "sandbox":
+			useSandbox = true
+		case name == "read-only":
+			readOnly = true
+		case hasValue:
+			repoDir = value
+		case len(args) == 0:
+			return nil, fmt.Errorf("--repo needs a path")
+		default:
+			repoDir, args = args[0], args[1:]
+		}
+	}
+	if repoDir == "" {
+		return args, nil
+	}
+	if info, err := os.Stat(repoDir); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", repoDir)
+	}
+	return args, nil
+}
+
+// Function to run a git command and return its trimmed standard output
+func gitOutput(args ...string) (string, error) {
+	return gitOutputWithInput("", args...)
+}
+
+// Function to run a git command with the given standard input and return its
+// trimmed standard output
+func gitOutputWithInput(input string, args ...string) (string, error) {
+	cmd := gitCommand(args...)
+	if input != "" {
+		cmd.Stdin = strings.NewReader(input)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w: %s", cmd.String(), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Function to resolve a revision to the full hash of the commit it names. In
+// a partial clone a commit hash that is not available locally is fetched on
+// demand before giving up, and so is a remote branch such as
+// origin/release-1.x that was never fetched.
+func resolveCommit(rev string) (string, error) {
+	commit, err := gitOutput("rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil && (fetchMissingObject(rev) || fetchRemoteBranch(rev)) {
+		return gitOutput("rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	}
+	return commit, err
+}
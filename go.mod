@@ -2,10 +2,10 @@ module github.com/chandanpasunoori/version
 
 go 1.21.6
 
-require github.com/rs/zerolog v1.31.0
-
 require (
-	github.com/mattn/go-colorable v0.1.13 // indirect
-	github.com/mattn/go-isatty v0.0.19 // indirect
-	golang.org/x/sys v0.12.0 // indirect
+	github.com/mattn/go-colorable v0.1.13
+	github.com/mattn/go-isatty v0.0.19
+	github.com/rs/zerolog v1.31.0
+	golang.org/x/sys v0.12.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// GoTags asks for the tags Go tooling finds the versions of a module in a
+// subdirectory by, such as services/api/v1.4.2 for the module whose go.mod is
+// in services/api
+type GoTags struct {
+	// Path is the directory of the go.mod, relative to the root of the
+	// repository, . for the root itself
+	Path string `yaml:"path"`
+	// Channel is the release channel whose versions get Go tags
+	Channel string `yaml:"channel"`
+}
+
+// goTagModules are the modules configured with go_tags, set when the
+// configuration is loaded
+var goTagModules map[string]GoTags
+
+// Function to check the Go tags settings of a module
+func validateGoTags(g GoTags) error {
+	clean := path.Clean(g.Path)
+	switch {
+	case g.Path == "":
+		return fmt.Errorf("go_tags: path is empty, expected the directory of the go.mod such as services/api")
+	case path.IsAbs(g.Path) || clean == ".." || strings.HasPrefix(clean, "../"):
+		return fmt.Errorf("go_tags: path %q is not a directory of the repository", g.Path)
+	case clean != g.Path:
+		return fmt.Errorf("go_tags: path %q is not clean, use %s", g.Path, clean)
+	case g.Channel == "":
+		return fmt.Errorf("go_tags: channel is empty, expected the release channel whose versions get Go tags")
+	}
+	return validateChannel(g.Channel)
+}
+
+// Function to name the Go tag of a version of a module, dropping its build
+// metadata, which Go versions cannot carry. Counters, four-segment versions
+// and the versions of modules using calendar versions are no Go versions and
+// get none.
+func goTag(module string, g GoTags, v Version) (string, bool) {
+	if v.Counter || v.FourSegment || schemeOf(module) == schemeCalver {
+		return "", false
+	}
+	tag := "v" + v.WithoutBuild().String()
+	if g.Path != "." {
+		tag = g.Path + "/" + tag
+	}
+	return tag, true
+}
+
+// Function to list the patterns of the Go tags of modules in
+// subdirectories, so services/api/v1.4.2 is never read as the 1.4.2 version
+// of a services module on an api channel. They name whole Go versions and
+// are matched against whole tag names, so the tags of an app module on a v2
+// channel are still read when app has Go tags.
+func goTagPatterns(modules map[string]GoTags) []string {
+	var patterns []string
+	for _, g := range modules {
+		if g.Path != "" && g.Path != "." {
+			patterns = append(patterns, g.Path+"/v[0-9]*.[0-9]*.[0-9]*")
+		}
+	}
+	// Sorted, since they are part of the key of the cached tag index
+	slices.Sort(patterns)
+	return patterns
+}
+
+// Function to create the Go tags of created versions on the commit of their
+// tag, for modules configured with go_tags. A Go tag already on the same
+// commit is kept; one on another commit is left alone and reported, as Go
+// tooling caches versions for good. It reports whether every Go tag is in
+// sync with its version tag.
+func createGoTags(results []tagResult) bool {
+	ok := true
+	for i, r := range results {
+		g, configured := goTagModules[r.Module]
+		if !configured || r.Channel != g.Channel {
+			continue
+		}
+		tag, valid := goTag(r.Module, g, r.New)
+		if !valid || tag == r.Tag {
+			continue
+		}
+		if existing, err := resolveCommit("refs/tags/" + tag); err == nil {
+			if existing != r.Commit {
+				log.Error().Str("tag", tag).Str("commit", shortHash(existing)).Str("version tag", r.Tag).Msg("Go tag exists on another commit")
+				ok = false
+				continue
+			}
+		} else if _, err := gitOutput("tag", tag, r.Commit); err != nil {
+			log.Error().Err(err).Str("tag", tag).Msg("unable to create Go tag")
+			ok = false
+			continue
+		}
+		results[i].GoTag = tag
+		log.Info().Str("tag", tag).Str("version tag", r.Tag).Msg("Go tag created")
+	}
+	return ok
+}
+
+// Function to push the Go tags of pushed tags, reporting whether every one
+// was pushed. Unlike aliases they are never forced.
+func pushGoTags(target pushTarget, tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	var refspecs []string
+	for _, tag := range tags {
+		refspecs = append(refspecs, "refs/tags/"+tag+":refs/tags/"+tag)
+	}
+	if err := pushRefspec(target, refspecs...); err != nil {
+		log.Error().Err(err).Str("remote", target.Remote).Msg("Pushing Go tags failed")
+		return false
+	}
+	log.Info().Str("remote", target.Remote).Str("tags", strings.Join(tags, ", ")).Msg("Go tags pushed")
+	return true
+}
+
+// Function to find versions of modules configured with go_tags whose Go tag
+// is missing or on another commit
+func goTagProblems(entries []tagEntry) []problem {
+	var problems []problem
+	for _, e := range entries {
+		g, configured := goTagModules[e.Module]
+		if !configured || e.Channel != g.Channel {
+			continue
+		}
+		tag, valid := goTag(e.Module, g, e.Version)
+		if !valid || tag == e.Tag {
+			continue
+		}
+		commit, err := resolveCommit("refs/tags/" + tag)
+		switch {
+		case err != nil:
+			problems = append(problems, problem{"go tag", e.Tag, "missing " + tag})
+		case commit != e.Commit:
+			problems = append(problems, problem{"go tag", e.Tag,
+				fmt.Sprintf("%s is on commit %s instead of %s", tag, shortHash(commit), shortHash(e.Commit))})
+		}
+	}
+	return problems
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+)
+
+// Function to configure modules for the length of a test
+func withModules(t *testing.T, schemes map[string]string, goTags map[string]GoTags) {
+	t.Helper()
+	previousSchemes, previousGoTags := moduleSchemes, goTagModules
+	moduleSchemes, goTagModules = schemes, goTags
+	t.Cleanup(func() { moduleSchemes, goTagModules = previousSchemes, previousGoTags })
+}
+
+func TestGoTagFollowsModuleScheme(t *testing.T) {
+	withModules(t, map[string]string{"cal": schemeCalver}, nil)
+	g := GoTags{Path: "services/app", Channel: "prod"}
+
+	for _, s := range []string{"2024.06.3", "2026.10.2", "2026.12.1"} {
+		v, err := parseVersion(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tag, ok := goTag("cal", g, v); ok {
+			t.Errorf("calendar version %s of cal got Go tag %s", s, tag)
+		}
+	}
+
+	v, err := parseVersion("1.10.2+abc1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag, ok := goTag("api", g, v); !ok || tag != "services/app/v1.10.2" {
+		t.Errorf("Go tag of api 1.10.2+abc1234 = %q (%v), want services/app/v1.10.2", tag, ok)
+	}
+}
+
+func TestGoTagProblemsSkipCalendarModules(t *testing.T) {
+	_, commit := newTestRepo(t)
+	withModules(t, map[string]string{"cal": schemeCalver}, map[string]GoTags{
+		"cal": {Path: "svc/cal", Channel: "prod"},
+		"api": {Path: "svc/api", Channel: "prod"},
+	})
+
+	var entries []tagEntry
+	for _, tag := range []string{"cal/prod/v2024.06.3", "cal/prod/v2026.10.2", "api/prod/v1.4.2"} {
+		module, channel, version, ok := parseTag(tag)
+		if !ok {
+			t.Fatalf("%s does not parse", tag)
+		}
+		entries = append(entries, tagEntry{Tag: tag, Module: module, Channel: channel, Version: version, Commit: commit})
+	}
+
+	problems := goTagProblems(entries)
+	if len(problems) != 1 || problems[0].Tag != "api/prod/v1.4.2" {
+		t.Fatalf("problems = %+v, want only the missing Go tag of api/prod/v1.4.2", problems)
+	}
+}
+
+func TestParseVersionKeepsPadding(t *testing.T) {
+	for _, s := range []string{"2024.06.3", "2026.10.2", "1.10.0", "1.2.3", "2024.06.3-rc.1+abc"} {
+		v, err := parseVersion(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.String() != s {
+			t.Errorf("parseVersion(%q).String() = %q", s, v.String())
+		}
+	}
+}
+
+func TestGoTagPatternsMatchWholeGoVersions(t *testing.T) {
+	previous := ignoredGoTags
+	ignoredGoTags = goTagPatterns(map[string]GoTags{
+		"app": {Path: "app", Channel: "prod"},
+		"api": {Path: "services/api", Channel: "prod"},
+	})
+	t.Cleanup(func() { ignoredGoTags = previous })
+
+	for _, tag := range []string{"app/v1.4.2", "app/v2.0.0-rc.1", "services/api/v1.4.2"} {
+		if !isIgnoredTag(tag) {
+			t.Errorf("Go tag %s is not ignored", tag)
+		}
+	}
+	// The module's own tags, on channels that look like the start of a Go
+	// version, are still read
+	for _, tag := range []string{"app/v2/v1.0.0", "app/v1/v3.1.0", "app/prod/v1.4.2", "services/api/v1.4.2/extra"} {
+		if isIgnoredTag(tag) {
+			t.Errorf("%s is ignored as a Go tag", tag)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	historyFile = "history.json"
+	maxHistory  = 20
+	maxRecent   = 3
+)
+
+// invocation is a tagging run remembered in the local history
+type invocation struct {
+	Time    time.Time `json:"time"`
+	Module  string    `json:"module"`
+	Channel string    `json:"channel"`
+	Args    []string  `json:"args"`
+}
+
+// Function to locate the directory holding local state for the repository,
+// kept inside the git directory so it is never committed
+func stateDir() (string, error) {
+	gitDir, err := gitOutput("rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(gitDir, "version")
+	if readOnly {
+		return dir, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// Function to load the recorded invocations, most recent first
+func loadHistory() []invocation {
+	dir, err := stateDir()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, historyFile))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Warn().Err(err).Msg("unable to read command history")
+		}
+		return nil
+	}
+	var history []invocation
+	if err := json.Unmarshal(data, &history); err != nil {
+		log.Warn().Err(err).Msg("ignoring unreadable command history")
+		return nil
+	}
+	return history
+}
+
+// Function to record a successful invocation at the top of the history,
+// dropping an older identical entry
+func recordInvocation(fs *flag.FlagSet) {
+	if readOnly {
+		return
+	}
+	var args []string
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "m", "r", "profile-cpu", "profile-mem", "plain-prompts", "no-summary", "format", "dry-run", "yes", "non-interactive", "no-cache":
+			return
+		}
+		args = append(args, "-"+f.Name+"="+f.Value.String())
+	})
+	current := invocation{Time: time.Now().UTC(), Module: moduleName, Channel: releaseChannel, Args: args}
+
+	err := withStateLock(func(dir string) error {
+		history := slices.DeleteFunc(loadHistory(), func(inv invocation) bool {
+			return inv.Module == current.Module && inv.Channel == current.Channel && slices.Equal(inv.Args, current.Args)
+		})
+		history = append([]invocation{current}, history...)
+		if len(history) > maxHistory {
+			history = history[:maxHistory]
+		}
+		data, err := json.MarshalIndent(history, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeFile(filepath.Join(dir, historyFile), data, 0o644)
+	})
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to record command history")
+	}
+}
+
+// Function to describe an invocation in a single line
+func (inv invocation) describe() string {
+	text := inv.Module + " on " + inv.Channel
+	if len(inv.Args) > 0 {
+		text += " " + strings.Join(inv.Args, " ")
+	}
+	return text
+}
+
+// Function to describe the most recent selections as picker shortcuts
+func recentShortcuts(history []invocation) []shortcut {
+	var shortcuts []shortcut
+	for i, inv := range history[:min(len(history), maxRecent)] {
+		shortcuts = append(shortcuts, shortcut{Key: fmt.Sprintf("r%d", i+1), Description: inv.describe()})
+	}
+	return shortcuts
+}
+
+// Function to interpret an answer as a recent selection shortcut
+func pickRecent(answer string, history []invocation) (invocation, bool) {
+	for i := 0; i < min(len(history), maxRecent); i++ {
+		if answer == fmt.Sprintf("r%d", i+1) {
+			return history[i], true
+		}
+	}
+	return invocation{}, false
+}
+
+// Function to handle `version again`, repeating a recorded invocation
+func runAgain(args []string) int {
+	fs := flag.NewFlagSet("again", flag.ExitOnError)
+	n := fs.Int("n", 1, "which recent invocation to repeat, 1 being the last one")
+	list := fs.Bool("l", false, "list the recorded invocations instead of running one")
+	fs.Parse(args)
+
+	history := loadHistory()
+	if *list {
+		for i, inv := range history {
+			fmt.Printf("%d\t%s\t%s\n", i+1, inv.Time.Local().Format(time.DateTime), inv.describe())
+		}
+		return 0
+	}
+	if *n < 1 || *n > len(history) {
+		log.Error().Int("n", *n).Int("recorded", len(history)).Msg("no such invocation in the history")
+		return 1
+	}
+
+	inv := history[*n-1]
+	tagFlags := flag.NewFlagSet("again", flag.ExitOnError)
+	registerTagFlags(tagFlags)
+	if err := tagFlags.Parse(append([]string{"-m", inv.Module, "-r", inv.Channel}, inv.Args...)); err != nil {
+		log.Error().Err(err).Msg("unable to replay invocation")
+		return 1
+	}
+	log.Info().Str("invocation", inv.describe()).Msg("Repeating")
+	return run(tagFlags)
+}
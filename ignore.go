@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ignoreFileName lists tag patterns to leave out, one per line, at the root
+// of the working tree
+const ignoreFileName = ".versionignore"
+
+// ignoredTags are the patterns of tags left out of module and channel
+// discovery and version resolution, set by loadIgnoredTags
+var ignoredTags []string
+
+// ignoredGoTags are the patterns of the Go tags of configured modules, set
+// by loadIgnoredTags. Unlike ignoredTags they only match whole tag names.
+var ignoredGoTags []string
+
+// Function to tell whether a tag is ignored. As in .gitignore, a pattern
+// matches the tag or any of its leading path segments, so old/* ignores
+// old/api/v1.0.0. Go tags are only ignored by their whole name.
+func isIgnoredTag(tag string) bool {
+	for _, pattern := range ignoredGoTags {
+		if ok, _ := path.Match(pattern, tag); ok {
+			return true
+		}
+	}
+	for _, pattern := range ignoredTags {
+		name := tag
+		for {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+			i := strings.LastIndex(name, "/")
+			if i < 0 {
+				break
+			}
+			name = name[:i]
+		}
+	}
+	return false
+}
+
+// Function to check the tag patterns to ignore
+func validateIgnorePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid ignored tag pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Function to read the patterns of .versionignore, skipping blank lines and
+// comments starting with #
+func readIgnoreFile(name string) ([]string, error) {
+	data, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var patterns []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns, scanner.Err()
+}
+
+// Function to read the tag patterns to ignore from .versionignore, the
+// ignore_tags list of the configuration and the Go tags of its modules,
+// before any tag is read
+func loadIgnoredTags() error {
+	root, err := gitOutput("rev-parse", "--show-toplevel")
+	if err != nil {
+		// Outside a repository there are no tags to ignore
+		return nil
+	}
+	patterns, err := readIgnoreFile(filepath.Join(root, ignoreFileName))
+	if err != nil {
+		return err
+	}
+	if err := validateIgnorePatterns(patterns); err != nil {
+		return fmt.Errorf("%s: %w", ignoreFileName, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, configFileName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	var config struct {
+		IgnoreTags []string `yaml:"ignore_tags"`
+		Modules    map[string]struct {
+			GoTags GoTags `yaml:"go_tags"`
+		} `yaml:"modules"`
+	}
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", configFileName, err)
+	}
+	if err := validateIgnorePatterns(config.IgnoreTags); err != nil {
+		return fmt.Errorf("%s: %w", configFileName, err)
+	}
+	goTags := make(map[string]GoTags)
+	for name, module := range config.Modules {
+		goTags[name] = module.GoTags
+	}
+	ignoredTags = append(patterns, config.IgnoreTags...)
+	ignoredGoTags = goTagPatterns(goTags)
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxImpactDirs is how many top-level directories the impact summary names
+const maxImpactDirs = 5
+
+// dirCount is the number of files changed under a top-level directory
+type dirCount struct {
+	Dir   string
+	Files int
+}
+
+// Function to count the changed files under each top-level directory, the
+// busiest first. Files at the root count under ".".
+func topLevelDirs(changes []fileChange) []dirCount {
+	counts := make(map[string]int)
+	for _, c := range changes {
+		dir, _, found := strings.Cut(c.Path, "/")
+		if !found {
+			dir = "."
+		}
+		counts[dir]++
+	}
+	dirs := make([]dirCount, 0, len(counts))
+	for dir, n := range counts {
+		dirs = append(dirs, dirCount{dir, n})
+	}
+	slices.SortFunc(dirs, func(a, b dirCount) int {
+		if c := cmp.Compare(b.Files, a.Files); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Dir, b.Dir)
+	})
+	return dirs
+}
+
+// Function to print the size of the release of each module before it is
+// tagged: files changed, lines inserted and deleted and the top-level
+// directories touched since its latest tag on the channels, within its
+// configured paths
+func printImpact(w io.Writer, idx *tagIndex, config *Config, targets, channels []string, commit string) {
+	for _, m := range targets {
+		version, channel, found := idx.latestTag(m, channels)
+		if !found {
+			fmt.Fprintf(w, "%s: first release\n", m)
+			continue
+		}
+		from := formatTag(m, channel, version)
+		changes, err := readFileChanges(from, commit, config.Modules[m].Paths)
+		if err != nil {
+			log.Warn().Err(err).Str("module", m).Msg("unable to measure the release")
+			continue
+		}
+		insertions, deletions := changeTotals(changes)
+		fmt.Fprintf(w, "%s since %s: %d files changed, %d insertions(+), %d deletions(-)\n", m, from, len(changes), insertions, deletions)
+
+		dirs := topLevelDirs(changes)
+		if len(dirs) == 0 {
+			continue
+		}
+		var touched []string
+		for _, d := range dirs[:min(len(dirs), maxImpactDirs)] {
+			touched = append(touched, fmt.Sprintf("%s (%d)", d.Dir, d.Files))
+		}
+		if len(dirs) > maxImpactDirs {
+			touched = append(touched, fmt.Sprintf("%d more", len(dirs)-maxImpactDirs))
+		}
+		fmt.Fprintf(w, "  touches %s\n", strings.Join(touched, ", "))
+	}
+}
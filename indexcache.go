@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	tagIndexCacheFile = "tag-index.json"
+	// tagIndexCacheVersion is bumped whenever the way tags are parsed
+	// changes, so indexes built by older releases are not reused
+	tagIndexCacheVersion = 4
+)
+
+// tagIndexCache is the tag index saved for the refs it was built from
+type tagIndexCache struct {
+	Key    string                        `json:"key"`
+	Latest map[string]map[string]Version `json:"latest"`
+}
+
+// Function to fingerprint the tags of the repository from packed-refs and
+// the loose refs under refs/tags, without running git. Repositories using
+// the reftable backend are not fingerprinted.
+func refsFingerprint() (string, error) {
+	gitDir, err := gitOutput("rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(filepath.Join(gitDir, "reftable")); err == nil {
+		return "", errors.New("reftable refs are not fingerprinted")
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(strconv.Itoa(tagIndexCacheVersion) + "\n" + layout.text + "\n" + layout.pattern.String() + "\n" + strings.Join(ignoredTags, "\n") + "\n" + strings.Join(ignoredGoTags, "\n") + "\n"))
+	if packed, err := os.ReadFile(filepath.Join(gitDir, "packed-refs")); err == nil {
+		hash.Write(packed)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	// Loose refs are walked in lexical order, so the same refs always hash
+	// the same
+	err = filepath.WalkDir(filepath.Join(gitDir, "refs", "tags"), func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		hash.Write([]byte("\x00" + path + "\x00"))
+		hash.Write(content)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Function to load the saved tag index when it was built from the same refs
+func loadCachedIndex(key string) *tagIndex {
+	dir, err := stateDir()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, tagIndexCacheFile))
+	if err != nil {
+		return nil
+	}
+	var cache tagIndexCache
+	if err := json.Unmarshal(data, &cache); err != nil || cache.Key != key || cache.Latest == nil {
+		return nil
+	}
+	return &tagIndex{latest: cache.Latest}
+}
+
+// Function to save the tag index together with the fingerprint of its refs.
+// The file is replaced atomically so concurrent runs never read half of it.
+func saveCachedIndex(key string, idx *tagIndex) error {
+	if readOnly {
+		return nil
+	}
+	data, err := json.Marshal(tagIndexCache{Key: key, Latest: idx.latest})
+	if err != nil {
+		return err
+	}
+	return withStateLock(func(dir string) error {
+		return writeFile(filepath.Join(dir, tagIndexCacheFile), data, 0o644)
+	})
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Function to ask for a comma separated list of names, falling back to the
+// given defaults on an empty answer and asking again until every name is valid
+func promptNames(kind, question string, defaults []string) []string {
+	if len(defaults) > 0 {
+		question += " (empty for " + strings.Join(defaults, ", ") + ")"
+	}
+	for {
+		var names stringList
+		names.Set(promptText(question))
+		if len(names) == 0 {
+			return defaults
+		}
+		var invalid error
+		for _, name := range names {
+			if err := validateName(kind, name); err != nil {
+				invalid = err
+				break
+			}
+		}
+		if invalid == nil {
+			return names
+		}
+		log.Warn().Err(invalid).Msg("invalid name entered")
+	}
+}
+
+// Function to tell whether the repository has ordinary v1.2.3 tags, named
+// by the plain tag template
+func hasPlainTags() bool {
+	plain := mustTagLayout(plainTagTemplate)
+	found := false
+	streamTags(func(tag string) {
+		if _, _, _, ok := plain.parse(tag); ok {
+			found = true
+		}
+	})
+	return found
+}
+
+// Function to handle `version init`, asking for the conventions of the
+// repository and writing them to the configuration file
+func runInit(args []string) int {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	force := fs.Bool("force", false, "overwrite an existing configuration file")
+	fs.Parse(args)
+
+	path, err := configPath()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to locate repository")
+		return 1
+	}
+	if _, err := os.Stat(path); err == nil && !*force {
+		log.Error().Str("file", path).Msg("configuration already exists, use --force to replace it")
+		return 1
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Error().Err(err).Str("file", path).Msg("unable to read configuration")
+		return 1
+	}
+
+	idx, err := scanTagIndex()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+
+	// Existing tags are offered as the defaults so adopting the tool in an
+	// established repository is a matter of confirming what is there
+	config := &Config{
+		Modules:  make(map[string]ModuleConfig),
+		Channels: make(map[string]ChannelConfig),
+	}
+	if layout.text != defaultTagTemplate {
+		config.TagTemplate = layout.text
+	}
+	// A single module already tagged v1.2.3 keeps its tags without module
+	// or channel
+	if len(idx.modules()) == 0 && hasPlainTags() && confirm("Existing tags look like v1.2.3, keep naming them without module or channel") {
+		config.TagTemplate = plainTagTemplate
+		layout = mustTagLayout(plainTagTemplate)
+		if idx, err = scanTagIndex(); err != nil {
+			log.Error().Err(err).Msg("unable to read tags")
+			return 1
+		}
+	}
+	if layout.hasModule {
+		for _, module := range promptNames("module", "Module names, comma separated", idx.modules()) {
+			config.Modules[module] = ModuleConfig{}
+		}
+	}
+	channels := []string{implicitChannel}
+	if layout.hasChannel {
+		channels = promptNames("release channel", "Release channels, comma separated", idx.channels())
+	}
+	for _, channel := range channels {
+		config.Channels[channel] = ChannelConfig{}
+	}
+
+	schemes := []string{schemeSemver, schemeRollover, schemeCalver, schemeFourPart}
+	labels := []string{
+		schemeSemver + " (1.0.9 is followed by 1.0.10, the default)",
+		schemeRollover + " (1.0.9 is followed by 1.1.0)",
+		schemeCalver + " (year.month.sequence, 2024.06.3 is followed by 2024.06.4 or 2024.07.1)",
+		schemeFourPart + " (major.minor.patch.build, 1.0.9.41 is followed by 1.0.10.0)",
+	}
+	for {
+		scheme := promptChoice("version scheme", "schemes", schemes, labels)
+		if scheme == "" || scheme == schemeSemver {
+			break
+		}
+		if scheme == schemeRollover || scheme == schemeCalver || scheme == schemeFourPart {
+			config.Scheme = scheme
+			break
+		}
+		log.Warn().Str("scheme", scheme).Msg("unknown version scheme")
+	}
+
+	for _, channel := range channels {
+		config.Channels[channel] = channelWizard(idx, config, channel)
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		log.Error().Err(err).Msg("unable to encode configuration")
+		return 1
+	}
+	if err := writeFile(path, out.Bytes(), 0o644); err != nil {
+		log.Error().Err(err).Str("file", path).Msg("unable to write configuration")
+		return 1
+	}
+	log.Info().Str("file", path).Msg("Configuration written, commit it so everyone shares the same settings")
+	return 0
+}
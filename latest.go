@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Function to handle `version latest`, printing the latest version of every
+// matching module on every release channel as one JSON or YAML document
+// mapping modules to channels to versions
+func runLatest(args []string) int {
+	fs := flag.NewFlagSet("latest", flag.ExitOnError)
+	modulePattern := fs.String("m", "", "glob of modules to include")
+	all := fs.Bool("all", false, "include every module, same as -m '*'")
+	output := fs.String("output", formatJSON, "output format: json or yaml")
+	fs.Var(&excludes, "exclude", "glob of modules or release channels to skip, may be repeated")
+	fs.BoolVar(&includeYanked, "include-yanked", false, "show yanked versions instead of the latest version that was not yanked")
+	fs.Parse(args)
+
+	if *all {
+		if *modulePattern != "" {
+			log.Error().Msg("--all and -m cannot be combined")
+			return 2
+		}
+		*modulePattern = "*"
+	}
+	if *modulePattern == "" {
+		log.Error().Msg("-m or --all is required")
+		return 2
+	}
+	if *output != formatJSON && *output != formatYAML {
+		log.Error().Str("output", *output).Msg("unknown output format, expected json or yaml")
+		return 2
+	}
+
+	idx, err := scanTagIndex()
+	if err == nil {
+		idx, err = withoutYanked(idx)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	latest := make(map[string]map[string]string)
+	if len(idx.latest) > 0 {
+		modules, err := expandModules(*modulePattern, idx)
+		if err != nil && !*all {
+			log.Error().Err(err).Msg("invalid module pattern entered")
+			return 1
+		}
+		for _, module := range applyExcludes(modules) {
+			channels := make(map[string]string)
+			for channel, version := range idx.latest[module] {
+				if !isExcluded(channel) {
+					channels[channel] = version.String()
+				}
+			}
+			if len(channels) > 0 {
+				latest[module] = channels
+			}
+		}
+	}
+
+	// Maps are encoded with sorted keys, so the output is stable
+	if *output == formatYAML {
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		err = encoder.Encode(latest)
+		if err == nil {
+			err = encoder.Close()
+		}
+	} else {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(latest)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("unable to write output")
+		return 1
+	}
+	return 0
+}
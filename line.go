@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// branchLineArg is the major.minor release line given with --branch-line
+var branchLineArg string
+
+// branchLine, when set, scopes the next version to a release line, so a
+// hotfix of 1.4 is tagged 1.4.6 even when 2.1.0 exists
+var branchLine *versionLine
+
+// versionLine is a major.minor release line, such as 1.4 for 1.4.x
+type versionLine struct {
+	Major int
+	Minor int
+}
+
+func (l versionLine) String() string {
+	return fmt.Sprintf("%d.%d", l.Major, l.Minor)
+}
+
+// Function to tell whether a version belongs to the release line
+func (l versionLine) contains(v Version) bool {
+	return !v.Counter && v.Major == l.Major && v.Minor == l.Minor
+}
+
+// Function to parse a release line written 1.4, v1.4 or 1.4.x
+func parseVersionLine(s string) (*versionLine, error) {
+	text := strings.TrimSuffix(strings.TrimPrefix(s, "v"), ".x")
+	parts := strings.Split(text, ".")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid release line %q, expected major.minor such as 1.4", s)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil || major < 0 {
+		return nil, fmt.Errorf("invalid release line %q, expected major.minor such as 1.4", s)
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil || minor < 0 {
+		return nil, fmt.Errorf("invalid release line %q, expected major.minor such as 1.4", s)
+	}
+	return &versionLine{Major: major, Minor: minor}, nil
+}
+
+// Function to find the highest version of a module on a release line across
+// the given channels, or the start of the line when none is tagged
+func latestOnLine(module string, channels []string, line versionLine) (Version, bool) {
+	entries, err := readTagEntries(func(m, c string) bool { return m == module && slices.Contains(channels, c) })
+	latest, found := Version{Major: line.Major, Minor: line.Minor}, false
+	if err != nil {
+		return latest, false
+	}
+	for _, e := range entries {
+		if line.contains(e.Version) && (!found || compareVersions(latest, e.Version) < 0) {
+			latest, found = e.Version, true
+		}
+	}
+	return latest, found
+}
+
+// Function to check that a release on the release line only moves along
+// it, and that every target has a version on the line to follow
+func checkBranchLine(targets, channels []string) error {
+	if branchLine == nil {
+		return nil
+	}
+	versioned := slices.DeleteFunc(slices.Clone(channels), isCounterChannel)
+	for _, module := range targets {
+		part := bumpPartOf(module)
+		if explicitVersion == nil && part != "patch" && part != "build" {
+			return fmt.Errorf("a %s bump of %s leaves the %s line, hotfixes take patch bumps only", part, module, branchLine)
+		}
+		if schemeOf(module) == schemeCalver {
+			return fmt.Errorf("%s uses calendar versions, which have no release lines", module)
+		}
+		if explicitVersion != nil {
+			continue
+		}
+		latest, ok := latestOnLine(module, versioned, *branchLine)
+		if !ok {
+			return fmt.Errorf("%s has no version on the %s line of %s to follow", module, branchLine, strings.Join(versioned, ", "))
+		}
+		if next, _ := bumpVersion(latest, part, schemeOf(module)); !branchLine.contains(next) {
+			return fmt.Errorf("%s follows %s on the %s line with %s, which leaves the line", module, latest, branchLine, next)
+		}
+	}
+	return nil
+}
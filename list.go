@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Function to handle `version list`, printing the latest version of every
+// module on every release channel as a matrix
+func runList(args []string) int {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	modulePattern := fs.String("m", "*", "glob of modules to list")
+	fs.Var(&excludes, "exclude", "glob of modules or release channels to skip, may be repeated")
+	fs.BoolVar(&includeYanked, "include-yanked", false, "show yanked versions instead of the latest version that was not yanked")
+	fs.Parse(args)
+
+	idx, err := scanTagIndex()
+	if err == nil {
+		idx, err = withoutYanked(idx)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	if len(idx.latest) == 0 {
+		log.Info().Msg("No version tags found")
+		return 0
+	}
+
+	modules, err := expandModules(*modulePattern, idx)
+	if err != nil {
+		log.Error().Err(err).Msg("invalid module pattern entered")
+		return 1
+	}
+	modules = applyExcludes(modules)
+
+	seen := make(map[string]bool)
+	var channels []string
+	for _, module := range modules {
+		for channel := range idx.latest[module] {
+			if !seen[channel] && !isExcluded(channel) {
+				seen[channel] = true
+				channels = append(channels, channel)
+			}
+		}
+	}
+	sort.Strings(channels)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, "MODULE")
+	for _, channel := range channels {
+		fmt.Fprintf(tw, "\t%s", channel)
+	}
+	fmt.Fprintln(tw)
+	for _, module := range modules {
+		fmt.Fprint(tw, module)
+		for _, channel := range channels {
+			if version, ok := idx.latest[module][channel]; ok {
+				fmt.Fprintf(tw, "\t%s", version)
+			} else {
+				fmt.Fprint(tw, "\t-")
+			}
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+	return 0
+}
@@ -1,23 +1,139 @@
 package main
 
 import (
-	"bufio"
+	"cmp"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
-	"os/exec"
-	"regexp"
 	"slices"
-	"sort"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
 type Version struct {
 	Major, Minor, Patch int
+	// Prerelease holds the dot separated prerelease identifiers, such as
+	// rc.1, without the leading dash
+	Prerelease string
+	// Build holds the build metadata, such as a commit hash, without the
+	// leading plus. It plays no part in precedence.
+	Build string
+	// ZeroPadded marks a version whose minor is written with two digits,
+	// as the month of calendar versions such as 2024.06.3 is. It only
+	// decides how the version is written; whether a module uses calendar
+	// versions is decided by its scheme.
+	ZeroPadded bool
+	// BuildNumber is the fourth segment of four-segment versions such as
+	// 1.4.2.118
+	BuildNumber int
+	// FourSegment marks a version written with its build number
+	FourSegment bool
+	// Counter marks the build counter of a counter channel, such as b1042,
+	// whose number is kept in Patch
+	Counter bool
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Counter {
+		s = fmt.Sprintf("b%d", v.Patch)
+	}
+	if v.ZeroPadded {
+		s = fmt.Sprintf("%d.%02d.%d", v.Major, v.Minor, v.Patch)
+	}
+	if v.FourSegment {
+		s += fmt.Sprintf(".%d", v.BuildNumber)
+	}
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Function to drop the prerelease identifiers and build metadata of a
+// version
+func (v Version) Release() Version {
+	return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch, ZeroPadded: v.ZeroPadded, BuildNumber: v.BuildNumber, FourSegment: v.FourSegment, Counter: v.Counter}
+}
+
+// Function to drop the build metadata of a version, leaving what identifies
+// it
+func (v Version) WithoutBuild() Version {
+	v.Build = ""
+	return v
+}
+
+func (v Version) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *Version) UnmarshalText(text []byte) error {
+	parsed, err := parseVersion(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+// Function to parse a version written as major.minor.patch, or
+// major.minor.patch.build, with an optional leading v, an optional
+// prerelease such as -rc.1 and optional build metadata such as +abc1234, or
+// a build counter such as b1042
+func parseVersion(s string) (Version, error) {
+	var v Version
+	rest, build, hasBuild := strings.Cut(strings.TrimPrefix(s, "v"), "+")
+	if counter, ok := strings.CutPrefix(rest, "b"); ok {
+		n, err := strconv.Atoi(counter)
+		if err != nil || !isNumeric(counter) {
+			return Version{}, fmt.Errorf("invalid build counter %q, expected b followed by a number", s)
+		}
+		v = Version{Patch: n, Counter: true}
+		if hasBuild {
+			if err := validateBuild(build); err != nil {
+				return Version{}, fmt.Errorf("invalid version %q: %w", s, err)
+			}
+			v.Build = build
+		}
+		return v, nil
+	}
+	core, prerelease, hasPrerelease := strings.Cut(rest, "-")
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 && len(parts) != 4 {
+		return v, fmt.Errorf("invalid version %q, expected major.minor.patch", s)
+	}
+	numbers := []*int{&v.Major, &v.Minor, &v.Patch, &v.BuildNumber}
+	v.FourSegment = len(parts) == 4
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version %q, expected major.minor.patch", s)
+		}
+		*numbers[i] = n
+	}
+	// Keep the padding of a minor such as the month of 2024.06.3, so the
+	// version is written back as it was tagged
+	v.ZeroPadded = len(parts[1]) == 2 && parts[1][0] == '0'
+	if hasPrerelease {
+		if err := validatePrerelease(prerelease); err != nil {
+			return Version{}, fmt.Errorf("invalid version %q: %w", s, err)
+		}
+		v.Prerelease = prerelease
+	}
+	if hasBuild {
+		if err := validateBuild(build); err != nil {
+			return Version{}, fmt.Errorf("invalid version %q: %w", s, err)
+		}
+		v.Build = build
+	}
+	return v, nil
 }
 
 type SemVerList []Version
@@ -31,120 +147,243 @@ func (s SemVerList) Swap(i, j int) {
 }
 
 func (s SemVerList) Less(i, j int) bool {
-	if s[i].Major != s[j].Major {
-		return s[i].Major < s[j].Major
+	return compareVersions(s[i], s[j]) < 0
+}
+
+// Function to compare two versions, returning -1, 0 or 1. The build number
+// of four-segment versions comes after the patch, a prerelease comes before
+// the release of the same version, and build metadata is ignored.
+func compareVersions(a, b Version) int {
+	if a.Major != b.Major {
+		return cmp.Compare(a.Major, b.Major)
+	}
+	if a.Minor != b.Minor {
+		return cmp.Compare(a.Minor, b.Minor)
+	}
+	if a.Patch != b.Patch {
+		return cmp.Compare(a.Patch, b.Patch)
 	}
-	if s[i].Minor != s[j].Minor {
-		return s[i].Minor < s[j].Minor
+	if a.BuildNumber != b.BuildNumber {
+		return cmp.Compare(a.BuildNumber, b.BuildNumber)
 	}
-	return s[i].Patch < s[j].Patch
+	return comparePrereleases(a.Prerelease, b.Prerelease)
 }
 
 var (
 	moduleName     string
 	releaseChannel string
+	commitRef      string
+	pushCreated    bool
+	pushRemote     string
+	bumpPart       = "patch"
+	bumpMajor      bool
+	bumpMinor      bool
+	dryRun         bool
+	setVersion     string
+	// explicitVersion is the parsed --set version, nil when the next version
+	// is computed
+	explicitVersion *Version
 )
 
-// Function to parse the current version from the version file
-func getCurrentModules() ([]string, []string, error) {
-	cmd := exec.Command("git", "tag", "--list", "--sort=-v:refname")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return []string{}, []string{}, err
+// Function to list the modules and release channels found in the tag index
+func getCurrentModules(idx *tagIndex) ([]string, []string) {
+	return idx.modules(), idx.channels()
+}
+
+// Function to add configured names that have no tags yet to a sorted list of
+// discovered names
+func mergeNames[T any](names []string, configured map[string]T) []string {
+	for name := range configured {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
 	}
+	slices.Sort(names)
+	return names
+}
 
-	tags := strings.Split(strings.TrimSpace(string(output)), "\n")
-	if len(tags) == 0 {
-		return []string{}, []string{}, err
+// Function to resolve the current version of a module across release channels
+func parseCurrentVersion(idx *tagIndex, moduleName string, releaseChannel []string) Version {
+	if branchLine != nil {
+		version, _ := latestOnLine(moduleName, releaseChannel, *branchLine)
+		return version
+	}
+	version, ok := idx.latestFor(moduleName, releaseChannel)
+	if !ok {
+		// No valid version tags found
+		return Version{Major: 0, Minor: 0, Patch: 0}
 	}
+	return version
+}
+
+// Function to tell assignMonotonic whether a planned tag has its version
+// chosen with --set. --set applies to every tag of the run alike, so the
+// index of the tag is not looked at.
+func isExplicit(int) bool {
+	return explicitVersion != nil
+}
 
-	moduleNameList := make(map[string]bool)
-	releaseChannelList := make(map[string]bool)
+// Function to compute the version following the current one of a module for
+// the bump selected on the command line
+func nextVersion(module string, currentVersion Version) Version {
+	return nextVersionWith(module, currentVersion, bumpPartOf(module))
+}
 
-	re := regexp.MustCompile(`^([a-z]+)/([a-z]+)/v(\d+\.\d+\.\d+)$`)
-	for _, tag := range tags {
-		if matches := re.FindStringSubmatch(tag); len(matches) == 4 {
-			module, release := matches[1], matches[2]
-			if _, ok := releaseChannelList[release]; !ok {
-				releaseChannelList[release] = true
-			}
-			if _, ok := moduleNameList[module]; !ok {
-				moduleNameList[module] = true
-			}
+// Function to compute the version following the current one of a module for
+// the given bump
+func nextVersionWith(module string, currentVersion Version, part string) Version {
+	if explicitVersion != nil {
+		return *explicitVersion
+	}
+	if prereleaseName != "" {
+		next, err := bumpPrerelease(currentVersion, part, prereleaseName, schemeOf(module))
+		if err != nil {
+			// Runs check the bump up front with checkBump
+			return currentVersion
 		}
+		return next
+	}
+	if currentVersion.Counter {
+		// Counters go up by one whatever --bump auto chose for the module
+		part = "patch"
 	}
+	next, _ := bumpVersion(currentVersion, part, schemeOf(module))
+	return next
+}
 
-	var modules []string
-	for key := range moduleNameList {
-		modules = append(modules, key)
+// Function to check that the bump selected on the command line applies to
+// every target, before any version is computed
+func checkBump(idx *tagIndex, targets, channels []string) error {
+	if err := checkCounterBump(channels); err != nil {
+		return err
 	}
-	var releases []string
-	for key := range releaseChannelList {
-		releases = append(releases, key)
+	if err := checkSnapshotBump(channels); err != nil {
+		return err
 	}
-
-	// Return the latest version
-	return modules, releases, nil
+	if err := checkBranchLine(targets, channels); err != nil {
+		return err
+	}
+	for _, module := range targets {
+		part := bumpPartOf(module)
+		if explicitVersion == nil && part != "patch" && schemeOf(module) == schemeCalver {
+			return fmt.Errorf("%s uses calendar versions, which follow the date and take no %s bump", module, part)
+		}
+		if part == "build" && schemeOf(module) != schemeFourPart {
+			return fmt.Errorf("%s has no build number, only the %s scheme takes a build bump", module, schemeFourPart)
+		}
+	}
+	return checkPrereleaseBump(idx, targets, channels)
 }
 
-// Function to parse the current version from the version file
-func parseCurrentVersion(moduleName string, releaseChannel []string) (Version, error) {
-	cmd := exec.Command("git", "tag", "--list", "--sort=-v:refname")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		log.Error().Err(err).Str("command", cmd.String()).Msg("error in the git command")
-		return Version{Major: 0, Minor: 0, Patch: 0}, nil
-	}
-
-	tags := strings.Split(strings.TrimSpace(string(output)), "\n")
-	if len(tags) == 0 {
-		// No tags found
-		return Version{Major: 0, Minor: 0, Patch: 0}, nil
-	}
-
-	// Extract versions and sort them in descending order
-	var versions SemVerList
-
-	for _, rc := range releaseChannel {
-		re := regexp.MustCompile(fmt.Sprintf(`^(%s)/(%s)/v(\d+)\.(\d+)\.(\d+)$`, moduleName, rc))
-		for _, tag := range tags {
-			if matches := re.FindStringSubmatch(tag); len(matches) == 6 {
-				major, err := strconv.Atoi(matches[3])
-				if err != nil {
-					log.Error().Msgf("invalid version parsing")
-					os.Exit(1)
-				}
-				minor, err := strconv.Atoi(matches[4])
-				if err != nil {
-					log.Error().Msgf("invalid version parsing")
-					os.Exit(1)
-				}
-				patch, err := strconv.Atoi(matches[5])
-				if err != nil {
-					log.Error().Msgf("invalid version parsing")
-					os.Exit(1)
-				}
-				versions = append(versions, Version{Major: major, Minor: minor, Patch: patch})
-			}
+// Function to bump one part of a version, resetting the parts below it.
+// Patch bumps follow the version scheme given, calendar versions follow
+// the date and four-segment versions also count builds. A prerelease is
+// released instead when it already is of the requested kind, so 2.0.0-rc.2
+// becomes 2.0.0 for any bump and 1.2.3-rc.1 for a patch bump only.
+func bumpVersion(v Version, part, scheme string) (Version, error) {
+	if v.Counter {
+		return nextCounter(v, part)
+	}
+	if scheme == schemeCalver {
+		return bumpCalver(v, part, time.Now())
+	}
+	if scheme == schemeFourPart {
+		return bumpFourPart(v, part)
+	}
+	if v.Prerelease != "" {
+		release := v.Release()
+		switch {
+		case part == "patch",
+			part == "minor" && v.Patch == 0,
+			part == "major" && v.Patch == 0 && v.Minor == 0:
+			return release, nil
 		}
+		v = release
 	}
-
-	if len(versions) == 0 {
-		// No valid version tags found
-		return Version{Major: 0, Minor: 0, Patch: 0}, nil
+	switch part {
+	case "patch":
+		return incrementVersion(v, scheme), nil
+	case "minor":
+		if scheme == schemeRollover && v.Minor >= 9 {
+			return Version{Major: v.Major + 1}, nil
+		}
+		return Version{Major: v.Major, Minor: v.Minor + 1}, nil
+	case "major":
+		return Version{Major: v.Major + 1}, nil
 	}
+	return v, fmt.Errorf("invalid bump %q, expected patch, minor or major", part)
+}
 
-	sort.Sort(sort.Reverse(versions))
-
-	// Return the latest version
-	return versions[0], nil
+// Function to settle the part of the version to bump from --bump, --major
+// and --minor, or the version given with --set, which must not contradict
+// each other
+func resolveBump() error {
+	explicitVersion = nil
+	branchLine = nil
+	if err := validateAPICheck(apiCheckMode); err != nil {
+		return err
+	}
+	if branchLineArg != "" {
+		line, err := parseVersionLine(branchLineArg)
+		if err != nil {
+			return err
+		}
+		branchLine = line
+	}
+	if setVersion != "" {
+		if bumpMajor || bumpMinor || bumpPart != "patch" {
+			return fmt.Errorf("--set cannot be combined with --bump, --major or --minor")
+		}
+		v, err := parseVersion(setVersion)
+		if err != nil {
+			return err
+		}
+		if prereleaseName != "" {
+			return fmt.Errorf("--set cannot be combined with --prerelease, include the prerelease in the version")
+		}
+		if branchLine != nil && !branchLine.contains(v) {
+			return fmt.Errorf("--set %s is not on the %s line given with --branch-line", v, branchLine)
+		}
+		explicitVersion = &v
+		return nil
+	}
+	if prereleaseName != "" {
+		if err := validatePrerelease(prereleaseName); err != nil {
+			return err
+		}
+		if isNumeric(prereleaseName) {
+			return fmt.Errorf("invalid prerelease %q, expected a name such as rc or beta", prereleaseName)
+		}
+	}
+	for _, shorthand := range []struct {
+		set  bool
+		part string
+	}{{bumpMajor, "major"}, {bumpMinor, "minor"}} {
+		if !shorthand.set {
+			continue
+		}
+		if bumpPart != "patch" && bumpPart != shorthand.part {
+			return fmt.Errorf("--%s conflicts with --bump %s", shorthand.part, bumpPart)
+		}
+		bumpPart = shorthand.part
+	}
+	if bumpPart == bumpAuto {
+		return nil
+	}
+	// The four-segment scheme takes every bump, build included; checkBump
+	// rejects those the scheme of a module does not take
+	_, err := bumpVersion(Version{}, bumpPart, schemeFourPart)
+	return err
 }
 
-// Function to generate the next version based on the specified pattern
-func generateNextVersion(moduleName, releaseChannel string, currentVersion Version) string {
-	// Increment the patch version
-	nextVersion := currentVersion
+// Function to increment the patch version, rolling over into minor and major
+// with the rollover scheme
+func incrementVersion(currentVersion Version, scheme string) Version {
+	nextVersion := currentVersion.WithoutBuild()
 	nextVersion.Patch += 1
+	if scheme != schemeRollover {
+		return nextVersion
+	}
 	if nextVersion.Patch > 9 {
 		nextVersion.Minor += 1
 		nextVersion.Patch = 0
@@ -153,126 +392,512 @@ func generateNextVersion(moduleName, releaseChannel string, currentVersion Versi
 		nextVersion.Major += 1
 		nextVersion.Minor = 0
 	}
-	// Construct the next version
-	return fmt.Sprintf("%s/%s/v%d.%d.%d", moduleName, releaseChannel, nextVersion.Major, nextVersion.Minor, nextVersion.Patch)
+	return nextVersion
+}
+
+// Function to construct the tag name for a version, following the tag
+// template of the repository
+func formatTag(moduleName, releaseChannel string, version Version) string {
+	if version.Counter {
+		return layout.formatCounter(moduleName, releaseChannel, version.String())
+	}
+	return layout.format(moduleName, releaseChannel, version.String())
+}
+
+// Function to warn about a name that is not known yet but is close to one
+// that is, since that is most likely a typo
+func suggestExisting(kind, name string, known []string) {
+	if slices.Contains(known, name) {
+		return
+	}
+	if suggestion := suggestName(name, known); suggestion != "" {
+		log.Warn().Msgf("%s %q does not exist yet, did you mean %q?", kind, name, suggestion)
+	}
 }
 
 // Function to create a git tag
-func createGitTag(tag string) error {
-	cmd := exec.Command("git", "tag", tag)
+func createGitTag(tag, commit string) error {
+	cmd := gitCommand("tag", tag, commit)
 	err := cmd.Run()
 	if err != nil {
 		log.Error().Err(err).Str("command", cmd.String()).Str("tag", tag).Msg("Git tag create error")
 		return err
 	}
-
-	log.Info().Str("tag", tag).Msg("Git tag created successfully")
 	return nil
 }
 
 func main() {
 
-	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "15:04:05"})
-	zerolog.SetGlobalLevel(zerolog.TraceLevel)
+	args, err := selectRepo(os.Args[1:])
+	if err != nil {
+		setupLogging(os.Stderr)
+		log.Error().Err(err).Msg("invalid repository")
+		os.Exit(2)
+	}
+	if err := loadTagLayout(); err != nil {
+		setupLogging(os.Stderr)
+		log.Error().Err(err).Msg("invalid tag template or pattern")
+		os.Exit(2)
+	}
+	if err := loadIgnoredTags(); err != nil {
+		setupLogging(os.Stderr)
+		log.Error().Err(err).Msg("invalid ignored tags")
+		os.Exit(2)
+	}
+
+	if !useSandbox {
+		os.Exit(runCommand(args))
+	}
+	setupLogging(os.Stderr)
+	sandbox, err := startSandbox()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to create sandbox")
+		os.Exit(1)
+	}
+	code := runCommand(args)
+	sandbox.finish()
+	os.Exit(code)
+}
+
+// Function to run a subcommand, or the tagging flow when the arguments do
+// not start with one, returning the exit code
+func runCommand(args []string) int {
+	if len(args) > 0 {
+		if command, ok := commands[args[0]]; ok {
+			// Subcommands print their results on stdout, so keep the log on
+			// stderr where it cannot end up in captured output
+			setupLogging(os.Stderr)
+			return command.run(args[1:])
+		}
+	}
+	return runTag(args)
+}
+
+// Function to register the flags choosing the part of the version to bump
+func registerBumpFlags(fs *flag.FlagSet) {
+	fs.StringVar(&bumpPart, "bump", "patch", "part of the version to bump: patch, minor or major, auto to choose from the conventional commits since the latest version, or build with the fourpart scheme")
+	fs.BoolVar(&bumpMajor, "major", false, "bump the major version, same as --bump major")
+	fs.BoolVar(&bumpMinor, "minor", false, "bump the minor version, same as --bump minor")
+	fs.StringVar(&setVersion, "set", "", "tag this exact version instead of computing the next one")
+	fs.StringVar(&apiCheckMode, "api-check", "", "how breaking changes to the exported API of Go modules are handled: off, warn or refuse (default api_check from the configuration, else off)")
+	fs.StringVar(&branchLineArg, "branch-line", "", "compute the next version on a major.minor release line such as 1.4, for hotfixes of older releases")
+	fs.StringVar(&prereleaseName, "prerelease", "", "tag a prerelease such as rc or beta: 2.0.0-rc.1, then 2.0.0-rc.2; a run without it releases 2.0.0")
+	fs.StringVar(&schemeOverride, "scheme", "", "version scheme instead of the configured one: semver, rollover to roll 1.0.9 over to 1.1.0, calver for year.month.sequence, or fourpart for major.minor.patch.build")
+	fs.StringVar(&versionSourceOverride, "version-source", "", "where the next version comes from when several channels are tagged: module for the highest across them, channel for each channel's own, from:<channel> for the latest of that channel")
+	fs.StringVar(&buildMetadata, "build-metadata", "", "template of build metadata to append to created versions, such as {{.ShortCommit}} or build.{{.BuildNumber}}")
+}
 
-	flag.StringVar(&moduleName, "m", "", "module name")
-	flag.StringVar(&releaseChannel, "r", "", "release channel")
-	flag.Parse()
+// Function to register the flags of the tagging flow on a flag set
+func registerTagFlags(fs *flag.FlagSet) {
+	fs.StringVar(&moduleName, "m", "", "module name")
+	fs.StringVar(&releaseChannel, "r", "", "release channel")
+	fs.BoolVar(&ignoreCase, "i", false, "match module and release channel names case-insensitively, normalizing input")
+	fs.StringVar(&commitRef, "c", "HEAD", "commit to tag, or a remote branch such as origin/release-1.x, fetched when missing")
+	registerBumpFlags(fs)
+	fs.StringVar(&searchQuery, "search", "", "pick the commit to tag among those whose message contains this text, starting from -c")
+	fs.BoolVar(&allowTemporary, "allow-temporary", false, "tag a commit that looks like a temporary merge queue commit")
+	fs.StringVar(&remindText, "remind", "", "schedule a follow-up reminder about the created tags, such as \"promote to prod if staging is healthy\", listed by 'version reminders'")
+	fs.StringVar(&remindIn, "remind-in", "", "when the reminder is due, such as 3d or 36h (default "+defaultRemindIn+")")
+	fs.BoolVar(&reproduceAfter, "reproduce", false, "rebuild the created tags in a clean worktree and compare the artifacts with the published ones")
+	fs.StringVar(&verifyCommand, "verify", "", "command that must succeed against the commit before it is tagged")
+	fs.BoolVar(&pushCreated, "push", false, "push the created tags to the remote")
+	fs.StringVar(&pushRemote, "remote", "origin", "remote to push created tags to")
+	fs.BoolVar(&floatingAliases, "aliases", false, "also move the floating major and major.minor alias tags, such as app/prod/v1 and app/prod/v1.4, to the created versions")
+	fs.BoolVar(&checkRemote, "check-remote", false, "make sure the remote does not already have the versions to tag, which --push always does")
+	fs.StringVar(&mirrorRemote, "mirror", "", "secondary remote (name or URL) to mirror created tags to")
+	fs.StringVar(&mirrorSSHCommand, "mirror-ssh-command", "", "ssh command used only for the mirror remote (default $VERSION_MIRROR_SSH_COMMAND)")
+	fs.Var(&excludes, "exclude", "glob of modules or release channels to skip, may be repeated")
+	fs.BoolVar(&releaseNotes, "notes", false, "create annotated tags carrying release notes generated from the commits since the previous tag")
+	fs.StringVar(&notesCommand, "notes-command", "", "command rewriting the release notes from stdin to stdout (default notes_command from the configuration)")
+	fs.BoolVar(&noNotesCache, "no-cache", false, "classify the commits of the release notes again instead of using the cache")
+	registerSummaryFlags(fs)
+	fs.StringVar(&cpuProfile, "profile-cpu", "", "write a CPU profile to this file")
+	fs.StringVar(&memProfile, "profile-mem", "", "write a memory profile to this file")
+	fs.BoolVar(&dryRun, "dry-run", false, "print the tags that would be created without creating them, failing if any already exists")
+	fs.BoolVar(&nonInteractive, "yes", false, "never prompt: answer yes to confirmations and fail when -m or -r is missing")
+	fs.BoolVar(&nonInteractive, "non-interactive", false, "same as --yes")
+	fs.BoolVar(&plainPrompts, "plain-prompts", false, "ask numbered plain-text questions, for screen readers and limited terminals")
+}
 
+func run(fs *flag.FlagSet) int {
 	log.Info().Msg("Welcome to the Tag Generator CLI")
+	moduleName, releaseChannel = impliedNames(moduleName, releaseChannel)
+
+	if err := resolveBump(); err != nil {
+		log.Error().Err(err).Msg("invalid bump")
+		return 2
+	}
+	if err := validateSummaryFormat(); err != nil {
+		log.Error().Err(err).Msg("invalid summary format")
+		return 2
+	}
+	if err := validateReminder(); err != nil {
+		log.Error().Err(err).Msg("invalid reminder")
+		return 2
+	}
+	if readOnly && !dryRun {
+		log.Error().Err(errReadOnly).Msg("only --dry-run can run, no tags are created in read-only mode")
+		return 2
+	}
 
-	modules, releases, err := getCurrentModules()
+	showDueReminders()
+	idx, err := scanTagIndex()
 	if err != nil {
 		log.Error().Err(err).Msgf("Error reading current modules: %v", err)
-		return
+		return 1
+	}
+	config, err := loadConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read configuration")
+		return 1
+	}
+	modules, releases := getCurrentModules(idx)
+	modules = activeModules(config, mergeNames(modules, config.Modules))
+	releases = mergeNames(releases, config.Channels)
+
+	interactive := len(moduleName) == 0 || len(releaseChannel) == 0
+	if interactive && nonInteractive {
+		log.Error().Msg("both -m and -r are required when running non-interactively")
+		return 2
 	}
-
 	if len(moduleName) == 0 {
-		// Get input for module name
-		log.Info().Strs("modules", modules).Msg("Enter module name from list:")
-		scanner := bufio.NewScanner(os.Stdin)
-		scanner.Scan()
-		moduleName = scanner.Text()
-
-		if !slices.Contains(modules, moduleName) {
-			log.Info().Msg("Are you sure you want to create new module (yes/no)?")
-			scanner := bufio.NewScanner(os.Stdin)
-			scanner.Scan()
-			yesOrNo := scanner.Text()
-			if yesOrNo != "yes" {
+		// Get input for module name, offering recent selections first
+		explain("module")
+		history := loadHistory()
+		answer := promptChoice("module", "modules", modules, ownerLabels(config, modules), recentShortcuts(history)...)
+		recent, fromHistory := pickRecent(answer, history)
+		if fromHistory {
+			moduleName = recent.Module
+			if len(releaseChannel) == 0 {
+				releaseChannel = recent.Channel
+			}
+		} else {
+			moduleName = resolveName(answer, modules)
+		}
+
+		if !fromHistory && !isPattern(moduleName) && !slices.Contains(modules, moduleName) {
+			suggestExisting("module", moduleName, modules)
+			explain("new")
+			if !confirm("Are you sure you want to create new module") {
 				log.Error().Msgf("invalid module name entered")
-				os.Exit(1)
-				return
+				return 1
 			}
 		}
+	} else {
+		moduleName = resolveModuleArg(moduleName, modules)
 	}
 
 	if len(releaseChannel) == 0 {
 		// Get input for release channel
-		log.Info().Strs("releases", releases).Msg("Enter release channel from list:")
-		scanner := bufio.NewScanner(os.Stdin)
-		scanner.Scan()
-		releaseChannel = scanner.Text()
+		explain("channel")
+		releaseChannel = resolveName(promptChoice("release channel", "releases", releases, nil), releases)
 
 		if !slices.Contains(releases, releaseChannel) {
-			log.Info().Msg("Are you sure you want to create new release channel (yes/no)?")
-			scanner := bufio.NewScanner(os.Stdin)
-			scanner.Scan()
-			yesOrNo := scanner.Text()
-			if yesOrNo != "yes" {
+			suggestExisting("release channel", releaseChannel, releases)
+			explain("new")
+			if !confirm("Are you sure you want to create new release channel") {
 				log.Error().Msgf("invalid release channel entered")
-				os.Exit(1)
-				return
+				return 1
 			}
 		}
 	}
 
-	if strings.ContainsRune(moduleName, ' ') || strings.ContainsRune(releaseChannel, ' ') {
-		log.Error().Msgf("invalid characters (space) in module name or release channel")
-		os.Exit(1)
-		return
+	targets, multiRelease, err := resolveTargets(idx, moduleName, releaseChannel)
+	if err != nil {
+		log.Error().Err(err).Msg("invalid module or release channel entered")
+		return 1
+	}
+	if targets = dropArchived(config, moduleName, targets); len(targets) == 0 {
+		log.Error().Msg("every module is archived")
+		return 1
+	}
+	for _, m := range targets {
+		if source := versionSourceOf(m); len(multiRelease) > 1 || strings.HasPrefix(source, sourceFrom) {
+			log.Info().Str("module", m).Str("version_source", source).Msg(describeVersionSource(source))
+		}
 	}
 
-	if len(strings.TrimSpace(releaseChannel)) == 0 {
-		log.Error().Msgf("invalid module name entered")
-		os.Exit(1)
+	if searchQuery != "" {
+		if commitRef, err = searchCommit(searchQuery, commitRef); err != nil {
+			log.Error().Err(err).Msg("unable to find commit to tag")
+			return 1
+		}
+	}
+	commit, err := resolveCommit(commitRef)
+	if err != nil {
+		log.Error().Err(err).Str("commit", commitRef).Msg("unable to resolve commit to tag")
+		return 1
+	}
+	if err := resolveBumps(idx, config, targets, multiRelease, commit); err != nil {
+		log.Error().Err(err).Msg("unable to choose the bump")
+		return 1
+	}
+	if blockers := releaseBlockers(idx, config, targets, multiRelease, commit, true); len(blockers) > 0 {
+		reportBlockers(blockers)
+		return 1
+	}
+	if pushCreated || checkRemote {
+		if err := resolveRemoteAhead(idx, pushRemote, targets, multiRelease); err != nil {
+			log.Error().Err(err).Msg("not tagging")
+			return 1
+		}
+	}
+	if !noSummary {
+		printImpact(os.Stderr, idx, config, targets, multiRelease, commit)
+	}
+	if dryRun {
+		return dryRunTags(idx, config, targets, multiRelease, commit)
+	}
+	if interactive && len(multiRelease) > 1 && !reviewMatrix(idx, config, targets, multiRelease, commit) {
+		log.Error().Msg("release cancelled")
+		return 1
 	}
 
-	if len(strings.TrimSpace(releaseChannel)) == 0 {
-		log.Error().Msgf("invalid release channel entered")
-		os.Exit(1)
+	var results []tagResult
+	defer func() { writeSummary(results) }()
+
+	explain("tag")
+	var plan []tagResult
+	for _, m := range targets {
+		planned, err := planTags(idx, config, m, multiRelease, commit)
+		if err != nil {
+			log.Error().Err(err).Msg("Error creating git tag. Exiting.")
+			return 1
+		}
+		plan = append(plan, planned...)
+	}
+	if err := checkTagsFree(plan); err != nil {
+		log.Error().Err(err).Msg("not tagging")
+		return 1
+	}
+	results, err = createTags(config, plan)
+	if err != nil {
+		log.Error().Err(err).Msg("Error creating git tag. Exiting.")
+		return 1
 	}
 
-	multiRelease := []string{releaseChannel}
-	if strings.ContainsRune(releaseChannel, ',') {
-		log.Info().Msg("please note first release channel version will be used for all subsequent release channels")
-		multiRelease = strings.Split(releaseChannel, ",")
+	if pushCreated {
+		explain("push")
+	}
+	if !publishResults(results) {
+		return 1
 	}
+	if reproduceAfter && !reproduceResults(config, results, pushCreated, pushRemote) {
+		return 1
+	}
+	scheduleReminder(results)
 
-	// Read and display the current version
-	currentVersion, err := parseCurrentVersion(moduleName, multiRelease)
-	if err != nil {
-		log.Error().Err(err).Msgf("Error reading current version: %v", err)
-		return
+	configureNewChannels(idx, config, multiRelease, interactive)
+	recordInvocation(fs)
+	if interactive {
+		offerSavePreset()
+	}
+
+	if pushCreated {
+		log.Info().Str("remote", pushRemote).Msg("Tags created and pushed")
+	} else {
+		log.Info().Msg("Tags updated in local repository, 'version push' and enjoy")
+	}
+	return 0
+}
+
+// Function to print the tags a run would create without touching the
+// repository, failing when any of them already exists
+func dryRunTags(idx *tagIndex, config *Config, targets, multiRelease []string, commit string) int {
+	var planned []tagResult
+	conflicts := 0
+	for _, m := range targets {
+		plan := planModule(idx, m, multiRelease)
+		for i := range plan {
+			plan[i].Commit = commit
+		}
+		if err := assignMonotonic(config, plan, isExplicit); err != nil {
+			log.Error().Err(err).Str("module", m).Msg("version cannot be used")
+			conflicts++
+		}
+		if err := applyBuildMetadata(config, plan); err != nil {
+			log.Error().Err(err).Str("module", m).Msg("invalid build metadata")
+			conflicts++
+		}
+		for _, result := range plan {
+			if existing, err := resolveCommit("refs/tags/" + result.Tag); err == nil {
+				log.Error().Str("tag", result.Tag).Str("commit", existing).Msg("tag already exists")
+				conflicts++
+			}
+			planned = append(planned, result)
+		}
+	}
+	writeSummary(planned)
+	if conflicts > 0 {
+		log.Error().Int("conflicts", conflicts).Msg("Dry run found conflicting tags")
+		return 1
+	}
+	log.Info().Int("tags", len(planned)).Msg("Dry run, no tags were created")
+	return 0
+}
+
+// Function to push the tags of a run when requested, record them as the
+// current session and mirror them, reporting whether every step succeeded
+func publishResults(results []tagResult) bool {
+	moveAliases(results)
+	goTagsOK := createGoTags(results)
+	var tags, aliases, goTags []string
+	collect := func(r tagResult) {
+		tags = append(tags, r.Tag)
+		aliases = append(aliases, r.Aliases...)
+		if r.GoTag != "" {
+			goTags = append(goTags, r.GoTag)
+		}
+	}
+	for _, r := range results {
+		collect(r)
+	}
+	aliasesOK := true
+	if pushCreated {
+		pushed := pushToRemote(pushRemote, tags)
+		tags, aliases, goTags = tags[:0], aliases[:0], goTags[:0]
+		for i := range results {
+			results[i].Pushed = pushed[results[i].Tag]
+			if results[i].Pushed {
+				collect(results[i])
+			}
+		}
+		aliasesOK = pushAliases(pushTarget{Remote: pushRemote}, aliases)
+		goTagsOK = pushGoTags(pushTarget{Remote: pushRemote}, goTags) && goTagsOK
 	}
+	recordSession(results)
+	// Only tags that reached the primary remote are mirrored
+	mirrorOK := mirrorTags(tags)
+	if mirrorRemote != "" {
+		mirrorOK = pushAliases(mirrorTarget(), aliases) && pushGoTags(mirrorTarget(), goTags) && mirrorOK
+	}
+	notifyPlugins(results)
+	return len(tags) == len(results) && mirrorOK && aliasesOK && goTagsOK
+}
 
-	log.Info().Interface("version", currentVersion).Msgf("Current version")
+// Function to compute the tags a run would create for a module on each
+// release channel, without touching the repository. Every channel gets the
+// version following the highest one among them, or the one following its
+// own with the channel version source.
+func planModule(idx *tagIndex, moduleName string, multiRelease []string) []tagResult {
+	source := versionSourceOf(moduleName)
+	// Counter and snapshot channels count on their own and play no part in
+	// the version of the others
+	versioned := slices.DeleteFunc(slices.Clone(multiRelease), func(r string) bool {
+		return isCounterChannel(r) || isSnapshotChannel(r)
+	})
+	currentVersion := parseCurrentVersion(idx, moduleName, versioned)
+	if channel, ok := strings.CutPrefix(source, sourceFrom); ok {
+		currentVersion = parseCurrentVersion(idx, moduleName, []string{channel})
+	}
+	var plan []tagResult
 	for _, r := range multiRelease {
-		// Generate and display the next version
-		nextVersion := generateNextVersion(moduleName, r, currentVersion)
-		if nextVersion == "" {
-			log.Error().Msg("Error generating next version. Exiting.")
-			return
+		if source == sourceChannel {
+			currentVersion = parseCurrentVersion(idx, moduleName, []string{r})
 		}
+		old := currentVersion
+		if isCounterChannel(r) {
+			old = currentCounter(idx, moduleName, r)
+		}
+		var previous string
+		if version, ok := idx.latest[moduleName][r]; ok {
+			previous = formatTag(moduleName, r, version)
+		}
+		if branchLine != nil && !isCounterChannel(r) {
+			// A hotfix follows the release it fixes, not the newest one
+			previous = ""
+			if version, ok := latestOnLine(moduleName, []string{r}, *branchLine); ok {
+				previous = formatTag(moduleName, r, version)
+			}
+		}
+		next := nextVersionWith(moduleName, old, channelPartOf(moduleName, r))
+		if isSnapshotChannel(r) {
+			old, _ = idx.latest[moduleName][r]
+			next = nextSnapshot(idx, moduleName, r, channelPartOf(moduleName, r), time.Now())
+		}
+		result := tagResult{
+			Module:   moduleName,
+			Channel:  r,
+			Old:      old,
+			New:      next,
+			Tag:      formatTag(moduleName, r, next),
+			Previous: previous,
+		}
+		if !isCounterChannel(r) && !isSnapshotChannel(r) && (len(versioned) > 1 || strings.HasPrefix(source, sourceFrom)) {
+			result.VersionSource = source
+		}
+		plan = append(plan, result)
+	}
+	return plan
+}
 
-		log.Info().Msgf("Generated next version: %s", nextVersion)
+// Function to plan the next tag of a module on each release channel, with
+// the commit to tag, checked against the monotonic rules and with its build
+// metadata
+func planTags(idx *tagIndex, config *Config, moduleName string, multiRelease []string, commit string) ([]tagResult, error) {
+	if verifyCommand != "" {
+		if err := runVerify(verifyCommand, moduleName, commit); err != nil {
+			return nil, fmt.Errorf("verify command failed for module %s: %w", moduleName, err)
+		}
+	}
+
+	// Read and display the current version
+	plan := planModule(idx, moduleName, multiRelease)
+	if noSummary {
+		log.Info().Str("module", moduleName).Interface("version", plan[0].Old).Msgf("Current version")
+	}
 
-		if err = createGitTag(nextVersion); err != nil {
-			log.Error().Msg("Error creating git tag. Exiting.")
-			return
+	for i := range plan {
+		plan[i].Commit = commit
+	}
+	if err := assignMonotonic(config, plan, isExplicit); err != nil {
+		return nil, err
+	}
+	if err := applyBuildMetadata(config, plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// Function to check that none of the tags planned for a run exists yet,
+// before any of them is created, so a run stopped by a taken version leaves
+// no tags behind
+func checkTagsFree(plan []tagResult) error {
+	var problems []error
+	for _, result := range plan {
+		if existing, err := resolveCommit("refs/tags/" + result.Tag); err == nil {
+			problems = append(problems, fmt.Errorf("version %s is already taken, %s exists on %s", result.New, result.Tag, shortHash(existing)))
 		}
 	}
+	return errors.Join(problems...)
+}
+
+// Function to create the planned tags of a run
+func createTags(config *Config, plan []tagResult) ([]tagResult, error) {
+	var results []tagResult
+	for _, result := range plan {
+		if explicitVersion != nil {
+			if compareVersions(result.New, result.Old) <= 0 {
+				log.Warn().Str("tag", result.Tag).Str("current", result.Old.String()).Msg("explicit version is not newer than the current one")
+			}
+		}
+		if noSummary {
+			log.Info().Msgf("Generated next version: %s", result.Tag)
+		}
 
-	log.Info().Msg("Tags updated in local repository, 'git push --tags' and enjoy")
+		var err error
+		if releaseNotes {
+			err = createNotesTag(config, result)
+		} else {
+			err = createGitTag(result.Tag, result.Commit)
+		}
+		if err != nil {
+			return results, err
+		}
+		if noSummary {
+			log.Info().Str("tag", result.Tag).Msg("Git tag created successfully")
+		}
+		results = append(results, result)
+	}
+	return results, nil
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+)
+
+// channelParts are bumps chosen for single release channels on the preview
+// matrix, overriding the bump of the run on those channels
+var channelParts map[string]string
+
+// Function to tell the bump a release channel takes
+func channelPartOf(module, channel string) string {
+	if part, ok := channelParts[channel]; ok {
+		return part
+	}
+	return bumpPartOf(module)
+}
+
+// Function to print the matrix of the tags a run would create on each
+// release channel, with the version they follow and the commit they point at
+func printMatrix(idx *tagIndex, config *Config, targets, channels []string, commit string) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHANNEL\tMODULE\tCURRENT\tBUMP\tNEXT\tCOMMIT")
+	var rows []tagResult
+	for _, m := range targets {
+		plan := planModule(idx, m, channels)
+		for i := range plan {
+			plan[i].Commit = commit
+		}
+		assignMonotonic(config, plan, isExplicit)
+		applyBuildMetadata(config, plan)
+		rows = append(rows, plan...)
+	}
+	// Rows are grouped by channel, in the order the channels were given
+	slices.SortStableFunc(rows, func(a, b tagResult) int {
+		return slices.Index(channels, a.Channel) - slices.Index(channels, b.Channel)
+	})
+	for _, r := range rows {
+		part := channelPartOf(r.Module, r.Channel)
+		switch {
+		case explicitVersion != nil:
+			part = "set"
+		case isCounterChannel(r.Channel):
+			part = "counter"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Channel, r.Module, r.Old, part, r.New, shortHash(r.Commit))
+	}
+	tw.Flush()
+}
+
+// Function to check a bump chosen for a release channel on the preview
+// matrix against the schemes of the modules tagged on it
+func checkChannelPart(targets []string, channel, part string) error {
+	switch {
+	case explicitVersion != nil:
+		return fmt.Errorf("the version is set with --set, there is no bump to change")
+	case isCounterChannel(channel):
+		return fmt.Errorf("%s counts builds, its bump cannot change", channel)
+	}
+	switch part {
+	case "patch", "minor", "major", "build":
+	default:
+		return fmt.Errorf("unknown bump %q, expected patch, minor, major or build", part)
+	}
+	for _, m := range targets {
+		if part != "patch" && schemeOf(m) == schemeCalver {
+			return fmt.Errorf("%s uses calendar versions, which follow the date and take no %s bump", m, part)
+		}
+		if part == "build" && schemeOf(m) != schemeFourPart {
+			return fmt.Errorf("%s has no build number, only the %s scheme takes a build bump", m, schemeFourPart)
+		}
+		if branchLine != nil && part != "patch" && part != "build" {
+			return fmt.Errorf("a %s bump of %s leaves the %s line, hotfixes take patch bumps only", part, m, branchLine)
+		}
+	}
+	return nil
+}
+
+// Function to show the tags of an interactive run on several release
+// channels before creating them, letting the bump of each channel change
+// until the matrix is confirmed. It reports whether the run should go on.
+func reviewMatrix(idx *tagIndex, config *Config, targets, channels []string, commit string) bool {
+	for {
+		printMatrix(idx, config, targets, channels, commit)
+		answer := promptText("Type yes to create these tags, no to cancel, or channel=bump such as " + channels[0] + "=minor to change the bump of a channel")
+		switch answer {
+		case "yes":
+			return true
+		case "no", "":
+			return false
+		}
+		channel, part, ok := strings.Cut(answer, "=")
+		channel, part = strings.TrimSpace(channel), strings.TrimSpace(part)
+		if !ok || !slices.Contains(channels, channel) {
+			log.Warn().Strs("channels", channels).Msgf("%q is not channel=bump for a channel of this run", answer)
+			continue
+		}
+		if err := checkChannelPart(targets, channel, part); err != nil {
+			log.Warn().Err(err).Str("channel", channel).Msg("bump not changed")
+			continue
+		}
+		if channelParts == nil {
+			channelParts = make(map[string]string)
+		}
+		channelParts[channel] = part
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"regexp"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Function to handle `version migrate`, copying tags of a legacy scheme such
+// as v1.2.3 or release-1.2.3 to module/channel/vX.Y.Z tags on the same commits
+func runMigrate(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	module := fs.String("module", "", "module of the migrated tags")
+	channel := fs.String("channel", "", "release channel of the migrated tags")
+	patternArg := fs.String("pattern", `v?(\d+)\.(\d+)\.(\d+)`, "regular expression matching a whole legacy tag, capturing major, minor and patch")
+	remove := fs.Bool("delete", false, "delete the legacy tags once migrated")
+	dryRun := fs.Bool("dry-run", false, "only print the tags that would be migrated")
+	fs.Parse(args)
+	*module, *channel = impliedNames(*module, *channel)
+
+	if *module == "" || *channel == "" {
+		log.Error().Msg("both --module and --channel are required")
+		return 2
+	}
+	if err := validateModule(*module); err != nil {
+		log.Error().Err(err).Msg("invalid module name entered")
+		return 2
+	}
+	if err := validateChannel(*channel); err != nil {
+		log.Error().Err(err).Msg("invalid release channel entered")
+		return 2
+	}
+	pattern, err := regexp.Compile(`^(?:` + *patternArg + `)$`)
+	if err != nil {
+		log.Error().Err(err).Msg("invalid pattern")
+		return 2
+	}
+	if pattern.NumSubexp() < 3 {
+		log.Error().Str("pattern", *patternArg).Msg("pattern must capture major, minor and patch")
+		return 2
+	}
+
+	var legacy []string
+	if err := streamTags(func(tag string) {
+		if _, _, _, ok := parseTag(tag); !ok && pattern.MatchString(tag) {
+			legacy = append(legacy, tag)
+		}
+	}); err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	if len(legacy) == 0 {
+		log.Info().Str("pattern", *patternArg).Msg("No legacy tags match")
+		return 0
+	}
+
+	failed := false
+	migrated := 0
+	var created []tagResult
+	for _, old := range legacy {
+		matches := pattern.FindStringSubmatch(old)
+		var numbers [3]int
+		for i := range numbers {
+			numbers[i], err = strconv.Atoi(matches[i+1])
+			if err != nil {
+				break
+			}
+		}
+		if err != nil {
+			log.Warn().Str("tag", old).Msg("skipping tag without a numeric version")
+			continue
+		}
+		tag := formatTag(*module, *channel, Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]})
+		commit, err := resolveCommit("refs/tags/" + old)
+		if err != nil {
+			log.Warn().Err(err).Str("tag", old).Msg("skipping tag that does not point at a commit")
+			continue
+		}
+
+		if existing, err := resolveCommit("refs/tags/" + tag); err == nil {
+			if existing != commit {
+				log.Error().Str("tag", old).Str("existing", tag).Msg("tag already exists on a different commit")
+				failed = true
+				continue
+			}
+		} else if *dryRun {
+			log.Info().Str("from", old).Str("to", tag).Msg("Would migrate")
+		} else if err := copyTag(old, tag, commit, false); err != nil {
+			log.Error().Err(err).Str("tag", old).Msg("unable to create migrated tag")
+			failed = true
+			continue
+		} else {
+			log.Info().Str("from", old).Str("to", tag).Msg("Migrated")
+			created = append(created, tagResult{Module: *module, Channel: *channel, Tag: tag, Commit: commit})
+		}
+		migrated++
+
+		if *remove && !*dryRun {
+			if _, err := gitOutput("tag", "--delete", old); err != nil {
+				log.Error().Err(err).Str("tag", old).Msg("unable to delete legacy tag")
+				failed = true
+			}
+		}
+	}
+
+	if *dryRun {
+		log.Info().Int("matched", len(legacy)).Msg("Dry run finished, nothing was changed")
+		return 0
+	}
+	if len(created) > 0 {
+		recordSession(created)
+	}
+	log.Info().Int("migrated", migrated).Int("matched", len(legacy)).Msg("Migration finished, 'version push' publishes the new tags")
+	if failed {
+		return 1
+	}
+	return 0
+}
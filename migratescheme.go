@@ -0,0 +1,202 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// schemeTransition maps the latest version of a channel to the version
+// continuing it in another scheme. A transition tag is only needed when the
+// two differ.
+type schemeTransition struct {
+	Channel string
+	Current Version
+	Tag     string
+	Commit  string
+	Next    Version
+}
+
+// Function to tell whether a transition needs a tag of its own
+func (t schemeTransition) tagged() bool {
+	return t.Current.String() != t.Next.String()
+}
+
+// Function to compute the first version of a scheme continuing v. Versions
+// already valid in the scheme are kept, so rollover and semver move to each
+// other freely; otherwise the result sorts after v so the new scheme takes
+// over as the latest version. Four-part versions take the number of releases
+// so far as their build number.
+func transitionVersion(v Version, scheme string, releases int, now time.Time) Version {
+	switch scheme {
+	case schemeSemver, schemeRollover:
+		if v.FourSegment {
+			return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+		}
+	case schemeFourPart:
+		if !v.FourSegment {
+			return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch, Prerelease: v.Prerelease, BuildNumber: releases, FourSegment: true}
+		}
+	case schemeCalver:
+		year, month := now.Year(), int(now.Month())
+		if year > v.Major || year == v.Major && month > v.Minor {
+			return Version{Major: year, Minor: month, Patch: 0, ZeroPadded: true}
+		}
+	}
+	return v
+}
+
+// Function to plan the move of a module to a scheme, channel by channel.
+// Counter channels number builds whatever the scheme and are left alone.
+func planSchemeTransitions(idx *tagIndex, module, scheme string, start *Version) ([]schemeTransition, error) {
+	entries, err := readTagEntries(func(m, _ string) bool { return m == module })
+	if err != nil {
+		return nil, err
+	}
+	var channels []string
+	for channel := range idx.latest[module] {
+		if !isCounterChannel(channel) {
+			channels = append(channels, channel)
+		}
+	}
+	sort.Strings(channels)
+
+	var transitions []schemeTransition
+	for _, channel := range channels {
+		current := idx.latest[module][channel]
+		if current.Counter {
+			continue
+		}
+		t := schemeTransition{Channel: channel, Current: current, Tag: formatTag(module, channel, current)}
+		if t.Commit, err = resolveCommit("refs/tags/" + t.Tag); err != nil {
+			return nil, fmt.Errorf("%s: %w", t.Tag, err)
+		}
+		t.Next = transitionVersion(current, scheme, len(entries), time.Now())
+		if start != nil {
+			t.Next = *start
+			if compareVersions(t.Next, current) <= 0 {
+				return nil, fmt.Errorf("start version %s does not sort after %s, the latest on %s", start, current, channel)
+			}
+		}
+		transitions = append(transitions, t)
+	}
+	return transitions, nil
+}
+
+// Function to handle `version migrate-scheme`, moving a module to another
+// version scheme: the latest version of every channel is mapped to the new
+// scheme, tagged on the same commit where the mapping changes it, and the
+// module's scheme is set in the configuration
+func runMigrateScheme(args []string) int {
+	fs := flag.NewFlagSet("migrate-scheme", flag.ExitOnError)
+	fs.StringVar(&moduleName, "m", "", "module to migrate")
+	to := fs.String("to", "", "scheme to migrate to: semver, rollover, calver or fourpart")
+	startArg := fs.String("start", "", "first version in the new scheme, for every channel, instead of the computed one")
+	dryRun := fs.Bool("dry-run", false, "only print the mapping")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	fs.Parse(args)
+	moduleName, _ = impliedNames(moduleName, "")
+
+	if moduleName == "" || *to == "" {
+		log.Error().Msg("-m and --to are required")
+		return 2
+	}
+	if err := validateScheme(*to); err != nil {
+		log.Error().Err(err).Msg("invalid scheme")
+		return 2
+	}
+	var start *Version
+	if *startArg != "" {
+		v, err := parseVersion(*startArg)
+		if err != nil {
+			log.Error().Err(err).Msg("invalid start version")
+			return 2
+		}
+		start = &v
+	}
+	if !*dryRun {
+		if err := checkWritable(); err != nil {
+			log.Error().Err(err).Msg("unable to migrate")
+			return 1
+		}
+	}
+
+	idx, err := scanTagIndex()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	config, err := loadConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read configuration")
+		return 1
+	}
+	if err := validateModule(moduleName); err != nil {
+		log.Error().Err(err).Msg("invalid module name entered")
+		return 2
+	}
+	if err := checkArchived(config, moduleName); err != nil {
+		log.Error().Err(err).Msg("not migrating")
+		return 1
+	}
+	from := schemeOf(moduleName)
+	if from == *to {
+		log.Info().Str("module", moduleName).Str("scheme", from).Msg("Module already uses the scheme")
+		return 0
+	}
+	transitions, err := planSchemeTransitions(idx, moduleName, *to, start)
+	if err != nil {
+		log.Error().Err(err).Msg("unable to map versions")
+		return 1
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHANNEL\tCURRENT\tTRANSITION\tTAG")
+	for _, t := range transitions {
+		tag := "-"
+		if t.tagged() {
+			tag = formatTag(moduleName, t.Channel, t.Next)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", t.Channel, t.Current, t.Next, tag)
+	}
+	tw.Flush()
+	if *dryRun {
+		log.Info().Str("from", from).Str("to", *to).Msg("Dry run, nothing was changed")
+		return 0
+	}
+	if !*yes && !confirm(fmt.Sprintf("Migrate %s from %s to %s", moduleName, from, *to)) {
+		log.Info().Msg("Nothing migrated")
+		return 1
+	}
+
+	var created []tagResult
+	for _, t := range transitions {
+		if !t.tagged() {
+			continue
+		}
+		tag := formatTag(moduleName, t.Channel, t.Next)
+		message := fmt.Sprintf("Transition of %s to the %s scheme", t.Tag, *to)
+		if _, err := gitOutput("tag", "--annotate", "--message", message, tag, t.Commit); err != nil {
+			log.Error().Err(err).Str("tag", tag).Msg("unable to create transition tag")
+			return 1
+		}
+		log.Info().Str("from", t.Tag).Str("to", tag).Msg("Transition tag created")
+		created = append(created, tagResult{Module: moduleName, Channel: t.Channel, Old: t.Current, New: t.Next, Tag: tag, Commit: t.Commit})
+	}
+	if err := setConfigValue([]string{"modules", moduleName, "scheme"}, *to); err != nil {
+		log.Error().Err(err).Msg("unable to update configuration")
+		return 1
+	}
+	if len(created) > 0 {
+		recordSession(created)
+		log.Info().Str("module", moduleName).Str("scheme", *to).Int("tags", len(created)).Msg("Scheme migrated, 'version push' publishes the transition tags")
+	} else {
+		log.Info().Str("module", moduleName).Str("scheme", *to).Msg("Scheme migrated, no transition tag was needed")
+	}
+	return 0
+}
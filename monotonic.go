@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// versionUse maps every version of a module to the commit it was tagged on
+// in each release channel
+type versionUse map[string]map[string]string
+
+// Function to read the versions used by a module on any channel
+func readVersionUse(module string) (versionUse, error) {
+	out, err := gitOutput("for-each-ref", "--format=%(refname:strip=2) %(objectname) %(*objectname)", layout.glob(module, ""))
+	if err != nil {
+		return nil, err
+	}
+	used := make(versionUse)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		m, channel, version, ok := parseTag(fields[0])
+		if !ok || m != module {
+			continue
+		}
+		// Annotated tags point at the commit through the tag object
+		commit := fields[len(fields)-1]
+		used.record(version, channel, commit)
+	}
+	return used, nil
+}
+
+// Function to remember that a version was tagged on a commit
+func (u versionUse) record(version Version, channel, commit string) {
+	channels, ok := u[version.WithoutBuild().String()]
+	if !ok {
+		channels = make(map[string]string)
+		u[version.WithoutBuild().String()] = channels
+	}
+	channels[channel] = commit
+}
+
+// Function to find a channel where a version was tagged on another commit
+func (u versionUse) conflict(version Version, commit string) (string, bool) {
+	for channel, used := range u[version.WithoutBuild().String()] {
+		if used != commit {
+			return channel, true
+		}
+	}
+	return "", false
+}
+
+// Function to check that tagging a version of a module on a commit does not
+// reuse the version with different content in monotonic mode
+func checkMonotonic(config *Config, module string, version Version, commit string) error {
+	if !config.Monotonic {
+		return nil
+	}
+	used, err := readVersionUse(module)
+	if err != nil {
+		return err
+	}
+	if channel, ok := used.conflict(version, commit); ok {
+		return fmt.Errorf("%s is already used on another commit, see %s", version, formatTag(module, channel, version))
+	}
+	return nil
+}
+
+// Function to move a planned tag to the next version not used on another
+// commit on any channel of its module. Explicit versions are never moved, a
+// conflict is an error instead.
+func (u versionUse) assign(result *tagResult, explicit bool) error {
+	for {
+		channel, ok := u.conflict(result.New, result.Commit)
+		if !ok {
+			break
+		}
+		if explicit {
+			return fmt.Errorf("%s is already used on another commit, see %s", result.New, formatTag(result.Module, channel, result.New))
+		}
+		skipped := result.New
+		result.New, _ = bumpVersion(result.New, "patch", schemeOf(result.Module))
+		result.Tag = formatTag(result.Module, result.Channel, result.New)
+		log.Info().Str("module", result.Module).Str("version", skipped.String()).Str("channel", channel).Msg("Version already used on another commit, skipping it")
+	}
+	u.record(result.New, result.Channel, result.Commit)
+	return nil
+}
+
+// Function to assign monotonic versions to planned tags, whose commits must be
+// set, when the configuration asks for them. explicit tells which tags have
+// a version chosen by the user.
+func assignMonotonic(config *Config, results []tagResult, explicit func(i int) bool) error {
+	if !config.Monotonic {
+		return nil
+	}
+	uses := make(map[string]versionUse)
+	for i := range results {
+		used, ok := uses[results[i].Module]
+		if !ok {
+			var err error
+			if used, err = readVersionUse(results[i].Module); err != nil {
+				return err
+			}
+			uses[results[i].Module] = used
+		}
+		if err := used.assign(&results[i], explicit(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Function to handle `version next`, printing the tags the next run would
+// create without touching the repository
+func runNext(args []string) int {
+	fs := flag.NewFlagSet("next", flag.ExitOnError)
+	fs.StringVar(&moduleName, "m", "", "module name or glob")
+	fs.StringVar(&releaseChannel, "r", "", "release channel, or a comma separated list")
+	fs.BoolVar(&ignoreCase, "i", false, "match module and release channel names case-insensitively, normalizing input")
+	fs.Var(&excludes, "exclude", "glob of modules or release channels to skip, may be repeated")
+	fs.StringVar(&commitRef, "c", "HEAD", "commit that would be tagged, which matters in monotonic mode")
+	registerBumpFlags(fs)
+	fs.Parse(args)
+	moduleName, releaseChannel = impliedNames(moduleName, releaseChannel)
+
+	if moduleName == "" || releaseChannel == "" {
+		log.Error().Msg("both -m and -r are required")
+		return 2
+	}
+	if err := resolveBump(); err != nil {
+		log.Error().Err(err).Msg("invalid bump")
+		return 2
+	}
+
+	idx, err := scanTagIndex()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	config, err := loadConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read configuration")
+		return 1
+	}
+	moduleName = resolveModuleArg(moduleName, idx.modules())
+	targets, channels, err := resolveTargets(idx, moduleName, releaseChannel)
+	if err != nil {
+		log.Error().Err(err).Msg("invalid module or release channel entered")
+		return 1
+	}
+
+	commit, err := resolveCommit(commitRef)
+	if err != nil {
+		log.Error().Err(err).Str("commit", commitRef).Msg("unable to resolve commit")
+		return 1
+	}
+	if err := resolveBumps(idx, config, targets, channels, commit); err != nil {
+		log.Error().Err(err).Msg("unable to choose the bump")
+		return 1
+	}
+	if err := checkBump(idx, targets, channels); err != nil {
+		log.Error().Err(err).Msg("invalid bump")
+		return 1
+	}
+
+	for _, m := range targets {
+		plan := planModule(idx, m, channels)
+		for i := range plan {
+			plan[i].Commit = commit
+		}
+		if err := assignMonotonic(config, plan, isExplicit); err != nil {
+			log.Error().Err(err).Str("module", m).Msg("version cannot be used")
+			return 1
+		}
+		if err := applyBuildMetadata(config, plan); err != nil {
+			log.Error().Err(err).Str("module", m).Msg("invalid build metadata")
+			return 1
+		}
+		for _, result := range plan {
+			fmt.Println(result.Tag)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// notesRef holds the generated release notes whenever the notes command
+// changed them, attached to the tag object
+const notesRef = "refs/notes/version-original"
+
+var (
+	releaseNotes bool
+	notesCommand string
+)
+
+// Function to generate release notes for a tag from the commits since the
+// previous tag on its channel, grouped by category
+func generateNotes(config *Config, result tagResult) (string, error) {
+	entries, err := readChangelog(result.Previous, result.Commit, config.Modules[result.Module].Paths)
+	if err != nil {
+		return "", err
+	}
+	sections, err := classifyEntries(config, entries)
+	if err != nil {
+		return "", err
+	}
+	var notes strings.Builder
+	fmt.Fprintf(&notes, "Release %s\n", result.Tag)
+	order := sectionOrder(sections)
+	for _, category := range order {
+		// A single section needs no heading
+		if len(order) > 1 {
+			fmt.Fprintf(&notes, "\n%s:\n", category)
+		} else {
+			notes.WriteString("\n")
+		}
+		for _, e := range sections[category] {
+			fmt.Fprintf(&notes, "- %s (%s, %s)\n", e.Subject, shortHash(e.Hash), e.Author)
+		}
+	}
+	if len(entries) == 0 {
+		notes.WriteString("\nNo changes\n")
+	}
+	return notes.String(), nil
+}
+
+// Function to pass release notes through the notes command, such as a
+// translation or summarization script, which reads them on stdin and prints
+// the notes to use
+func processNotes(command, notes string, result tagResult) (string, error) {
+	cmd := shellCommand(command)
+	cmd.Dir = repoDir
+	cmd.Env = append(os.Environ(),
+		"VERSION_MODULE="+result.Module, "VERSION_CHANNEL="+result.Channel,
+		"VERSION_TAG="+result.Tag, "VERSION_COMMIT="+result.Commit)
+	cmd.Stdin = strings.NewReader(notes)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("notes command %q: %w", command, err)
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return "", fmt.Errorf("notes command %q printed no notes", command)
+	}
+	return string(out), nil
+}
+
+// Function to create an annotated tag whose message is the release notes of
+// the tag, keeping the generated notes in a git note on the tag when the
+// notes command changed them
+func createNotesTag(config *Config, result tagResult) error {
+	original, err := generateNotes(config, result)
+	if err != nil {
+		return fmt.Errorf("unable to generate release notes for %s: %w", result.Tag, err)
+	}
+	notes := original
+	command := notesCommand
+	if command == "" {
+		command = config.NotesCommand
+	}
+	if command != "" {
+		if notes, err = processNotes(command, original, result); err != nil {
+			return err
+		}
+	}
+
+	if _, err := gitOutputWithInput(notes, "tag", "--annotate", "--cleanup=verbatim", "--file=-", result.Tag, result.Commit); err != nil {
+		log.Error().Err(err).Str("tag", result.Tag).Msg("Git tag create error")
+		return err
+	}
+	if notes != original {
+		if _, err := gitOutputWithInput(original, "notes", "--ref="+notesRef, "add", "--force", "--file=-", "refs/tags/"+result.Tag); err != nil {
+			log.Warn().Err(err).Str("tag", result.Tag).Msg("unable to keep the original release notes")
+		}
+	}
+	return nil
+}
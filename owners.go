@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// codeownersLocations are the places GitHub and GitLab look for CODEOWNERS
+var codeownersLocations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// codeownersRule is a single pattern line of a CODEOWNERS file
+type codeownersRule struct {
+	Pattern string
+	Owners  []string
+}
+
+// Function to read the CODEOWNERS rules of the repository, if any
+func readCodeowners() []codeownersRule {
+	root, err := gitOutput("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil
+	}
+	for _, location := range codeownersLocations {
+		f, err := os.Open(filepath.Join(root, filepath.FromSlash(location)))
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+
+		var rules []codeownersRule
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") {
+				continue
+			}
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			rules = append(rules, codeownersRule{Pattern: fields[0], Owners: fields[1:]})
+		}
+		return rules
+	}
+	return nil
+}
+
+// Function to report whether a CODEOWNERS pattern covers a directory, using
+// the common subset of the gitignore-style syntax
+func (r codeownersRule) matches(dir string) bool {
+	pattern := strings.TrimSuffix(strings.TrimPrefix(r.Pattern, "/"), "/**")
+	pattern = strings.TrimSuffix(pattern, "/")
+	if pattern == "*" || pattern == "**" {
+		return true
+	}
+	if dir == pattern || strings.HasPrefix(dir, pattern+"/") {
+		return true
+	}
+	ok, _ := path.Match(pattern, dir)
+	return ok
+}
+
+// Function to find the owners of a module, preferring the configuration and
+// falling back to the last CODEOWNERS rule covering one of its paths
+func moduleOwners(config *Config, rules []codeownersRule, module string) []string {
+	moduleConfig := config.Modules[module]
+	if len(moduleConfig.Owners) > 0 {
+		return moduleConfig.Owners
+	}
+	paths := moduleConfig.Paths
+	if len(paths) == 0 {
+		paths = []string{module}
+	}
+	var owners []string
+	for _, rule := range rules {
+		for _, p := range paths {
+			if rule.matches(strings.Trim(filepath.ToSlash(p), "/")) {
+				owners = rule.Owners
+			}
+		}
+	}
+	return owners
+}
+
+// Function to label modules with their owners for display in pickers
+func ownerLabels(config *Config, modules []string) []string {
+	rules := readCodeowners()
+	labels := make([]string, len(modules))
+	for i, module := range modules {
+		labels[i] = module
+		if owners := moduleOwners(config, rules, module); len(owners) > 0 {
+			labels[i] = fmt.Sprintf("%s (%s)", module, strings.Join(owners, ", "))
+		}
+	}
+	return labels
+}
+
+// Function to check that the current git user may tag a protected channel of
+// a module. Modules without owners and unprotected channels are open to all.
+func checkOwnership(config *Config, module, channel string) error {
+	if !config.Channels[channel].Protected {
+		return nil
+	}
+	owners := moduleOwners(config, readCodeowners(), module)
+	if len(owners) == 0 {
+		return nil
+	}
+	email, _ := gitOutput("config", "user.email")
+	name, _ := gitOutput("config", "user.name")
+	for _, owner := range owners {
+		if (email != "" && strings.EqualFold(owner, email)) || (name != "" && owner == name) || (name != "" && owner == "@"+name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("channel %s is protected and only the owners of %s (%s) may tag it, not %s <%s>", channel, module, strings.Join(owners, ", "), name, email)
+}
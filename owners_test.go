@@ -0,0 +1,31 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestModuleOwnersFromCodeowners(t *testing.T) {
+	newTestRepo(t)
+	commitFile(t, ".github/CODEOWNERS", "# owners\n* @everyone\n/services/api/ @api-team\nservices/web/** @web-team\n")
+
+	rules := readCodeowners()
+	if len(rules) != 3 {
+		t.Fatalf("read %d rules, want 3", len(rules))
+	}
+	config := &Config{Modules: map[string]ModuleConfig{
+		"api":   {Paths: []string{"services/api/"}},
+		"web":   {Paths: []string{"/services/web"}},
+		"batch": {Paths: []string{"jobs/batch"}, Owners: []string{"batch@example.com"}},
+	}}
+	for module, want := range map[string][]string{
+		"api":   {"@api-team"},
+		"web":   {"@web-team"},
+		"batch": {"batch@example.com"},
+		"other": {"@everyone"},
+	} {
+		if got := moduleOwners(config, rules, module); !slices.Equal(got, want) {
+			t.Errorf("owners of %s = %v, want %v", module, got, want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	hashPattern = regexp.MustCompile(`^[0-9a-f]{7,64}$`)
+
+	promisorOnce   sync.Once
+	promisorRemote string
+)
+
+// Function to find the promisor remote of a partial clone, returning an
+// empty string for a regular clone
+func partialCloneRemote() string {
+	promisorOnce.Do(func() {
+		if remote, err := gitOutput("config", "--get", "extensions.partialClone"); err == nil && remote != "" {
+			promisorRemote = remote
+			return
+		}
+		out, err := gitOutput("config", "--get-regexp", `^remote\..*\.promisor$`)
+		if err != nil {
+			return
+		}
+		for _, line := range strings.Split(out, "\n") {
+			key, value, _ := strings.Cut(line, " ")
+			if value == "true" {
+				promisorRemote = strings.TrimSuffix(strings.TrimPrefix(key, "remote."), ".promisor")
+				return
+			}
+		}
+	})
+	return promisorRemote
+}
+
+// Function to fetch a missing object from the promisor remote of a partial
+// clone, so commands keep working when history was filtered at clone time
+func fetchMissingObject(object string) bool {
+	remote := partialCloneRemote()
+	if remote == "" || !hashPattern.MatchString(object) {
+		return false
+	}
+	log.Info().Str("object", object).Str("remote", remote).Msg("Fetching missing object from promisor remote")
+	if _, err := gitOutput("fetch", "--quiet", "--no-tags", "--filter=blob:none", remote, object); err != nil {
+		log.Warn().Err(err).Str("object", object).Msg("unable to fetch missing object")
+		return false
+	}
+	return true
+}
+
+// Function to split a remote-tracking ref such as origin/release-1.x into
+// the remote and the branch, matching the longest configured remote name
+func splitRemoteRef(ref string) (string, string) {
+	out, err := gitOutput("config", "--get-regexp", `^remote\..*\.url$`)
+	if err != nil {
+		return "", ""
+	}
+	var remote, branch string
+	for _, line := range strings.Split(out, "\n") {
+		key, _, _ := strings.Cut(line, " ")
+		name := strings.TrimSuffix(strings.TrimPrefix(key, "remote."), ".url")
+		if rest, ok := strings.CutPrefix(ref, name+"/"); ok && rest != "" && len(name) > len(remote) {
+			remote, branch = name, rest
+		}
+	}
+	return remote, branch
+}
+
+// Function to fetch the branch a remote-tracking ref such as
+// origin/release-1.x names when it was never fetched, so a branch can be
+// tagged without checking it out or fetching every branch first. Revision
+// suffixes such as ~2 are kept out of the branch name.
+func fetchRemoteBranch(rev string) bool {
+	ref := strings.TrimPrefix(rev, "refs/remotes/")
+	if i := strings.IndexAny(ref, "~^@:"); i >= 0 {
+		ref = ref[:i]
+	}
+	remote, branch := splitRemoteRef(ref)
+	if remote == "" {
+		return false
+	}
+	log.Info().Str("remote", remote).Str("branch", branch).Msg("Fetching remote branch")
+	refspec := "+refs/heads/" + branch + ":refs/remotes/" + remote + "/" + branch
+	if _, err := gitOutput("fetch", "--quiet", "--no-tags", remote, refspec); err != nil {
+		log.Warn().Err(err).Str("remote", remote).Str("branch", branch).Msg("unable to fetch remote branch")
+		return false
+	}
+	return true
+}
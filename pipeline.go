@@ -0,0 +1,410 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const pipelineStateFile = "pipelines.json"
+
+// pipelineRun is a release of a module going through a pipeline, saved
+// between invocations so it can be resumed
+type pipelineRun struct {
+	Pipeline string  `json:"pipeline"`
+	Version  Version `json:"version"`
+	Commit   string  `json:"commit"`
+	// Next is the index of the first stage not reached yet
+	Next    int       `json:"next"`
+	Started time.Time `json:"started"`
+	Updated time.Time `json:"updated"`
+	// Waiting describes the gate the run stopped at, if any
+	Waiting string `json:"waiting,omitempty"`
+}
+
+// Function to load the pipeline runs in progress, keyed by module
+func loadPipelineRuns() (map[string]pipelineRun, error) {
+	runs := make(map[string]pipelineRun)
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, pipelineStateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return runs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// Function to save the pipeline run of a module, or forget it when run is
+// nil. Other modules' runs are read again under the state lock, so runs of
+// different modules may go on at the same time.
+func savePipelineRun(module string, run *pipelineRun) error {
+	return withStateLock(func(dir string) error {
+		runs, err := loadPipelineRuns()
+		if err != nil {
+			return err
+		}
+		if run == nil {
+			delete(runs, module)
+		} else {
+			runs[module] = *run
+		}
+		data, err := json.MarshalIndent(runs, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeFile(filepath.Join(dir, pipelineStateFile), append(data, '\n'), 0o644)
+	})
+}
+
+// Function to pick the pipeline to run: the named one, or the only one
+// configured
+func selectPipeline(config *Config, name string) (string, Pipeline, error) {
+	if name == "" {
+		if len(config.Pipelines) != 1 {
+			var names []string
+			for n := range config.Pipelines {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return "", Pipeline{}, fmt.Errorf("choose a pipeline with -p among: %s", strings.Join(names, ", "))
+		}
+		for n := range config.Pipelines {
+			name = n
+		}
+	}
+	pipeline, ok := config.Pipelines[name]
+	if !ok {
+		return "", Pipeline{}, fmt.Errorf("no pipeline %q in configuration", name)
+	}
+	if len(pipeline.Stages) == 0 {
+		return "", Pipeline{}, fmt.Errorf("pipeline %q has no stages", name)
+	}
+	for _, stage := range pipeline.Stages {
+		if err := validateChannel(stage.Channel); err != nil {
+			return "", Pipeline{}, fmt.Errorf("pipeline %q: %w", name, err)
+		}
+	}
+	return name, pipeline, nil
+}
+
+// Function to run the command gate of a stage against the version about to
+// reach it
+func runGateCommand(stage PipelineStage, module string, run pipelineRun) error {
+	cmd := shellCommand(stage.Command)
+	cmd.Dir = repoDir
+	cmd.Env = append(os.Environ(),
+		"VERSION_MODULE="+module, "VERSION_CHANNEL="+stage.Channel,
+		"VERSION_VERSION="+run.Version.String(), "VERSION_COMMIT="+run.Commit)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("gate %q: %w", stage.Command, err)
+	}
+	return nil
+}
+
+// Function to tag the version of a run on the channel of a stage. A tag
+// already there on the same commit, left by an interrupted run, counts as
+// done.
+func tagStage(idx *tagIndex, module string, stage PipelineStage, run pipelineRun) (tagResult, bool, error) {
+	tag := formatTag(module, stage.Channel, run.Version)
+	result := tagResult{Module: module, Channel: stage.Channel, Old: idx.latest[module][stage.Channel], New: run.Version, Tag: tag, Commit: run.Commit}
+	if _, ok := idx.latest[module][stage.Channel]; ok {
+		result.Previous = formatTag(module, stage.Channel, result.Old)
+	}
+	if existing, err := resolveCommit("refs/tags/" + tag); err == nil {
+		if existing != run.Commit {
+			return result, false, fmt.Errorf("%s already exists on commit %s", tag, existing)
+		}
+		return result, false, nil
+	}
+	if err := createGitTag(tag, run.Commit); err != nil {
+		return result, false, err
+	}
+	return result, true, nil
+}
+
+// Function to handle `version pipeline`, running a module through a
+// configured promotion pipeline, showing the runs in progress or dropping
+// one
+func runPipeline(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: version pipeline run|status|abort [flags]")
+		return 2
+	}
+	switch args[0] {
+	case "run":
+		return runPipelineRun(args[1:])
+	case "status":
+		return runPipelineStatus(args[1:])
+	case "abort":
+		return runPipelineAbort(args[1:])
+	}
+	fmt.Fprintln(os.Stderr, "usage: version pipeline run|status|abort [flags]")
+	return 2
+}
+
+// Function to handle `version pipeline run`, starting a release of a module
+// on the first stage of a pipeline or resuming the one in progress, and
+// promoting it stage after stage until a gate stops it
+func runPipelineRun(args []string) int {
+	fs := flag.NewFlagSet("pipeline run", flag.ExitOnError)
+	fs.StringVar(&moduleName, "m", "", "module name")
+	name := fs.String("p", "", "pipeline to run (default the only one configured)")
+	fs.StringVar(&commitRef, "c", "HEAD", "commit to release when starting")
+	registerBumpFlags(fs)
+	approve := fs.Bool("approve", false, "approve the approval gate the run is waiting at")
+	fs.BoolVar(&nonInteractive, "yes", false, "never prompt: approval gates pause the run unless --approve is given")
+	fs.BoolVar(&pushCreated, "push", false, "push the created tags to the remote")
+	fs.StringVar(&pushRemote, "remote", "origin", "remote to push created tags to")
+	fs.StringVar(&mirrorRemote, "mirror", "", "secondary remote (name or URL) to mirror created tags to")
+	registerSummaryFlags(fs)
+	fs.Parse(args)
+	moduleName, _ = impliedNames(moduleName, "")
+
+	if moduleName == "" {
+		log.Error().Msg("-m is required")
+		return 2
+	}
+	if err := resolveBump(); err != nil {
+		log.Error().Err(err).Msg("invalid bump")
+		return 2
+	}
+	if err := validateSummaryFormat(); err != nil {
+		log.Error().Err(err).Msg("invalid summary format")
+		return 2
+	}
+	config, err := loadConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read configuration")
+		return 1
+	}
+	if err := checkArchived(config, moduleName); err != nil {
+		log.Error().Err(err).Msg("not allowed to tag")
+		return 1
+	}
+	idx, err := scanTagIndex()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	runs, err := loadPipelineRuns()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read pipeline state")
+		return 1
+	}
+
+	run, resuming := runs[moduleName]
+	if resuming {
+		startFlags := false
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "c", "bump", "major", "minor", "set", "prerelease", "scheme", "build-metadata":
+				startFlags = true
+			}
+		})
+		if startFlags || (*name != "" && *name != run.Pipeline) {
+			log.Error().Str("version", run.Version.String()).Str("pipeline", run.Pipeline).
+				Msg("a release of the module is in progress, run without -p, -c and bump flags to resume it or abort it first")
+			return 2
+		}
+		*name = run.Pipeline
+	}
+	pipelineName, pipeline, err := selectPipeline(config, *name)
+	if err != nil {
+		log.Error().Err(err).Msg("invalid pipeline")
+		return 1
+	}
+	if !resuming {
+		commit, err := resolveCommit(commitRef)
+		if err != nil {
+			log.Error().Err(err).Str("commit", commitRef).Msg("unable to resolve commit to tag")
+			return 1
+		}
+		if err := checkTemporaryCommit(config, commit); err != nil {
+			log.Error().Err(err).Msg("not allowed to tag")
+			return 1
+		}
+		first := pipeline.Stages[0].Channel
+		if err := resolveBumps(idx, config, []string{moduleName}, []string{first}, commit); err != nil {
+			log.Error().Err(err).Msg("unable to choose the bump")
+			return 1
+		}
+		if err := checkBump(idx, []string{moduleName}, []string{first}); err != nil {
+			log.Error().Err(err).Msg("invalid bump")
+			return 1
+		}
+		plan := planModule(idx, moduleName, []string{first})
+		plan[0].Commit = commit
+		if err := applyBuildMetadata(config, plan); err != nil {
+			log.Error().Err(err).Msg("invalid build metadata")
+			return 1
+		}
+		run = pipelineRun{Pipeline: pipelineName, Version: plan[0].New, Commit: commit, Started: time.Now().UTC()}
+		log.Info().Str("pipeline", pipelineName).Str("version", run.Version.String()).Str("commit", commit).Msg("Starting release")
+	} else {
+		log.Info().Str("pipeline", pipelineName).Str("version", run.Version.String()).Int("stage", run.Next+1).Msg("Resuming release")
+	}
+
+	save := func() bool {
+		run.Updated = time.Now().UTC()
+		saved := &run
+		if run.Next >= len(pipeline.Stages) {
+			saved = nil
+		}
+		if err := savePipelineRun(moduleName, saved); err != nil {
+			log.Error().Err(err).Msg("unable to save pipeline state")
+			return false
+		}
+		return true
+	}
+
+	var results []tagResult
+	defer func() { writeSummary(results) }()
+	for run.Next < len(pipeline.Stages) {
+		stage := pipeline.Stages[run.Next]
+		if err := checkOwnership(config, moduleName, stage.Channel); err != nil {
+			log.Error().Err(err).Msg("not allowed to tag")
+			save()
+			return 1
+		}
+		if err := checkMonotonic(config, moduleName, run.Version, run.Commit); err != nil {
+			log.Error().Err(err).Msg("version cannot be used")
+			save()
+			return 1
+		}
+		if stage.Command != "" {
+			log.Info().Str("channel", stage.Channel).Str("command", stage.Command).Msg("Running gate")
+			if err := runGateCommand(stage, moduleName, run); err != nil {
+				log.Error().Err(err).Str("channel", stage.Channel).Msg("gate failed, run again to retry")
+				run.Waiting = "command " + stage.Command
+				save()
+				return 1
+			}
+		}
+		if stage.Approval && !*approve {
+			question := fmt.Sprintf("Promote %s %s to %s", moduleName, run.Version, stage.Channel)
+			if nonInteractive || !confirm(question) {
+				run.Waiting = "approval for " + stage.Channel
+				if !save() {
+					return 1
+				}
+				log.Info().Str("channel", stage.Channel).Msg("Paused for approval, resume with 'version pipeline run -m " + moduleName + " --approve'")
+				return 0
+			}
+		}
+		// An approval given on the command line covers a single gate
+		*approve = false
+
+		result, created, err := tagStage(idx, moduleName, stage, run)
+		if err != nil {
+			log.Error().Err(err).Msg("Error creating git tag. Exiting.")
+			save()
+			return 1
+		}
+		if created {
+			log.Info().Str("tag", result.Tag).Msg("Stage reached")
+			if !publishResults([]tagResult{result}) {
+				save()
+				return 1
+			}
+			results = append(results, result)
+		}
+		run.Next++
+		run.Waiting = ""
+		if !save() {
+			return 1
+		}
+	}
+	log.Info().Str("version", run.Version.String()).Msg("Pipeline complete")
+	return 0
+}
+
+// Function to handle `version pipeline status`, listing the releases in
+// progress
+func runPipelineStatus(args []string) int {
+	fs := flag.NewFlagSet("pipeline status", flag.ExitOnError)
+	fs.Parse(args)
+
+	runs, err := loadPipelineRuns()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read pipeline state")
+		return 1
+	}
+	config, err := loadConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read configuration")
+		return 1
+	}
+	if len(runs) == 0 {
+		log.Info().Msg("No releases in progress")
+		return 0
+	}
+	var modules []string
+	for module := range runs {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	for _, module := range modules {
+		run := runs[module]
+		var stages []string
+		for i, stage := range config.Pipelines[run.Pipeline].Stages {
+			mark := " "
+			if i < run.Next {
+				mark = "x"
+			}
+			stages = append(stages, fmt.Sprintf("[%s] %s", mark, stage.Channel))
+		}
+		fmt.Printf("%s %s (%s, %s): %s\n", module, run.Version, run.Pipeline, shortHash(run.Commit), strings.Join(stages, " -> "))
+		if run.Waiting != "" {
+			fmt.Printf("  waiting for %s since %s\n", run.Waiting, run.Updated.Format(time.RFC3339))
+		}
+	}
+	return 0
+}
+
+// Function to handle `version pipeline abort`, forgetting the release of a
+// module in progress. Tags already created are kept.
+func runPipelineAbort(args []string) int {
+	fs := flag.NewFlagSet("pipeline abort", flag.ExitOnError)
+	fs.StringVar(&moduleName, "m", "", "module name")
+	fs.Parse(args)
+	moduleName, _ = impliedNames(moduleName, "")
+
+	if moduleName == "" {
+		log.Error().Msg("-m is required")
+		return 2
+	}
+	runs, err := loadPipelineRuns()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read pipeline state")
+		return 1
+	}
+	run, ok := runs[moduleName]
+	if !ok {
+		log.Error().Str("module", moduleName).Msg("no release in progress")
+		return 1
+	}
+	if err := savePipelineRun(moduleName, nil); err != nil {
+		log.Error().Err(err).Msg("unable to save pipeline state")
+		return 1
+	}
+	log.Info().Str("module", moduleName).Str("version", run.Version.String()).Msg("Release aborted, its tags are kept")
+	return 0
+}
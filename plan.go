@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const planFormatVersion = 1
+
+// planFile is the reviewable list of tags written by `version plan` and
+// executed by `version apply`
+type planFile struct {
+	Version int         `json:"version"`
+	Created time.Time   `json:"created"`
+	Tags    []tagResult `json:"tags"`
+}
+
+// Function to handle `version plan`, writing the tags a run would create to a
+// plan file instead of creating them
+func runPlan(args []string) int {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	registerTagFlags(fs)
+	output := fs.String("o", "-", "file to write the plan to, - for stdout")
+	fs.Parse(args)
+	moduleName, releaseChannel = impliedNames(moduleName, releaseChannel)
+
+	if moduleName == "" || releaseChannel == "" {
+		log.Error().Msg("both -m and -r are required")
+		return 2
+	}
+	if err := resolveBump(); err != nil {
+		log.Error().Err(err).Msg("invalid bump")
+		return 2
+	}
+
+	idx, err := scanTagIndex()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	config, err := loadConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read configuration")
+		return 1
+	}
+	targets, channels, err := resolveTargets(idx, resolveModuleArg(moduleName, idx.modules()), releaseChannel)
+	if err != nil {
+		log.Error().Err(err).Msg("invalid module or release channel entered")
+		return 1
+	}
+	if targets = dropArchived(config, moduleName, targets); len(targets) == 0 {
+		log.Error().Msg("every module is archived")
+		return 1
+	}
+	commit, err := resolveCommit(commitRef)
+	if err != nil {
+		log.Error().Err(err).Str("commit", commitRef).Msg("unable to resolve commit to tag")
+		return 1
+	}
+	if err := resolveBumps(idx, config, targets, channels, commit); err != nil {
+		log.Error().Err(err).Msg("unable to choose the bump")
+		return 1
+	}
+	// Ownership is checked when the plan is applied, by whoever applies it
+	if blockers := releaseBlockers(idx, config, targets, channels, commit, false); len(blockers) > 0 {
+		reportBlockers(blockers)
+		return 1
+	}
+	if pushCreated || checkRemote {
+		if err := resolveRemoteAhead(idx, pushRemote, targets, channels); err != nil {
+			log.Error().Err(err).Msg("not planning")
+			return 1
+		}
+	}
+
+	plan := planFile{Version: planFormatVersion, Created: time.Now().UTC()}
+	for _, m := range targets {
+		for _, result := range planModule(idx, m, channels) {
+			result.Commit = commit
+			plan.Tags = append(plan.Tags, result)
+		}
+	}
+	if err := assignMonotonic(config, plan.Tags, isExplicit); err != nil {
+		log.Error().Err(err).Msg("version cannot be used")
+		return 1
+	}
+	if err := applyBuildMetadata(config, plan.Tags); err != nil {
+		log.Error().Err(err).Msg("invalid build metadata")
+		return 1
+	}
+
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		log.Error().Err(err).Msg("unable to encode plan")
+		return 1
+	}
+	data = append(data, '\n')
+	if *output == "-" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := writeFile(*output, data, 0o644); err != nil {
+		log.Error().Err(err).Str("file", *output).Msg("unable to write plan")
+		return 1
+	}
+	log.Info().Int("tags", len(plan.Tags)).Str("file", *output).Msg("Plan written, review it and run 'version apply'")
+	return 0
+}
+
+// Function to handle `version apply <plan>`, creating exactly the tags listed
+// in a plan file after checking that the plan is still valid
+func runApply(args []string) int {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	fs.BoolVar(&pushCreated, "push", false, "push the created tags to the remote")
+	fs.StringVar(&pushRemote, "remote", "origin", "remote to push created tags to")
+	fs.StringVar(&mirrorRemote, "mirror", "", "secondary remote (name or URL) to mirror created tags to")
+	registerSummaryFlags(fs)
+	manifest := fs.String("f", "", "release manifest (YAML) whose tags are created all at once or not at all, instead of a plan file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: version apply [flags] <plan.json>")
+		fmt.Fprintln(fs.Output(), "       version apply [flags] -f <release.yaml>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if (*manifest == "") == (fs.NArg() != 1) {
+		fs.Usage()
+		return 2
+	}
+	if err := validateSummaryFormat(); err != nil {
+		log.Error().Err(err).Msg("invalid summary format")
+		return 2
+	}
+	if *manifest != "" {
+		return applyBatch(*manifest, true, false)
+	}
+
+	plan, err := readPlan(fs.Arg(0))
+	if err != nil {
+		log.Error().Err(err).Str("file", fs.Arg(0)).Msg("unable to read plan")
+		return 1
+	}
+	if err := checkPlan(plan.Tags); err != nil {
+		log.Error().Err(err).Msg("plan can no longer be applied, create a new one")
+		return 1
+	}
+
+	var results []tagResult
+	defer func() { writeSummary(results) }()
+	for _, result := range plan.Tags {
+		if err := createGitTag(result.Tag, result.Commit); err != nil {
+			log.Error().Err(err).Msg("Error creating git tag. Exiting.")
+			return 1
+		}
+		results = append(results, result)
+	}
+	if !publishResults(results) {
+		return 1
+	}
+	log.Info().Int("tags", len(results)).Msg("Plan applied")
+	return 0
+}
+
+// Function to read a plan file, - meaning stdin
+func readPlan(path string) (planFile, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return planFile{}, err
+	}
+	var plan planFile
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return planFile{}, err
+	}
+	if plan.Version != planFormatVersion {
+		return planFile{}, fmt.Errorf("unsupported plan version %d", plan.Version)
+	}
+	return plan, nil
+}
+
+// Function to check that every planned tag can still be created: its name
+// matches its fields, it does not exist, its commit is available and no
+// newer version has been tagged on its channel since the plan was made
+func checkPlan(tags []tagResult) error {
+	idx, err := scanTagIndex()
+	if err != nil {
+		return err
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	var problems []error
+	for _, t := range tags {
+		if err := checkArchived(config, t.Module); err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		if err := checkOwnership(config, t.Module, t.Channel); err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		if t.Tag != formatTag(t.Module, t.Channel, t.New) {
+			problems = append(problems, fmt.Errorf("%s does not match module %s, channel %s and version %s", t.Tag, t.Module, t.Channel, t.New))
+			continue
+		}
+		if existing, ok := idx.latest[t.Module][t.Channel]; ok && compareVersions(existing, t.New) >= 0 {
+			problems = append(problems, fmt.Errorf("%s/%s is already at %s", t.Module, t.Channel, existing))
+			continue
+		}
+		if _, err := gitOutput("rev-parse", "--verify", "--quiet", "refs/tags/"+t.Tag); err == nil {
+			problems = append(problems, fmt.Errorf("%s already exists", t.Tag))
+			continue
+		}
+		if commit, err := resolveCommit(t.Commit); err != nil || commit != t.Commit {
+			problems = append(problems, fmt.Errorf("commit %s of %s is not available", t.Commit, t.Tag))
+			continue
+		}
+		if err := checkMonotonic(config, t.Module, t.New, t.Commit); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", t.Tag, err))
+		}
+	}
+	return errors.Join(problems...)
+}
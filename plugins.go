@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// releaseEventVersion is bumped whenever a field of releaseEvent changes
+	// meaning or is removed
+	releaseEventVersion = 1
+	// defaultPluginsDir holds the plugins of a repository, relative to its root
+	defaultPluginsDir = ".version/plugins"
+	// pluginTimeout bounds how long a plugin may take for one event
+	pluginTimeout = 30 * time.Second
+)
+
+// releaseEvent is written as JSON to the stdin of every plugin, once for
+// each tag created
+type releaseEvent struct {
+	Version int    `json:"version"`
+	Event   string `json:"event"`
+	tagResult
+	// Notify are the notify targets of the release channel
+	Notify []string `json:"notify,omitempty"`
+	// Owners are the owners of the module
+	Owners []string `json:"owners,omitempty"`
+}
+
+// Function to list the plugins of the repository: the executable files of
+// its plugins directory, in name order
+func discoverPlugins(config *Config) ([]string, error) {
+	root, err := gitOutput("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	dir := config.PluginsDir
+	if dir == "" {
+		dir = defaultPluginsDir
+	}
+	dir = filepath.FromSlash(dir)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var plugins []string
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || !isExecutable(info) {
+			continue
+		}
+		plugins = append(plugins, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(plugins)
+	return plugins, nil
+}
+
+// Function to tell whether a plugin file can be run. Windows has no
+// executable bit, so there the extension must be one of PATHEXT.
+func isExecutable(info os.FileInfo) bool {
+	if runtime.GOOS != "windows" {
+		return info.Mode().Perm()&0o111 != 0
+	}
+	pathext := os.Getenv("PATHEXT")
+	if pathext == "" {
+		pathext = ".com;.exe;.bat;.cmd"
+	}
+	ext := filepath.Ext(info.Name())
+	for _, allowed := range filepath.SplitList(pathext) {
+		if ext != "" && strings.EqualFold(ext, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Function to run a plugin with an event on its stdin
+func runPlugin(plugin string, event releaseEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, plugin)
+	cmd.Dir = repoDir
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = os.Stderr
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Function to send a release event for every created tag to the plugins of
+// the repository. Tags already exist at this point, so failing plugins are
+// only reported.
+func notifyPlugins(results []tagResult) {
+	if len(results) == 0 {
+		return
+	}
+	config, err := loadConfig()
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to read configuration, plugins not run")
+		return
+	}
+	plugins, err := discoverPlugins(config)
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to list plugins")
+		return
+	}
+	if len(plugins) == 0 {
+		return
+	}
+	if useSandbox {
+		log.Info().Int("plugins", len(plugins)).Msg("Plugins are not run in the sandbox")
+		return
+	}
+	for _, result := range results {
+		event := releaseEvent{
+			Version:   releaseEventVersion,
+			Event:     "release",
+			tagResult: result,
+			Notify:    config.Channels[result.Channel].Notify,
+			Owners:    config.Modules[result.Module].Owners,
+		}
+		for _, plugin := range plugins {
+			if err := runPlugin(plugin, event); err != nil {
+				log.Warn().Err(err).Str("plugin", filepath.Base(plugin)).Str("tag", result.Tag).Msg("plugin failed")
+			}
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestDiscoverPlugins(t *testing.T) {
+	root, _ := newTestRepo(t)
+	dir := filepath.Join(root, "ci", "plugins")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	plugin := "notify"
+	if runtime.GOOS == "windows" {
+		plugin = "notify.cmd"
+	}
+	for name, mode := range map[string]os.FileMode{plugin: 0o755, "README.md": 0o644} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("exit 0\n"), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// plugins_dir is written with forward slashes whatever the platform
+	plugins, err := discoverPlugins(&Config{PluginsDir: "ci/plugins"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plugins) != 1 || filepath.Base(plugins[0]) != plugin {
+		t.Fatalf("plugins = %v, want only %s", plugins, plugin)
+	}
+	if _, err := os.Stat(plugins[0]); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// prereleaseName is the prerelease to tag, such as rc or beta, given with
+// --prerelease
+var prereleaseName string
+
+// prereleaseIdentifier matches one dot separated prerelease identifier
+var prereleaseIdentifier = regexp.MustCompile(`^[0-9A-Za-z-]+$`)
+
+// Function to check prerelease identifiers against the semver rules:
+// alphanumerics and hyphens, and numbers without leading zeros
+func validatePrerelease(prerelease string) error {
+	for _, identifier := range strings.Split(prerelease, ".") {
+		if !prereleaseIdentifier.MatchString(identifier) {
+			return fmt.Errorf("invalid prerelease %q, identifiers are alphanumerics and hyphens separated by dots", prerelease)
+		}
+		if isNumeric(identifier) && len(identifier) > 1 && identifier[0] == '0' {
+			return fmt.Errorf("invalid prerelease %q, numbers cannot have leading zeros", prerelease)
+		}
+	}
+	return nil
+}
+
+// Function to tell whether a prerelease identifier is a number
+func isNumeric(identifier string) bool {
+	for _, c := range identifier {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return identifier != ""
+}
+
+// Function to compare prereleases by semver precedence: no prerelease comes
+// after any prerelease, numeric identifiers compare as numbers and before
+// alphanumeric ones, and a longer list wins when all shared identifiers are
+// equal
+func comparePrereleases(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, y := as[i], bs[i]
+		if x == y {
+			continue
+		}
+		xNumeric, yNumeric := isNumeric(x), isNumeric(y)
+		switch {
+		case xNumeric && yNumeric:
+			// Compare by length first so numbers of any size compare right
+			if c := cmp.Compare(len(x), len(y)); c != 0 {
+				return c
+			}
+			return cmp.Compare(x, y)
+		case xNumeric:
+			return -1
+		case yNumeric:
+			return 1
+		}
+		return cmp.Compare(x, y)
+	}
+	return cmp.Compare(len(as), len(bs))
+}
+
+// Function to split a prerelease into its name and trailing counter, so
+// rc.2 gives rc and 2. Without a counter the number is 0.
+func splitPrerelease(prerelease string) (string, int) {
+	if i := strings.LastIndex(prerelease, "."); i >= 0 {
+		if n, err := strconv.Atoi(prerelease[i+1:]); err == nil && isNumeric(prerelease[i+1:]) {
+			return prerelease[:i], n
+		}
+	}
+	return prerelease, 0
+}
+
+// Function to compute the next prerelease called name. A prerelease of the
+// same name on the current version is continued, rc.1 becoming rc.2, when
+// the bump is the default patch one; otherwise the version is bumped and
+// the prerelease starts at 1.
+func bumpPrerelease(v Version, part, name, scheme string) (Version, error) {
+	if v.Prerelease != "" && part == "patch" {
+		current, n := splitPrerelease(v.Prerelease)
+		if current != name {
+			n = 0
+		}
+		next := v.Release()
+		next.Prerelease = fmt.Sprintf("%s.%d", name, n+1)
+		if compareVersions(next, v) <= 0 {
+			return v, fmt.Errorf("%s would not come after %s", next, v)
+		}
+		return next, nil
+	}
+	next, err := bumpVersion(v.Release(), part, scheme)
+	if err != nil {
+		return v, err
+	}
+	next.Prerelease = name + ".1"
+	return next, nil
+}
+
+// Function to check that the prerelease asked for can follow the current
+// version of every target, so a beta is not tagged after an rc
+func checkPrereleaseBump(idx *tagIndex, targets, channels []string) error {
+	if prereleaseName == "" {
+		return nil
+	}
+	for _, module := range targets {
+		for _, planned := range planModule(idx, module, channels) {
+			if _, err := bumpPrerelease(planned.Old, bumpPartOf(module), prereleaseName, schemeOf(module)); err != nil {
+				return fmt.Errorf("%s: %w", module, err)
+			}
+		}
+	}
+	return nil
+}
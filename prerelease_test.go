@@ -0,0 +1,96 @@
+package main
+
+import "testing"
+
+func TestVersionPrecedence(t *testing.T) {
+	// The example of the semver specification, lowest first
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			a, err := parseVersion(ordered[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := parseVersion(ordered[j])
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := 0
+			switch {
+			case i < j:
+				want = -1
+			case i > j:
+				want = 1
+			}
+			if got := compareVersions(a, b); got != want {
+				t.Errorf("compareVersions(%s, %s) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+}
+
+func TestComparePrereleases(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"alpha", "alpha", 0},
+		{"", "rc.1", 1},
+		{"rc.1", "", -1},
+		// Numeric identifiers compare as numbers, not as text
+		{"beta.2", "beta.11", -1},
+		{"1", "10", -1},
+		{"99999999999999999999", "100000000000000000000", -1},
+		// and come before alphanumeric ones
+		{"1", "alpha", -1},
+		{"alpha.1", "alpha.beta", -1},
+		{"alpha.beta", "alpha.1", 1},
+		// Alphanumeric identifiers compare in ASCII order
+		{"alpha", "beta", -1},
+		{"RC", "rc", -1},
+		{"alpha-1", "alpha1", -1},
+		// A longer list wins when the shared identifiers are equal
+		{"alpha", "alpha.1", -1},
+		{"alpha.1.1", "alpha.1", 1},
+	}
+	for _, tt := range tests {
+		if got := comparePrereleases(tt.a, tt.b); got != tt.want {
+			t.Errorf("comparePrereleases(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCompareVersionsIgnoresBuildMetadata(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0+build.1", "1.0.0+build.2", 0},
+		{"1.0.0+build.1", "1.0.0", 0},
+		{"1.0.0-rc.1+zzz", "1.0.0-rc.1+aaa", 0},
+		{"1.0.0-rc.1+zzz", "1.0.0+aaa", -1},
+		{"1.0.1+aaa", "1.0.0+zzz", 1},
+	}
+	for _, tt := range tests {
+		a, err := parseVersion(tt.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := parseVersion(tt.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := compareVersions(a, b); got != tt.want {
+			t.Errorf("compareVersions(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
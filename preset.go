@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Function to translate a preset into the flags of the tagging flow
+func (p Preset) args() []string {
+	args := []string{"-m", strings.Join(p.Modules, ","), "-r", p.Channel}
+	if p.Commit != "" {
+		args = append(args, "-c", p.Commit)
+	}
+	if p.Bump != "" {
+		args = append(args, "-bump", p.Bump)
+	}
+	for _, exclude := range p.Exclude {
+		args = append(args, "-exclude", exclude)
+	}
+	if p.Verify != "" {
+		args = append(args, "-verify", p.Verify)
+	}
+	if p.Mirror != "" {
+		args = append(args, "-mirror", p.Mirror)
+	}
+	if p.Push {
+		args = append(args, "-push")
+	}
+	return args
+}
+
+// Function to handle `version run <preset>`, tagging with the options saved
+// under that name in the repository configuration
+func runPreset(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: version run <preset> [tagging flags]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	config, err := loadConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read configuration")
+		return 1
+	}
+
+	if fs.NArg() == 0 {
+		var names []string
+		for name := range config.Presets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			p := config.Presets[name]
+			fmt.Printf("%s\t%s on %s\n", name, strings.Join(p.Modules, ","), p.Channel)
+		}
+		return 0
+	}
+
+	name := fs.Arg(0)
+	preset, ok := config.Presets[name]
+	if !ok {
+		log.Error().Str("preset", name).Msg("no such preset in configuration")
+		return 1
+	}
+	if len(preset.Modules) == 0 || preset.Channel == "" {
+		log.Error().Str("preset", name).Msg("preset needs modules and a channel")
+		return 1
+	}
+
+	tagFlags := flag.NewFlagSet("run "+name, flag.ExitOnError)
+	registerTagFlags(tagFlags)
+	// Flags given after the preset name override the saved ones
+	if err := tagFlags.Parse(append(preset.args(), fs.Args()[1:]...)); err != nil {
+		log.Error().Err(err).Msg("invalid preset")
+		return 1
+	}
+	log.Info().Str("preset", name).Strs("args", preset.args()).Msg("Running preset")
+	return run(tagFlags)
+}
+
+// Function to describe the options of the current run as a preset
+func presetFromFlags() Preset {
+	p := Preset{
+		Modules: strings.Split(moduleName, ","),
+		Channel: releaseChannel,
+		Exclude: excludes,
+		Verify:  verifyCommand,
+		Mirror:  mirrorRemote,
+		Push:    pushCreated,
+	}
+	if commitRef != "HEAD" {
+		p.Commit = commitRef
+	}
+	if bumpPart != "patch" {
+		p.Bump = bumpPart
+	}
+	return p
+}
+
+// Function to offer saving the choices of an interactive run as a preset
+func offerSavePreset() {
+	if !confirm("Save these choices as a preset") {
+		return
+	}
+	name := promptText("Preset name")
+	if err := validateName("preset", name); err != nil {
+		log.Error().Err(err).Msg("preset not saved")
+		return
+	}
+	if err := setConfigValue([]string{"presets", name}, presetFromFlags()); err != nil {
+		log.Error().Err(err).Msg("unable to save preset")
+		return
+	}
+	log.Info().Str("preset", name).Msgf("Preset saved, run it with 'version run %s'", name)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"runtime/pprof"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	cpuProfile string
+	memProfile string
+)
+
+// Function to start the requested pprof profiles; the returned function
+// stops CPU profiling and writes the heap profile
+func startProfiling() (func(), error) {
+	if cpuProfile != "" || memProfile != "" {
+		if err := checkWritable(); err != nil {
+			return nil, err
+		}
+	}
+	var cpuFile *os.File
+	if cpuProfile != "" {
+		f, err := os.Create(cpuProfile)
+		if err != nil {
+			return nil, err
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, err
+		}
+		cpuFile = f
+	}
+
+	return func() {
+		if cpuFile != nil {
+			pprof.StopCPUProfile()
+			cpuFile.Close()
+			log.Info().Str("file", cpuProfile).Msg("CPU profile written")
+		}
+		if memProfile != "" {
+			f, err := os.Create(memProfile)
+			if err != nil {
+				log.Error().Err(err).Str("file", memProfile).Msg("unable to create memory profile")
+				return
+			}
+			defer f.Close()
+			runtime.GC()
+			if err := pprof.WriteHeapProfile(f); err != nil {
+				log.Error().Err(err).Str("file", memProfile).Msg("unable to write memory profile")
+				return
+			}
+			log.Info().Str("file", memProfile).Msg("Memory profile written")
+		}
+	}, nil
+}
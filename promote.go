@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Function to handle `version promote`, tagging the commit behind a version
+// on one release channel with the same version on other channels
+func runPromote(args []string) int {
+	fs := flag.NewFlagSet("promote", flag.ExitOnError)
+	fs.StringVar(&moduleName, "m", "", "module name")
+	from := fs.String("from", "", "release channel to promote from")
+	to := fs.String("to", "", "release channel to promote to, or a comma separated list")
+	versionArg := fs.String("version", "", "version to promote (default the latest on the source channel)")
+	fs.BoolVar(&pushCreated, "push", false, "push the created tags to the remote")
+	fs.StringVar(&pushRemote, "remote", "origin", "remote to push created tags to")
+	fs.StringVar(&mirrorRemote, "mirror", "", "secondary remote (name or URL) to mirror created tags to")
+	registerSummaryFlags(fs)
+	fs.Parse(args)
+	moduleName, _ = impliedNames(moduleName, "")
+
+	if moduleName == "" || *from == "" || *to == "" {
+		log.Error().Msg("-m, --from and --to are required")
+		return 2
+	}
+	if err := validateSummaryFormat(); err != nil {
+		log.Error().Err(err).Msg("invalid summary format")
+		return 2
+	}
+
+	idx, err := scanTagIndex()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	if err := validateModule(moduleName); err != nil {
+		log.Error().Err(err).Msg("invalid module name entered")
+		return 1
+	}
+	config, err := loadConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read configuration")
+		return 1
+	}
+	if err := checkArchived(config, moduleName); err != nil {
+		log.Error().Err(err).Msg("not allowed to tag")
+		return 1
+	}
+
+	version, ok := idx.latest[moduleName][*from]
+	if *versionArg != "" {
+		version, err = parseVersion(*versionArg)
+		if err != nil {
+			log.Error().Err(err).Msg("invalid version entered")
+			return 1
+		}
+	} else if !ok {
+		log.Error().Str("module", moduleName).Str("channel", *from).Msg("no version tags found on the source channel")
+		return 1
+	}
+
+	source := formatTag(moduleName, *from, version)
+	commit, err := resolveCommit("refs/tags/" + source)
+	if err != nil {
+		log.Error().Err(err).Str("tag", source).Msg("unable to resolve source tag")
+		return 1
+	}
+
+	var results []tagResult
+	defer func() { writeSummary(results) }()
+	for _, channel := range strings.Split(*to, ",") {
+		if err := validateChannel(channel); err != nil {
+			log.Error().Err(err).Msg("invalid release channel entered")
+			return 1
+		}
+		if err := checkOwnership(config, moduleName, channel); err != nil {
+			log.Error().Err(err).Msg("not allowed to tag")
+			return 1
+		}
+		tag := formatTag(moduleName, channel, version)
+		if existing, err := resolveCommit("refs/tags/" + tag); err == nil {
+			if existing == commit {
+				log.Info().Str("tag", tag).Msg("Already promoted")
+				continue
+			}
+			log.Error().Str("tag", tag).Str("commit", existing).Msg("tag already exists on a different commit")
+			return 1
+		}
+		if err := checkMonotonic(config, moduleName, version, commit); err != nil {
+			log.Error().Err(err).Str("tag", tag).Msg("not allowed to promote")
+			return 1
+		}
+		old := idx.latest[moduleName][channel]
+		if compareVersions(old, version) > 0 {
+			log.Warn().Str("tag", tag).Str("current", old.String()).Msg("promoting a version older than the current one on the target channel")
+		}
+		if err := createGitTag(tag, commit); err != nil {
+			log.Error().Err(err).Msg("Error creating git tag. Exiting.")
+			return 1
+		}
+		log.Info().Str("from", source).Str("tag", tag).Str("commit", commit).Msg("Promoted")
+		result := tagResult{Module: moduleName, Channel: channel, Old: old, New: version, Tag: tag, Commit: commit}
+		if _, ok := idx.latest[moduleName][channel]; ok {
+			result.Previous = formatTag(moduleName, channel, old)
+		}
+		results = append(results, result)
+	}
+
+	if !publishResults(results) {
+		return 1
+	}
+	return 0
+}
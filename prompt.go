@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	plainPrompts bool
+	// nonInteractive answers every confirmation with yes; flows that would
+	// need any other answer fail instead of asking
+	nonInteractive bool
+)
+
+var (
+	// stdin is shared by every prompt so input buffered by one question is
+	// still available to the next one
+	stdin = bufio.NewScanner(os.Stdin)
+	// questionNumber counts the questions asked in plain prompt mode
+	questionNumber int
+)
+
+// Function to switch logging to plain uncolored lines without timestamps,
+// which reads better through screen readers and limited terminals. They go to
+// stderr so that stdout stays free for results.
+func setupPlainLogging() {
+	log.Logger = log.Output(zerolog.ConsoleWriter{
+		Out:          os.Stderr,
+		NoColor:      true,
+		PartsExclude: []string{zerolog.TimestampFieldName},
+	})
+}
+
+// Function to read one line of input
+func readLine() string {
+	stdin.Scan()
+	return strings.TrimSpace(stdin.Text())
+}
+
+// Function to print a numbered question in plain prompt mode
+func askPlain(question string) {
+	questionNumber++
+	fmt.Printf("Question %d: %s\n", questionNumber, question)
+}
+
+// shortcut is an extra answer offered above the choices of a picker
+type shortcut struct {
+	Key         string
+	Description string
+}
+
+// Function to ask for a name, offering the known names as choices and any
+// shortcuts above them. Labels, when given, describe the options for
+// display. In plain prompt mode the choices are numbered and the selection is
+// echoed back.
+func promptChoice(kind, field string, options, labels []string, shortcuts ...shortcut) string {
+	if labels == nil {
+		labels = options
+	}
+	if !plainPrompts {
+		if len(shortcuts) > 0 {
+			var entries []string
+			for _, s := range shortcuts {
+				entries = append(entries, s.Key+": "+s.Description)
+			}
+			log.Info().Strs("recent", entries).Msgf("Type %s to repeat a recent selection", shortcuts[0].Key)
+		}
+		log.Info().Strs(field, labels).Msgf("Enter %s name from list:", kind)
+		return readLine()
+	}
+
+	askPlain(fmt.Sprintf("Which %s?", kind))
+	if len(shortcuts) > 0 {
+		fmt.Println("Recent selections:")
+		for _, s := range shortcuts {
+			fmt.Printf("  %s. %s\n", s.Key, s.Description)
+		}
+		fmt.Printf("Choices:\n")
+	}
+	for i, label := range labels {
+		fmt.Printf("  %d. %s\n", i+1, label)
+	}
+	if len(shortcuts) > 0 {
+		fmt.Printf("Type a recent selection such as %s, a number from 1 to %d, or a new name: ", shortcuts[0].Key, len(options))
+	} else if len(options) > 0 {
+		fmt.Printf("Type a number from 1 to %d, or a new name: ", len(options))
+	} else {
+		fmt.Printf("No existing choices. Type a new name: ")
+	}
+	answer := readLine()
+	for _, s := range shortcuts {
+		if answer == s.Key {
+			fmt.Printf("You selected recent selection %s: %s\n", s.Key, s.Description)
+			return answer
+		}
+	}
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+		answer = options[n-1]
+	}
+	fmt.Printf("You selected %s: %s\n", kind, answer)
+	return answer
+}
+
+// Function to ask a yes/no question
+func confirm(question string) bool {
+	if nonInteractive {
+		log.Info().Msgf("%s? yes, running non-interactively", question)
+		return true
+	}
+	if !plainPrompts {
+		log.Info().Msgf("%s (yes/no)?", question)
+		return readLine() == "yes"
+	}
+
+	askPlain(question + "?")
+	fmt.Printf("Type yes or no: ")
+	answer := readLine()
+	fmt.Printf("You answered: %s\n", answer)
+	return answer == "yes"
+}
+
+// Function to ask an open question
+func promptText(question string) string {
+	if !plainPrompts {
+		log.Info().Msg(question + ":")
+		return readLine()
+	}
+
+	askPlain(question + "?")
+	fmt.Printf("Type your answer: ")
+	answer := readLine()
+	fmt.Printf("You answered: %s\n", answer)
+	return answer
+}
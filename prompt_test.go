@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Function to redirect a standard stream to a pipe until the returned
+// function is called, which gives back what was written
+func capture(t *testing.T, stream **os.File) func() string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := *stream
+	*stream = w
+	return func() string {
+		*stream = previous
+		w.Close()
+		data, _ := io.ReadAll(r)
+		r.Close()
+		return string(data)
+	}
+}
+
+func TestPlainLoggingWritesToStderr(t *testing.T) {
+	previous := log.Logger
+	t.Cleanup(func() { log.Logger = previous })
+
+	stdout := capture(t, &os.Stdout)
+	stderr := capture(t, &os.Stderr)
+	setupPlainLogging()
+	log.Info().Msg("Tag created")
+	errOut, out := stderr(), stdout()
+
+	if out != "" {
+		t.Errorf("stdout = %q, want nothing", out)
+	}
+	if !strings.Contains(errOut, "Tag created") {
+		t.Errorf("stderr = %q, want the log line", errOut)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// pruneBatchSize caps the number of tags deleted by a single git command so
+// thousands of tags neither hit argument limits nor need a push each
+const pruneBatchSize = 200
+
+// Function to parse an age such as 90d, 2w or any Go duration like 36h
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if number, ok := strings.CutSuffix(s, suffix); ok {
+			n, err := strconv.Atoi(number)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// Function to select the tags to prune from the tags of one module and
+// channel: all but the newest keep versions, and of those only the ones
+// created before cutoff when it is set. The highest version is always kept
+// so the next version is still computed from it.
+func selectPrunable(entries []tagEntry, keep int, cutoff time.Time) []tagEntry {
+	entries = slices.Clone(entries)
+	slices.SortFunc(entries, func(a, b tagEntry) int {
+		return compareVersions(b.Version, a.Version)
+	})
+	var prunable []tagEntry
+	for i, e := range entries {
+		if i < max(keep, 1) {
+			continue
+		}
+		if !cutoff.IsZero() && !e.Date.Before(cutoff) {
+			continue
+		}
+		prunable = append(prunable, e)
+	}
+	return prunable
+}
+
+// Function to split tags into groups of at most pruneBatchSize
+func batches(tags []string) [][]string {
+	var groups [][]string
+	for len(tags) > pruneBatchSize {
+		groups = append(groups, tags[:pruneBatchSize])
+		tags = tags[pruneBatchSize:]
+	}
+	if len(tags) > 0 {
+		groups = append(groups, tags)
+	}
+	return groups
+}
+
+// Function to handle `version prune`, deleting the old tags of a module on
+// release channels that accumulate many of them
+func runPrune(args []string) int {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	module := fs.String("m", "", "module name")
+	channelArg := fs.String("r", "", "release channel or comma separated list")
+	keep := fs.Int("keep", 0, "number of newest versions to keep on each channel (the newest is always kept)")
+	olderThan := fs.String("older-than", "", "only prune tags created longer ago than this, such as 90d, 2w or 36h")
+	remote := fs.Bool("remote", false, "also delete the tags from the remote")
+	remoteName := fs.String("remote-name", "origin", "remote to delete the tags from")
+	dryRun := fs.Bool("dry-run", false, "list the tags that would be deleted without deleting them")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	fs.Parse(args)
+	*module, *channelArg = impliedNames(*module, *channelArg)
+
+	if *module == "" || *channelArg == "" {
+		log.Error().Msg("-m and -r are required")
+		return 2
+	}
+	if *keep <= 0 && *olderThan == "" {
+		log.Error().Msg("at least one of --keep and --older-than is required")
+		return 2
+	}
+	var cutoff time.Time
+	if *olderThan != "" {
+		age, err := parseAge(*olderThan)
+		if err != nil {
+			log.Error().Err(err).Msg("invalid --older-than")
+			return 2
+		}
+		cutoff = time.Now().Add(-age)
+	}
+
+	channels := strings.Split(*channelArg, ",")
+	entries, err := readTagEntries(func(m, c string) bool {
+		return m == *module && slices.Contains(channels, c)
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	var tags []string
+	for _, channel := range channels {
+		var onChannel []tagEntry
+		for _, e := range entries {
+			if e.Channel == channel {
+				onChannel = append(onChannel, e)
+			}
+		}
+		for _, e := range selectPrunable(onChannel, *keep, cutoff) {
+			tags = append(tags, e.Tag)
+			fmt.Printf("%s\t%s\n", e.Tag, e.Date.Format(time.DateOnly))
+		}
+	}
+
+	if len(tags) == 0 {
+		log.Info().Msg("Nothing to prune")
+		return 0
+	}
+	where := "locally"
+	if *remote {
+		where = "locally and from " + *remoteName
+	}
+	if *dryRun {
+		log.Info().Int("tags", len(tags)).Msgf("Dry run, these tags would be deleted %s", where)
+		return 0
+	}
+	if !*yes && !confirm(fmt.Sprintf("Delete these %d tags %s", len(tags), where)) {
+		log.Info().Msg("Nothing deleted")
+		return 1
+	}
+
+	deleted := 0
+	for _, group := range batches(tags) {
+		if *remote {
+			refspecs := make([]string, len(group))
+			for i, tag := range group {
+				refspecs[i] = ":refs/tags/" + tag
+			}
+			if err := pushRefspec(pushTarget{Remote: *remoteName}, refspecs...); err != nil {
+				log.Error().Err(err).Str("remote", *remoteName).Msg("unable to delete remote tags, keeping them locally")
+				continue
+			}
+		}
+		if _, err := gitOutput(append([]string{"tag", "--delete"}, group...)...); err != nil {
+			log.Error().Err(err).Msg("unable to delete tags")
+			continue
+		}
+		deleted += len(group)
+	}
+	log.Info().Int("deleted", deleted).Int("tags", len(tags)).Msg("Tags pruned")
+	if deleted != len(tags) {
+		return 1
+	}
+	return 0
+}
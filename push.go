@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	mirrorRemote     string
+	mirrorSSHCommand string
+)
+
+// pushTarget describes a remote and the credentials used to push to it
+type pushTarget struct {
+	Remote     string
+	SSHCommand string
+	Token      string
+}
+
+// Function to describe the mirror remote, taking its credentials from flags
+// or the environment so they stay separate from the primary remote
+func mirrorTarget() pushTarget {
+	sshCommand := mirrorSSHCommand
+	if sshCommand == "" {
+		sshCommand = os.Getenv("VERSION_MIRROR_SSH_COMMAND")
+	}
+	return pushTarget{
+		Remote:     mirrorRemote,
+		SSHCommand: sshCommand,
+		Token:      os.Getenv("VERSION_MIRROR_TOKEN"),
+	}
+}
+
+// Function to push each tag with its own refspec, returning the error of
+// every tag that could not be pushed
+func pushTags(target pushTarget, tags []string) map[string]error {
+	failures := make(map[string]error)
+	for _, tag := range tags {
+		if err := pushRefspec(target, "refs/tags/"+tag+":refs/tags/"+tag); err != nil {
+			failures[tag] = err
+		}
+	}
+	return failures
+}
+
+// Function to push refspecs to a remote in a single push
+func pushRefspec(target pushTarget, refspecs ...string) error {
+	if useSandbox && target.Remote != "origin" {
+		return fmt.Errorf("the sandbox only pushes to its own origin, not %s", target.Remote)
+	}
+	cmd := pushCommand(target, refspecs...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git push %s %s: %w: %s", target.Remote, strings.Join(refspecs, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Function to prepare the git push of refspecs to a remote. The token is
+// passed through the environment rather than -c, so it never shows up in
+// the command line other users can read.
+func pushCommand(target pushTarget, refspecs ...string) *exec.Cmd {
+	args := append([]string{"push", "--quiet", target.Remote}, refspecs...)
+	cmd := gitCommand(args...)
+	cmd.Env = os.Environ()
+	if target.Token != "" {
+		cmd.Env = withGitConfig(cmd.Env, "http.extraHeader", "Authorization: Bearer "+target.Token)
+	}
+	if target.SSHCommand != "" {
+		cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND="+target.SSHCommand)
+	}
+	return cmd
+}
+
+// Function to add a configuration entry to the environment of a git command
+// through GIT_CONFIG_COUNT, after any entries the environment already has
+func withGitConfig(env []string, key, value string) []string {
+	count := 0
+	for i, entry := range env {
+		if n, ok := strings.CutPrefix(entry, "GIT_CONFIG_COUNT="); ok {
+			count, _ = strconv.Atoi(n)
+			env = slices.Delete(env, i, i+1)
+			break
+		}
+	}
+	return append(env,
+		fmt.Sprintf("GIT_CONFIG_COUNT=%d", count+1),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", count, key),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", count, value),
+	)
+}
+
+// Function to mirror tags to the secondary remote, logging the outcome of
+// every tag
+func mirrorTags(tags []string) bool {
+	if mirrorRemote == "" || len(tags) == 0 {
+		return true
+	}
+	failures := pushTags(mirrorTarget(), tags)
+	for _, tag := range tags {
+		if err, failed := failures[tag]; failed {
+			log.Error().Err(err).Str("remote", mirrorRemote).Str("tag", tag).Msg("Mirroring tag failed")
+		} else {
+			log.Info().Str("remote", mirrorRemote).Str("tag", tag).Msg("Tag mirrored")
+		}
+	}
+	return len(failures) == 0
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPushCommandKeepsTokenOutOfArguments(t *testing.T) {
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "core.askPass")
+	t.Setenv("GIT_CONFIG_VALUE_0", "true")
+
+	cmd := pushCommand(pushTarget{Remote: "mirror", Token: "s3cret"}, "refs/tags/app/prod/v1.0.0")
+	for _, arg := range cmd.Args {
+		if strings.Contains(arg, "s3cret") {
+			t.Fatalf("token passed as an argument: %q", cmd.Args)
+		}
+	}
+	for _, entry := range []string{
+		"GIT_CONFIG_COUNT=2",
+		"GIT_CONFIG_KEY_0=core.askPass",
+		"GIT_CONFIG_KEY_1=http.extraHeader",
+		"GIT_CONFIG_VALUE_1=Authorization: Bearer s3cret",
+	} {
+		if !slices.Contains(cmd.Env, entry) {
+			t.Errorf("environment is missing %s", entry)
+		}
+	}
+	if slices.Contains(cmd.Env, "GIT_CONFIG_COUNT=1") {
+		t.Error("environment still has the previous GIT_CONFIG_COUNT")
+	}
+}
+
+func TestPushCommandWithoutToken(t *testing.T) {
+	cmd := pushCommand(pushTarget{Remote: "origin"}, "refs/tags/app/prod/v1.0.0")
+	for _, entry := range cmd.Env {
+		if strings.HasPrefix(entry, "GIT_CONFIG_KEY_") && strings.HasSuffix(entry, "=http.extraHeader") {
+			t.Fatalf("extra header configured without a token: %s", entry)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// comparator is a single condition of a version constraint, such as >=1.2.0
+type comparator struct {
+	Op      string
+	Version Version
+}
+
+// Function to tell whether a version meets the comparator
+func (c comparator) matches(v Version) bool {
+	order := compareVersions(v, c.Version)
+	switch c.Op {
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	case ">=":
+		return order >= 0
+	case "!=":
+		return order != 0
+	}
+	return order == 0
+}
+
+// versionConstraint is a semver range: sets of comparators that must all
+// hold, separated by || when any of the sets may
+type versionConstraint [][]comparator
+
+// Function to tell whether a version satisfies the constraint. As with npm
+// ranges, a prerelease only satisfies a set of comparators when one of them
+// names a prerelease of the same major.minor.patch, so >=1.2.0 does not
+// pick 2.0.0-rc.1 up unless prereleases are included.
+func (c versionConstraint) matches(v Version, prereleases bool) bool {
+	if v.Counter {
+		return false
+	}
+	for _, set := range c {
+		ok := true
+		for _, term := range set {
+			ok = ok && term.matches(v)
+		}
+		if ok && v.Prerelease != "" && !prereleases {
+			ok = slices.ContainsFunc(set, func(term comparator) bool {
+				return term.Version.Prerelease != "" && compareVersions(term.Version.Release(), v.Release()) == 0
+			})
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Function to parse a partial version of a constraint, such as 1, 1.2,
+// 1.2.x or 1.2.3-rc.1, telling how many of its parts were given
+func parsePartialVersion(s string) (Version, int, error) {
+	s = strings.TrimPrefix(s, "v")
+	if s == "" || s == "*" || s == "x" || s == "X" {
+		return Version{}, 0, nil
+	}
+	core, _, _ := strings.Cut(s, "-")
+	parts := strings.Split(core, ".")
+	given := 0
+	for _, part := range parts {
+		if part == "x" || part == "X" || part == "*" {
+			break
+		}
+		given++
+	}
+	if given == len(parts) && given >= 3 {
+		v, err := parseVersion(s)
+		return v, given, err
+	}
+	if core != s || given > 3 || slices.ContainsFunc(parts[given:], func(p string) bool { return p != "x" && p != "X" && p != "*" }) {
+		return Version{}, 0, fmt.Errorf("invalid version %q in constraint", s)
+	}
+	var v Version
+	numbers := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i := 0; i < given; i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil || n < 0 {
+			return Version{}, 0, fmt.Errorf("invalid version %q in constraint", s)
+		}
+		*numbers[i] = n
+	}
+	return v, given, nil
+}
+
+// Function to compute the version right above everything a partial version
+// with the given number of parts covers, so 1.2 gives 1.3.0
+func nextPartial(v Version, given int) Version {
+	switch given {
+	case 1:
+		return Version{Major: v.Major + 1}
+	case 2:
+		return Version{Major: v.Major, Minor: v.Minor + 1}
+	}
+	return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+}
+
+// Function to turn one term of a constraint, such as ^1.2 or <=2, into the
+// comparators it stands for
+func parseComparator(term string) ([]comparator, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", "!=", ">", "<", "=", "^", "~"} {
+		if rest, ok := strings.CutPrefix(term, prefix); ok {
+			op, term = prefix, strings.TrimSpace(rest)
+			break
+		}
+	}
+	v, given, err := parsePartialVersion(term)
+	if err != nil {
+		return nil, err
+	}
+	if given == 0 {
+		if op == "<" || op == "!=" {
+			return nil, fmt.Errorf("%s* matches no version", op)
+		}
+		return nil, nil
+	}
+	upper := nextPartial(v, given)
+	switch op {
+	case "", "=":
+		if given >= 3 {
+			return []comparator{{"=", v}}, nil
+		}
+		return []comparator{{">=", v}, {"<", upper}}, nil
+	case "!=":
+		if given < 3 {
+			return nil, fmt.Errorf("!= needs a full version, not %s", term)
+		}
+		return []comparator{{"!=", v}}, nil
+	case ">":
+		if given >= 3 {
+			return []comparator{{">", v}}, nil
+		}
+		return []comparator{{">=", upper}}, nil
+	case "<=":
+		if given >= 3 {
+			return []comparator{{"<=", v}}, nil
+		}
+		return []comparator{{"<", upper}}, nil
+	case ">=", "<":
+		return []comparator{{op, v}}, nil
+	case "~":
+		// ~1.2.3 allows patch releases, ~1 minor releases
+		return []comparator{{">=", v}, {"<", nextPartial(v, min(given, 2))}}, nil
+	}
+	// ^ allows changes that keep the leftmost non-zero part
+	switch {
+	case v.Major > 0 || given == 1:
+		upper = nextPartial(v, 1)
+	case v.Minor > 0 || given == 2:
+		upper = nextPartial(v, 2)
+	default:
+		upper = nextPartial(v, 3)
+	}
+	return []comparator{{">=", v}, {"<", upper}}, nil
+}
+
+// Function to parse a semver constraint such as ">=1.2.0 <2.0.0", "^1.4",
+// "~2.1.3" or "1.x || >=3.0.0". Terms separated by spaces must all hold and
+// groups separated by || are alternatives; 1.2 - 1.4 stands for >=1.2 <=1.4.
+func parseConstraint(s string) (versionConstraint, error) {
+	var constraint versionConstraint
+	for _, group := range strings.Split(s, "||") {
+		fields := strings.Fields(group)
+		// Operators written apart from their version, as in ">= 1.2"
+		for i := 0; i < len(fields)-1; i++ {
+			if strings.Trim(fields[i], "<>=!^~") == "" {
+				fields[i] += fields[i+1]
+				fields = slices.Delete(fields, i+1, i+2)
+			}
+		}
+		var terms []string
+		for i := 0; i < len(fields); i++ {
+			if i+2 < len(fields) && fields[i+1] == "-" {
+				terms = append(terms, ">="+fields[i], "<="+fields[i+2])
+				i += 2
+				continue
+			}
+			terms = append(terms, fields[i])
+		}
+		set := []comparator{}
+		for _, term := range terms {
+			comparators, err := parseComparator(term)
+			if err != nil {
+				return nil, fmt.Errorf("invalid constraint %q: %w", s, err)
+			}
+			set = append(set, comparators...)
+		}
+		constraint = append(constraint, set)
+	}
+	return constraint, nil
+}
+
+// Function to handle `version query`, listing the tags of a module whose
+// versions satisfy a semver constraint, newest first
+func runQuery(args []string) int {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	fs.StringVar(&moduleName, "m", "", "module name")
+	fs.StringVar(&releaseChannel, "r", "", "release channel, or a comma separated list")
+	satisfying := fs.String("satisfying", "", "semver constraint the versions must satisfy, such as \">=1.2.0 <2.0.0\", \"^1.4\" or \"~2.1\"")
+	prereleases := fs.Bool("prereleases", false, "let prereleases satisfy the constraint even when it names none")
+	latest := fs.Bool("latest", false, "print only the newest matching tag")
+	versionsOnly := fs.Bool("versions", false, "print the versions instead of the full tag names")
+	fs.BoolVar(&includeYanked, "include-yanked", false, "also list yanked versions")
+	fs.Parse(args)
+	moduleName, releaseChannel = impliedNames(moduleName, releaseChannel)
+
+	if moduleName == "" || releaseChannel == "" || *satisfying == "" {
+		log.Error().Msg("-m, -r and --satisfying are required")
+		return 2
+	}
+	constraint, err := parseConstraint(*satisfying)
+	if err != nil {
+		log.Error().Err(err).Msg("invalid --satisfying")
+		return 2
+	}
+	channels := strings.Split(releaseChannel, ",")
+	entries, err := readTagEntries(func(m, c string) bool { return m == moduleName && slices.Contains(channels, c) })
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	yanked := map[string]yankRecord{}
+	if !includeYanked {
+		if yanked, err = loadYanked(); err != nil {
+			log.Error().Err(err).Msg("unable to read tags")
+			return 1
+		}
+	}
+	entries = slices.DeleteFunc(entries, func(e tagEntry) bool {
+		_, isYanked := yanked[e.Tag]
+		return isYanked || !constraint.matches(e.Version, *prereleases)
+	})
+	if len(entries) == 0 {
+		log.Error().Str("module", moduleName).Strs("channels", channels).Str("constraint", *satisfying).Msg("no version satisfies the constraint")
+		return 1
+	}
+	// Newest first, the same version on several channels in the order given
+	slices.SortStableFunc(entries, func(a, b tagEntry) int {
+		if c := compareVersions(b.Version, a.Version); c != 0 {
+			return c
+		}
+		return slices.Index(channels, a.Channel) - slices.Index(channels, b.Channel)
+	})
+	if *latest {
+		entries = entries[:1]
+	}
+	for _, e := range entries {
+		if *versionsOnly {
+			fmt.Println(e.Version)
+		} else {
+			fmt.Println(e.Tag)
+		}
+	}
+	return 0
+}
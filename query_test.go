@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestConstraintMatches(t *testing.T) {
+	tests := []struct {
+		constraint  string
+		prereleases bool
+		match       []string
+		miss        []string
+	}{
+		{"^1.4", false, []string{"1.4.0", "1.9.9"}, []string{"1.3.9", "2.0.0"}},
+		// A caret keeps the leftmost non-zero part
+		{"^0.2", false, []string{"0.2.0", "0.2.9"}, []string{"0.1.9", "0.3.0", "1.0.0"}},
+		{"^0.0.3", false, []string{"0.0.3"}, []string{"0.0.2", "0.0.4", "0.1.0"}},
+		{"~1", false, []string{"1.0.0", "1.9.0"}, []string{"0.9.9", "2.0.0"}},
+		{"~1.2.3", false, []string{"1.2.3", "1.2.9"}, []string{"1.2.2", "1.3.0"}},
+		// A hyphen range includes everything the upper bound covers
+		{"1.2 - 1.4", false, []string{"1.2.0", "1.4.9"}, []string{"1.1.9", "1.5.0"}},
+		{">= 1.2 < 2", false, []string{"1.2.0", "1.99.0"}, []string{"1.1.0", "2.0.0"}},
+		{"1.x || >=3.0.0", false, []string{"1.0.0", "1.9.9", "3.0.0", "4.1.0"}, []string{"0.9.0", "2.0.0"}},
+		{"!=1.2.3", false, []string{"1.2.2", "1.2.4"}, []string{"1.2.3"}},
+		{"*", false, []string{"0.0.1", "9.9.9"}, []string{"1.0.0-rc.1"}},
+		// Prereleases only match a comparator naming one of the same version
+		{">=1.0.0-rc.1", false, []string{"1.0.0-rc.1", "1.0.0-rc.2", "1.0.0", "1.2.0"}, []string{"1.0.0-beta.9", "1.0.1-rc.1", "2.0.0-alpha"}},
+		{">=1.2.0", false, []string{"1.2.0", "2.0.0"}, []string{"2.0.0-rc.1", "1.2.1-beta"}},
+		{">=1.2.0", true, []string{"1.2.0", "2.0.0-rc.1", "1.2.1-beta"}, []string{"1.2.0-rc.1"}},
+	}
+	for _, tt := range tests {
+		constraint, err := parseConstraint(tt.constraint)
+		if err != nil {
+			t.Fatalf("parseConstraint(%q): %v", tt.constraint, err)
+		}
+		for _, want := range []bool{true, false} {
+			versions := tt.match
+			if !want {
+				versions = tt.miss
+			}
+			for _, s := range versions {
+				v, err := parseVersion(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := constraint.matches(v, tt.prereleases); got != want {
+					t.Errorf("%q matches %s = %v, want %v", tt.constraint, s, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestConstraintSkipsCounters(t *testing.T) {
+	constraint, err := parseConstraint("*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if constraint.matches(Version{Patch: 42, Counter: true}, true) {
+		t.Fatal("a build counter satisfies a version constraint")
+	}
+}
+
+func TestParseConstraintRejectsMalformed(t *testing.T) {
+	for _, s := range []string{"abc", ">=1.a", "1.2.x.3", "1.2.3.4.5", "^1.2.3-", "!=1.2", "<*", ">=1.2 || ~x.1"} {
+		if _, err := parseConstraint(s); err == nil {
+			t.Errorf("parseConstraint(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestQuerySkipsYanked(t *testing.T) {
+	newTestRepo(t)
+	for _, tag := range []string{"api/prod/v1.2.0", "api/prod/v1.3.0", "api/prod/v1.4.0"} {
+		runGit(t, "tag", tag)
+	}
+	if _, err := createYankMarker("api/prod/v1.3.0", "broken build"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { includeYanked = false })
+
+	stdout := capture(t, &os.Stdout)
+	code := runQuery([]string{"-m", "api", "-r", "prod", "--satisfying", "^1.2"})
+	if out := stdout(); code != 0 || out != "api/prod/v1.4.0\napi/prod/v1.2.0\n" {
+		t.Fatalf("query exited with %d and printed:\n%s", code, out)
+	}
+
+	stdout = capture(t, &os.Stdout)
+	code = runQuery([]string{"-m", "api", "-r", "prod", "--satisfying", "^1.2", "--include-yanked"})
+	if out := stdout(); code != 0 || !strings.Contains(out, "api/prod/v1.3.0") {
+		t.Fatalf("query --include-yanked exited with %d and printed:\n%s", code, out)
+	}
+}
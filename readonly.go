@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// readOnly refuses every change to the repository, its remotes and files,
+// set with --read-only or VERSION_READ_ONLY=1
+var readOnly bool
+
+var errReadOnly = errors.New("refusing to make changes in read-only mode")
+
+// readGitCommands are the git commands that never change anything, the only
+// ones run in read-only mode
+var readGitCommands = map[string]bool{
+	"cat-file":     true,
+	"diff":         true,
+	"for-each-ref": true,
+	"log":          true,
+	"ls-remote":    true,
+	"ls-tree":      true,
+	"rev-list":     true,
+	"rev-parse":    true,
+	"verify-tag":   true,
+}
+
+// Function to fail when in read-only mode, before making a change
+func checkWritable() error {
+	if readOnly {
+		return errReadOnly
+	}
+	return nil
+}
+
+// Function to write a file, which read-only mode refuses. The data goes to
+// a temporary file renamed over the target, so a reader, or a run stopped
+// halfway, never sees a partly written file.
+func writeFile(name string, data []byte, perm os.FileMode) error {
+	if err := checkWritable(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// Function to tell whether VERSION_READ_ONLY asks for read-only mode. Any
+// value but 0 and false does, so a typo errs on the safe side.
+func readOnlyFromEnv() bool {
+	value := os.Getenv("VERSION_READ_ONLY")
+	return value != "" && value != "0" && !strings.EqualFold(value, "false")
+}
+
+// Function to tell whether git arguments only read from the repository,
+// skipping the global options before the command
+func gitReads(args []string) bool {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		if args[0] == "-c" || args[0] == "-C" {
+			args = args[1:]
+		}
+		if len(args) > 0 {
+			args = args[1:]
+		}
+	}
+	if len(args) == 0 {
+		return true
+	}
+	switch args[0] {
+	case "remote":
+		return len(args) > 1 && args[1] == "get-url"
+	case "config":
+		// Reading a key, as opposed to setting it
+		return len(args) == 2 || len(args) > 1 && strings.HasPrefix(args[1], "--get")
+	case "notes":
+		for _, arg := range args[1:] {
+			if !strings.HasPrefix(arg, "-") {
+				return arg == "show" || arg == "list"
+			}
+		}
+		return true
+	}
+	return readGitCommands[args[0]]
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const remindersFile = "reminders.json"
+
+// defaultRemindIn is when a reminder is due when --remind-in is not given
+const defaultRemindIn = "1d"
+
+var (
+	// remindText schedules a follow-up reminder about the created tags,
+	// such as "promote to prod if staging is healthy"
+	remindText string
+	// remindIn is how long after tagging the reminder is due
+	remindIn string
+)
+
+// reminder is a follow-up of a release, kept in the local state until it is
+// marked done
+type reminder struct {
+	ID      int       `json:"id"`
+	Created time.Time `json:"created"`
+	Due     time.Time `json:"due"`
+	Text    string    `json:"text"`
+	Tags    []string  `json:"tags,omitempty"`
+	Done    bool      `json:"done,omitempty"`
+}
+
+// Function to describe a reminder in a single line
+func (r reminder) describe() string {
+	if len(r.Tags) == 0 {
+		return r.Text
+	}
+	return strings.Join(r.Tags, ", ") + ": " + r.Text
+}
+
+// Function to load the reminders of the repository, oldest first
+func loadReminders() ([]reminder, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, remindersFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var reminders []reminder
+	if err := json.Unmarshal(data, &reminders); err != nil {
+		return nil, fmt.Errorf("%s: %w", remindersFile, err)
+	}
+	return reminders, nil
+}
+
+// Function to change the reminders of the repository, holding the state
+// lock between reading and writing them back
+func updateReminders(change func([]reminder) ([]reminder, error)) error {
+	return withStateLock(func(dir string) error {
+		reminders, err := loadReminders()
+		if err != nil {
+			return err
+		}
+		if reminders, err = change(reminders); err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(reminders, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeFile(filepath.Join(dir, remindersFile), data, 0o644)
+	})
+}
+
+// Function to check the reminder flags of a run before anything is tagged
+func validateReminder() error {
+	if remindIn != "" && remindText == "" {
+		return fmt.Errorf("--remind-in needs --remind")
+	}
+	if remindIn != "" {
+		if _, err := parseAge(remindIn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Function to schedule the reminder asked for with --remind about the tags
+// of a run
+func scheduleReminder(results []tagResult) {
+	if remindText == "" || len(results) == 0 {
+		return
+	}
+	in := remindIn
+	if in == "" {
+		in = defaultRemindIn
+	}
+	after, _ := parseAge(in)
+	r := reminder{Created: time.Now().UTC(), Text: remindText}
+	r.Due = r.Created.Add(after)
+	for _, result := range results {
+		r.Tags = append(r.Tags, result.Tag)
+	}
+	err := updateReminders(func(reminders []reminder) ([]reminder, error) {
+		for _, existing := range reminders {
+			r.ID = max(r.ID, existing.ID)
+		}
+		r.ID++
+		return append(reminders, r), nil
+	})
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to schedule reminder")
+		return
+	}
+	log.Info().Int("id", r.ID).Str("due", r.Due.Local().Format("2006-01-02 15:04")).Msg("Reminder scheduled: " + r.describe())
+}
+
+// Function to point out the reminders that are due, at the start of a run
+func showDueReminders() {
+	reminders, err := loadReminders()
+	if err != nil {
+		log.Debug().Err(err).Msg("unable to read reminders")
+		return
+	}
+	now := time.Now()
+	for _, r := range reminders {
+		if !r.Done && !r.Due.After(now) {
+			log.Warn().Int("id", r.ID).Str("due", r.Due.Local().Format("2006-01-02 15:04")).Msg("Reminder: " + r.describe() + " ('version reminders --done " + fmt.Sprint(r.ID) + "' when handled)")
+		}
+	}
+}
+
+// Function to write reminders as an iCalendar document, so they can be
+// imported in a calendar
+func writeICS(reminders []reminder) {
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	fmt.Print("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//version//reminders//EN\r\n")
+	for _, r := range reminders {
+		summary := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(r.describe())
+		fmt.Printf("BEGIN:VEVENT\r\nUID:version-reminder-%d-%d@version\r\nDTSTAMP:%s\r\nDTSTART:%s\r\nSUMMARY:%s\r\nEND:VEVENT\r\n",
+			r.ID, r.Created.Unix(), stamp, r.Due.UTC().Format("20060102T150405Z"), summary)
+	}
+	fmt.Print("END:VCALENDAR\r\n")
+}
+
+// Function to handle `version reminders`, listing the follow-ups scheduled
+// after releases and marking them done
+func runReminders(args []string) int {
+	fs := flag.NewFlagSet("reminders", flag.ExitOnError)
+	all := fs.Bool("all", false, "also list the reminders marked done")
+	done := fs.Int("done", 0, "mark the reminder with this id done")
+	ics := fs.Bool("ics", false, "print the listed reminders as an iCalendar document to import in a calendar")
+	fs.Parse(args)
+
+	if *done != 0 {
+		if err := checkWritable(); err != nil {
+			log.Error().Err(err).Msg("unable to update reminders")
+			return 1
+		}
+		err := updateReminders(func(reminders []reminder) ([]reminder, error) {
+			i := slices.IndexFunc(reminders, func(r reminder) bool { return r.ID == *done })
+			if i < 0 {
+				return nil, fmt.Errorf("no reminder with id %d", *done)
+			}
+			reminders[i].Done = true
+			return reminders, nil
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("unable to update reminders")
+			return 1
+		}
+		log.Info().Int("id", *done).Msg("Reminder done")
+		return 0
+	}
+
+	reminders, err := loadReminders()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read reminders")
+		return 1
+	}
+	if !*all {
+		reminders = slices.DeleteFunc(reminders, func(r reminder) bool { return r.Done })
+	}
+	slices.SortStableFunc(reminders, func(a, b reminder) int { return a.Due.Compare(b.Due) })
+	if *ics {
+		writeICS(reminders)
+		return 0
+	}
+	if len(reminders) == 0 {
+		log.Info().Msg("No reminders")
+		return 0
+	}
+	now := time.Now()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tDUE\tSTATE\tREMINDER")
+	for _, r := range reminders {
+		state := "pending"
+		switch {
+		case r.Done:
+			state = "done"
+		case !r.Due.After(now):
+			state = "due"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", r.ID, r.Due.Local().Format("2006-01-02 15:04"), state, r.describe())
+	}
+	tw.Flush()
+	return 0
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// checkRemote compares the versions about to be tagged with the remote
+// before tagging, which always happens when pushing
+var checkRemote bool
+
+// remoteAhead is a channel whose latest version on the remote is at least
+// the version about to be tagged
+type remoteAhead struct {
+	Module  string
+	Channel string
+	Version Version
+}
+
+// Function to read the highest version of a module on each channel of a
+// remote
+func remoteVersions(remote, module string, channels []string) (map[string]Version, error) {
+	patterns := make([]string, len(channels))
+	for i, channel := range channels {
+		patterns[i] = layout.glob(module, channel)
+	}
+	out, err := gitOutput(append([]string{"ls-remote", "--tags", "--refs", remote}, patterns...)...)
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[string]Version)
+	for _, line := range strings.Split(out, "\n") {
+		_, ref, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		m, channel, version, ok := parseTag(strings.TrimPrefix(ref, "refs/tags/"))
+		if !ok || m != module {
+			continue
+		}
+		if current, ok := latest[channel]; !ok || compareVersions(current, version) < 0 {
+			latest[channel] = version
+		}
+	}
+	return latest, nil
+}
+
+// Function to find the channels where the remote already has the version
+// about to be tagged on a module, or a newer one
+func findRemoteAhead(idx *tagIndex, remote, module string, channels []string) ([]remoteAhead, error) {
+	remoteLatest, err := remoteVersions(remote, module, channels)
+	if err != nil {
+		return nil, err
+	}
+	var ahead []remoteAhead
+	for _, planned := range planModule(idx, module, channels) {
+		if version, ok := remoteLatest[planned.Channel]; ok && compareVersions(version, planned.New) >= 0 {
+			ahead = append(ahead, remoteAhead{Module: module, Channel: planned.Channel, Version: version})
+		}
+	}
+	return ahead, nil
+}
+
+// Function to print the commit behind a tag of the remote
+func inspectRemoteTag(remote, tag string) {
+	if _, err := gitOutput("fetch", "--quiet", "--no-tags", remote, "refs/tags/"+tag); err != nil {
+		log.Error().Err(err).Str("tag", tag).Msg("unable to fetch remote tag")
+		return
+	}
+	out, err := gitOutput("log", "-1", "--format=commit %H%nAuthor: %an <%ae>%nDate:   %cd%n%n    %s", "FETCH_HEAD")
+	if err != nil {
+		log.Error().Err(err).Str("tag", tag).Msg("unable to read remote tag")
+		return
+	}
+	fmt.Printf("%s on %s\n%s\n\n", tag, remote, out)
+}
+
+// Function to fetch the tags of the remote for the channels where it is
+// ahead and record them in the index, so the next version follows them
+func adoptRemoteVersions(idx *tagIndex, remote string, ahead []remoteAhead) error {
+	for _, a := range ahead {
+		pattern := layout.glob(a.Module, a.Channel)
+		if _, err := gitOutput("fetch", "--quiet", "--no-tags", remote, pattern+":"+pattern); err != nil {
+			return err
+		}
+		idx.add(a.Module, a.Channel, a.Version)
+	}
+	return nil
+}
+
+// Function to tell the version a channel where the remote is ahead would get
+// once the remote's tags are adopted, planned on a copy of the module's
+// versions so each channel follows its own version source
+func followingVersion(idx *tagIndex, module string, channels []string, ahead []remoteAhead) Version {
+	adopted := newTagIndex()
+	for channel, version := range idx.latest[module] {
+		adopted.add(module, channel, version)
+	}
+	for _, a := range ahead {
+		adopted.add(a.Module, a.Channel, a.Version)
+	}
+	for _, p := range planModule(adopted, module, channels) {
+		if p.Channel == ahead[0].Channel {
+			return p.New
+		}
+	}
+	return nextVersion(module, ahead[0].Version)
+}
+
+// Function to check that the remote does not already have the versions about
+// to be tagged. When it does, an interactive run offers to continue after
+// the remote's version, to inspect the remote tag, or to abort; other runs
+// fail.
+func resolveRemoteAhead(idx *tagIndex, remote string, targets, channels []string) error {
+	for _, module := range targets {
+		ahead, err := findRemoteAhead(idx, remote, module, channels)
+		if err != nil {
+			log.Warn().Err(err).Str("remote", remote).Str("module", module).Msg("unable to compare with the remote, continuing")
+			continue
+		}
+		for len(ahead) > 0 {
+			var planned Version
+			for _, p := range planModule(idx, module, channels) {
+				if p.Channel == ahead[0].Channel {
+					planned = p.New
+				}
+			}
+			tag := formatTag(module, ahead[0].Channel, ahead[0].Version)
+			log.Warn().Str("remote", remote).Str("tag", tag).Str("planned", planned.String()).Msg("the remote is ahead of the local tags")
+			if nonInteractive || explicitVersion != nil {
+				return fmt.Errorf("%s already has %s, fetch its tags with 'git fetch --tags %s' and run again", remote, tag, remote)
+			}
+
+			following := followingVersion(idx, module, channels, ahead)
+			labels := []string{
+				fmt.Sprintf("next: fetch the remote tags and tag %s instead", following),
+				fmt.Sprintf("inspect: show %s from %s", tag, remote),
+				"abort: create no tags",
+			}
+			switch promptChoice("resolution", "resolutions", []string{"next", "inspect", "abort"}, labels) {
+			case "next":
+				if err := adoptRemoteVersions(idx, remote, ahead); err != nil {
+					return fmt.Errorf("unable to fetch the remote tags: %w", err)
+				}
+				if ahead, err = findRemoteAhead(idx, remote, module, channels); err != nil {
+					return err
+				}
+			case "inspect":
+				inspectRemoteTag(remote, tag)
+			default:
+				return fmt.Errorf("%s already has %s", remote, tag)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFollowingVersionPerChannel(t *testing.T) {
+	previous := moduleVersionSources
+	moduleVersionSources = map[string]string{"api": sourceChannel}
+	t.Cleanup(func() { moduleVersionSources = previous })
+
+	idx := newTagIndex()
+	idx.add("api", "prod", Version{Major: 1})
+	idx.add("api", "dev", Version{Major: 1})
+	ahead := []remoteAhead{
+		{Module: "api", Channel: "prod", Version: Version{Major: 1, Patch: 1}},
+		{Module: "api", Channel: "dev", Version: Version{Major: 2}},
+	}
+
+	// prod follows its own remote version, not the higher one of dev
+	got := followingVersion(idx, "api", []string{"prod", "dev"}, ahead)
+	if want := (Version{Major: 1, Patch: 2}); compareVersions(got, want) != 0 {
+		t.Fatalf("following prod = %s, want %s", got, want)
+	}
+	got = followingVersion(idx, "api", []string{"prod", "dev"}, ahead[1:])
+	if want := (Version{Major: 2, Patch: 1}); compareVersions(got, want) != 0 {
+		t.Fatalf("following dev = %s, want %s", got, want)
+	}
+}
+
+func TestResolveRemoteAheadContinuesAfterError(t *testing.T) {
+	newTestRepo(t)
+	logs := captureLog(t)
+
+	idx := newTagIndex()
+	if err := resolveRemoteAhead(idx, "missing", []string{"api", "web"}, []string{"prod"}); err != nil {
+		t.Fatal(err)
+	}
+	// Each module is compared in turn, rather than giving up after the first
+	for _, module := range []string{`"module":"api"`, `"module":"web"`} {
+		if !strings.Contains(logs.String(), module) {
+			t.Fatalf("no warning for %s:\n%s", module, logs)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Function to handle `version rename-module`, recreating every tag of a
+// module under a new name on the same commits
+func runRenameModule(args []string) int {
+	fs := flag.NewFlagSet("rename-module", flag.ExitOnError)
+	from := fs.String("from", "", "current module name")
+	to := fs.String("to", "", "new module name")
+	remove := fs.Bool("delete", false, "delete the original tags once recreated")
+	dryRun := fs.Bool("dry-run", false, "only print the tags that would be recreated")
+	fs.Parse(args)
+
+	if *from == "" || *to == "" {
+		log.Error().Msg("both --from and --to are required")
+		return 2
+	}
+	if err := validateModule(*to); err != nil {
+		log.Error().Err(err).Msg("invalid module name entered")
+		return 2
+	}
+
+	entries, err := readTagEntries(func(module, _ string) bool { return module == *from })
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	if len(entries) == 0 {
+		log.Error().Str("module", *from).Msg("module has no tags")
+		return 1
+	}
+
+	failed := false
+	var created []tagResult
+	for _, e := range entries {
+		tag := formatTag(*to, e.Channel, e.Version)
+		if existing, err := resolveCommit("refs/tags/" + tag); err == nil {
+			if existing != e.Commit {
+				log.Error().Str("tag", e.Tag).Str("existing", tag).Msg("tag already exists on a different commit")
+				failed = true
+				continue
+			}
+		} else if *dryRun {
+			log.Info().Str("from", e.Tag).Str("to", tag).Msg("Would recreate")
+			continue
+		} else if err := copyTag(e.Tag, tag, e.Commit, false); err != nil {
+			log.Error().Err(err).Str("tag", e.Tag).Msg("unable to recreate tag")
+			failed = true
+			continue
+		} else {
+			log.Info().Str("from", e.Tag).Str("to", tag).Msg("Recreated")
+			created = append(created, tagResult{Module: *to, Channel: e.Channel, Tag: tag, Commit: e.Commit})
+		}
+
+		if *remove && !*dryRun {
+			if _, err := gitOutput("tag", "--delete", e.Tag); err != nil {
+				log.Error().Err(err).Str("tag", e.Tag).Msg("unable to delete original tag")
+				failed = true
+			}
+		}
+	}
+
+	if *dryRun {
+		log.Info().Int("tags", len(entries)).Msg("Dry run finished, nothing was changed")
+		return 0
+	}
+	if len(created) > 0 {
+		recordSession(created)
+	}
+	if config, err := loadConfig(); err == nil {
+		if _, ok := config.Modules[*from]; ok {
+			log.Warn().Str("module", *from).Msgf("rename the module under modules in %s as well", configFileName)
+		}
+	}
+	log.Info().Int("tags", len(created)).Str("module", *to).Msg("Module renamed, 'version push' publishes the new tags")
+	if failed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// summaryFormatVersion is bumped whenever the JSON or YAML summary changes
+// in a way that can break scripts reading it
+const summaryFormatVersion = 1
+
+// Summary formats accepted by --format
+const (
+	formatTable    = "table"
+	formatJSON     = "json"
+	formatYAML     = "yaml"
+	formatMarkdown = "markdown"
+)
+
+var summaryFormat = formatTable
+
+// summaryDocument is the machine readable form of a summary
+type summaryDocument struct {
+	Version int           `json:"version" yaml:"version"`
+	Tags    []renderedTag `json:"tags" yaml:"tags"`
+}
+
+// renderedTag is a tag result together with its compare link
+type renderedTag struct {
+	tagResult `yaml:",inline"`
+	Compare   string `json:"compare,omitempty" yaml:"compare,omitempty"`
+}
+
+// Function to register the flags controlling the summary printed at the end
+// of a run
+func registerSummaryFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&noSummary, "no-summary", false, "do not print the summary at the end of the run")
+	fs.StringVar(&summaryFormat, "format", formatTable, "summary format: table, json, yaml or markdown")
+	fs.Var(&outputs, "output", "deliver the summary to a file, an http(s):// URL or an s3:// or gs:// object instead of stdout, - for stdout; repeatable")
+}
+
+// Function to tell whether a summary format is known
+func validFormat(format string) bool {
+	return slices.Contains([]string{formatTable, formatJSON, formatYAML, formatMarkdown}, format)
+}
+
+// Function to check the summary format and outputs given on the command line
+func validateSummaryFormat() error {
+	if !validFormat(summaryFormat) {
+		return fmt.Errorf("unknown format %q, expected table, json, yaml or markdown", summaryFormat)
+	}
+	for _, to := range outputs {
+		if _, err := newOutputSink(OutputConfig{To: to}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Function to render the tags handled during a run in the given format
+func renderSummary(w io.Writer, format string, results []tagResult) error {
+	switch format {
+	case formatTable:
+		printSummary(w, results)
+		return nil
+	case formatMarkdown:
+		return renderMarkdown(w, results)
+	}
+
+	doc := summaryDocument{Version: summaryFormatVersion, Tags: make([]renderedTag, len(results))}
+	for i, r := range results {
+		doc.Tags[i] = renderedTag{tagResult: r, Compare: compareURL(pushRemote, r.Previous, r.Tag)}
+	}
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(doc)
+	case formatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(doc); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// Function to render the tags handled during a run as a markdown table, for
+// pasting into release announcements
+func renderMarkdown(w io.Writer, results []tagResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("| Module | Channel | Old | New | Tag | Pushed |\n")
+	b.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+	for _, r := range results {
+		pushed := "no"
+		if r.Pushed {
+			pushed = "yes"
+		}
+		tag := "`" + r.Tag + "`"
+		if link := compareURL(pushRemote, r.Previous, r.Tag); link != "" {
+			tag = "[" + tag + "](" + link + ")"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n", r.Module, r.Channel, r.Old, r.New, tag, pushed)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Function to deliver the summary of a run to its outputs, stdout unless
+// --output names others or the summary was disabled
+func writeSummary(results []tagResult) {
+	for _, o := range summaryOutputs() {
+		if err := deliverSummary(o, results); err != nil {
+			log.Error().Err(err).Str("output", o.To).Msg("unable to deliver summary")
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files under testdata")
+
+// Function to compare output with a golden file under testdata, rewriting
+// the file instead when the tests run with -update
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	golden := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v, run go test -update to create it", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s, run go test -update if the change is intended\ngot:\n%s\nwant:\n%s", golden, got, want)
+	}
+}
+
+// Function to read the tag results of the summary fixture
+func loadSummaryFixture(t *testing.T) []tagResult {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "summary.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []tagResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func TestRenderSummaryGolden(t *testing.T) {
+	newTestRepo(t)
+	runGit(t, "remote", "add", "origin", "git@github.com:example/shop.git")
+	previous := pushRemote
+	pushRemote = "origin"
+	t.Cleanup(func() { pushRemote = previous })
+	results := loadSummaryFixture(t)
+
+	for _, format := range []string{formatTable, formatJSON, formatYAML, formatMarkdown} {
+		t.Run(format, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := renderSummary(&out, format, results); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, "summary."+format, out.Bytes())
+		})
+	}
+}
+
+func TestRenderSummaryEmpty(t *testing.T) {
+	for _, format := range []string{formatTable, formatMarkdown} {
+		var out bytes.Buffer
+		if err := renderSummary(&out, format, nil); err != nil {
+			t.Fatal(err)
+		}
+		if out.Len() != 0 {
+			t.Errorf("%s summary of no tags = %q, want nothing", format, out.String())
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Function to create an empty repository with a single commit, selected as
+// the repository every git command runs in until the test ends
+func newTestRepo(tb testing.TB) (string, string) {
+	tb.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		tb.Skip("git is not installed")
+	}
+	dir := tb.TempDir()
+	previous := repoDir
+	repoDir = dir
+	tb.Cleanup(func() { repoDir = previous })
+
+	runGit(tb, "init", "-q", "-b", "main")
+	runGit(tb, "config", "user.name", "test")
+	runGit(tb, "config", "user.email", "test@example.com")
+	return dir, commitFile(tb, "readme.md", "hello\n")
+}
+
+// Function to write a file in the test repository and commit it, returning
+// the hash of the commit
+func commitFile(tb testing.TB, name, content string) string {
+	tb.Helper()
+	path := filepath.Join(repoDir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		tb.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		tb.Fatal(err)
+	}
+	runGit(tb, "add", "--", name)
+	runGit(tb, "commit", "-q", "-m", "change "+name)
+	return runGit(tb, "rev-parse", "HEAD")
+}
+
+// Function to run git in the test repository with a fixed identity
+func runGit(tb testing.TB, args ...string) string {
+	tb.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoDir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_CONFIG_NOSYSTEM=1", "HOME="+repoDir,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		tb.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Function to add many tags at once by writing them to packed-refs, which is
+// far quicker than creating them one by one. Tags must be sorted.
+func packTags(tb testing.TB, commit string, tags []string) {
+	tb.Helper()
+	gitDir := runGit(tb, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	var packed strings.Builder
+	packed.WriteString("# pack-refs with: peeled fully-peeled sorted \n")
+	for _, tag := range tags {
+		packed.WriteString(commit + " refs/tags/" + tag + "\n")
+	}
+	if err := os.WriteFile(filepath.Join(gitDir, "packed-refs"), []byte(packed.String()), 0o644); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// Function to collect the log lines written until the test ends
+func captureLog(tb testing.TB) *bytes.Buffer {
+	tb.Helper()
+	var buf bytes.Buffer
+	previous := log.Logger
+	log.Logger = zerolog.New(&buf)
+	tb.Cleanup(func() { log.Logger = previous })
+	return &buf
+}
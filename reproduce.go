@@ -0,0 +1,275 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// reproduceNotesRef holds the outcome of rebuilding a release, as JSON
+// attached to its tag
+const reproduceNotesRef = "refs/notes/version-reproducibility"
+
+// Reproducibility statuses of a release
+const (
+	// statusReproducible means every rebuilt artifact matches the
+	// published one
+	statusReproducible = "reproducible"
+	// statusDiffers means some artifact differs or exists on one side only
+	statusDiffers = "differs"
+	// statusFailed means the rebuild itself failed
+	statusFailed = "failed"
+)
+
+// reproduceAfter rebuilds the created tags once they are published
+var reproduceAfter bool
+
+// reproduction is the outcome of rebuilding a release and comparing its
+// artifacts with the published ones
+type reproduction struct {
+	Tag       string          `json:"tag"`
+	Commit    string          `json:"commit"`
+	Status    string          `json:"status"`
+	Checked   time.Time       `json:"checked"`
+	Command   string          `json:"command"`
+	Artifacts []artifactCheck `json:"artifacts,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// artifactCheck compares the SHA-256 checksums of an artifact, empty on the
+// side missing it
+type artifactCheck struct {
+	Path      string `json:"path"`
+	Published string `json:"published"`
+	Rebuilt   string `json:"rebuilt"`
+}
+
+// Function to hash a file with SHA-256
+func fileChecksum(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Function to hash the files of a directory matching the artifact globs,
+// keyed by their path relative to the directory
+func artifactChecksums(dir string, globs []string) (map[string]string, error) {
+	sums := make(map[string]string)
+	for _, glob := range globs {
+		matches, err := filepath.Glob(filepath.Join(dir, glob))
+		if err != nil {
+			return nil, fmt.Errorf("invalid artifact glob %q: %w", glob, err)
+		}
+		for _, match := range matches {
+			if info, err := os.Stat(match); err != nil || info.IsDir() {
+				continue
+			}
+			rel, err := filepath.Rel(dir, match)
+			if err != nil {
+				return nil, err
+			}
+			if sums[filepath.ToSlash(rel)], err = fileChecksum(match); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sums, nil
+}
+
+// Function to rebuild a release in a clean worktree of its commit and
+// compare the artifacts with the published ones
+func reproduceRelease(rules ReproduceConfig, result tagResult) reproduction {
+	r := reproduction{Tag: result.Tag, Commit: result.Commit, Checked: time.Now().UTC(), Command: rules.Command}
+	root, err := gitOutput("rev-parse", "--show-toplevel")
+	if err != nil {
+		r.Status, r.Error = statusFailed, err.Error()
+		return r
+	}
+	published := rules.Published
+	if !filepath.IsAbs(published) {
+		published = filepath.Join(root, published)
+	}
+
+	var rebuilt map[string]string
+	err = withWorktree(result.Commit, func(dir string) error {
+		cmd := shellCommand(rules.Command)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"VERSION_MODULE="+result.Module, "VERSION_CHANNEL="+result.Channel,
+			"VERSION_VERSION="+result.New.String(), "VERSION_TAG="+result.Tag, "VERSION_COMMIT="+result.Commit)
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		log.Info().Str("command", rules.Command).Str("tag", result.Tag).Msg("Rebuilding release")
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("build command %q: %w", rules.Command, err)
+		}
+		var err error
+		rebuilt, err = artifactChecksums(dir, rules.Artifacts)
+		return err
+	})
+	if err != nil {
+		r.Status, r.Error = statusFailed, err.Error()
+		return r
+	}
+	original, err := artifactChecksums(published, rules.Artifacts)
+	if err != nil {
+		r.Status, r.Error = statusFailed, err.Error()
+		return r
+	}
+
+	paths := make(map[string]bool)
+	for path := range rebuilt {
+		paths[path] = true
+	}
+	for path := range original {
+		paths[path] = true
+	}
+	r.Status = statusReproducible
+	for path := range paths {
+		check := artifactCheck{Path: path, Published: original[path], Rebuilt: rebuilt[path]}
+		if check.Published != check.Rebuilt {
+			r.Status = statusDiffers
+		}
+		r.Artifacts = append(r.Artifacts, check)
+	}
+	sort.Slice(r.Artifacts, func(i, j int) bool { return r.Artifacts[i].Path < r.Artifacts[j].Path })
+	if len(r.Artifacts) == 0 {
+		r.Status, r.Error = statusFailed, "no artifact matches "+strings.Join(rules.Artifacts, ", ")
+	}
+	return r
+}
+
+// Function to attach the outcome of a rebuild to its tag
+func recordReproduction(r reproduction) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = gitOutputWithInput(string(data), "notes", "--ref="+reproduceNotesRef, "add", "--force", "--file=-", "refs/tags/"+r.Tag)
+	return err
+}
+
+// Function to read the outcome of the last rebuild of a tag, if any
+func loadReproduction(tag string) (reproduction, bool) {
+	out, err := gitOutput("notes", "--ref="+reproduceNotesRef, "show", "refs/tags/"+tag)
+	if err != nil {
+		return reproduction{}, false
+	}
+	var r reproduction
+	if err := json.Unmarshal([]byte(out), &r); err != nil {
+		return reproduction{}, false
+	}
+	return r, true
+}
+
+// Function to rebuild every release of a run, record the outcome on each tag
+// and push the records along with the tags, reporting whether all of them
+// were reproduced
+func reproduceResults(config *Config, results []tagResult, push bool, remote string) bool {
+	if config.Reproduce == nil || config.Reproduce.Command == "" {
+		log.Error().Msg("no reproduce command is configured")
+		return false
+	}
+	ok := true
+	for _, result := range results {
+		r := reproduceRelease(*config.Reproduce, result)
+		event := log.Info()
+		if r.Status != statusReproducible {
+			event, ok = log.Error(), false
+		}
+		for _, a := range r.Artifacts {
+			if a.Published != a.Rebuilt {
+				log.Warn().Str("tag", r.Tag).Str("artifact", a.Path).Str("published", a.Published).Str("rebuilt", a.Rebuilt).Msg("artifact differs")
+			}
+		}
+		if r.Error != "" {
+			event = event.Str("error", r.Error)
+		}
+		event.Str("tag", r.Tag).Str("status", r.Status).Int("artifacts", len(r.Artifacts)).Msg("Reproducibility checked")
+		if err := recordReproduction(r); err != nil {
+			log.Error().Err(err).Str("tag", r.Tag).Msg("unable to record reproducibility")
+			ok = false
+		}
+	}
+	if push {
+		if err := pushRefspec(pushTarget{Remote: remote}, reproduceNotesRef+":"+reproduceNotesRef); err != nil {
+			log.Error().Err(err).Str("remote", remote).Msg("unable to push reproducibility records")
+			ok = false
+		}
+	}
+	return ok
+}
+
+// Function to handle `version reproduce`, rebuilding the given tags, or
+// those of the last run, and comparing the artifacts with the published ones
+func runReproduce(args []string) int {
+	fs := flag.NewFlagSet("reproduce", flag.ExitOnError)
+	push := fs.Bool("push", false, "push the reproducibility records to the remote")
+	remote := fs.String("remote", "origin", "remote to push the records to")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: version reproduce [flags] [tag...]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := checkWritable(); err != nil {
+		log.Error().Err(err).Msg("unable to record reproducibility")
+		return 1
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read configuration")
+		return 1
+	}
+	tags := fs.Args()
+	if len(tags) == 0 {
+		s, err := loadSession()
+		if err != nil {
+			log.Error().Err(err).Msg("unable to read the last run")
+			return 1
+		}
+		for _, t := range s.Tags {
+			tags = append(tags, t.Tag)
+		}
+		if len(tags) == 0 {
+			log.Error().Msg("no tags given and the last run created none")
+			return 2
+		}
+	}
+
+	var results []tagResult
+	for _, tag := range tags {
+		module, channel, version, ok := parseTag(tag)
+		if !ok {
+			log.Error().Str("tag", tag).Msg("not a version tag")
+			return 1
+		}
+		commit, err := resolveCommit("refs/tags/" + tag)
+		if err != nil {
+			log.Error().Err(err).Str("tag", tag).Msg("no such tag")
+			return 1
+		}
+		results = append(results, tagResult{Module: module, Channel: channel, New: version, Tag: tag, Commit: commit})
+	}
+	if !reproduceResults(config, results, *push, *remote) {
+		return 1
+	}
+	return 0
+}
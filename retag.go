@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Function to report whether a tag exists on a remote. A repository without
+// that remote has nothing to conflict with.
+func remoteHasTag(remote, tag string) (bool, error) {
+	if _, err := gitOutput("remote", "get-url", remote); err != nil {
+		return false, nil
+	}
+	out, err := gitOutput("ls-remote", "--tags", remote, "refs/tags/"+tag)
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+// Function to create a tag on a commit from an existing tag, keeping the
+// message when the existing tag is annotated
+func copyTag(source, tag, commit string, force bool) error {
+	args := []string{"tag"}
+	if force {
+		args = append(args, "--force")
+	}
+	objectType, _ := gitOutput("cat-file", "-t", "refs/tags/"+source)
+	if objectType != "tag" {
+		_, err := gitOutput(append(args, tag, commit)...)
+		return err
+	}
+	message, err := gitOutput("for-each-ref", "--format=%(contents)", "refs/tags/"+source)
+	if err != nil {
+		return err
+	}
+	_, err = gitOutputWithInput(message, append(args, "--annotate", "--file=-", tag, commit)...)
+	return err
+}
+
+// Function to handle `version retag <tag> -c <commit>`, moving an existing
+// tag to another commit
+func runRetag(args []string) int {
+	fs := flag.NewFlagSet("retag", flag.ExitOnError)
+	commitArg := fs.String("c", "", "commit to move the tag to")
+	remote := fs.String("remote", "origin", "remote checked for an already published tag")
+	force := fs.Bool("force", false, "move the tag even if it was already pushed")
+	push := fs.Bool("push", false, "force-push the moved tag to the remote")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: version retag [flags] <tag> -c <commit>")
+		fs.PrintDefaults()
+	}
+	// Allow the tag before or after the flags
+	fs.Parse(args)
+	var tag string
+	if fs.NArg() > 0 {
+		tag = fs.Arg(0)
+		fs.Parse(fs.Args()[1:])
+	}
+	if tag == "" || *commitArg == "" {
+		fs.Usage()
+		return 2
+	}
+
+	current, err := resolveCommit("refs/tags/" + tag)
+	if err != nil {
+		log.Error().Str("tag", tag).Msg("no such tag")
+		return 1
+	}
+	commit, err := resolveCommit(*commitArg)
+	if err != nil {
+		log.Error().Err(err).Str("commit", *commitArg).Msg("unable to resolve commit")
+		return 1
+	}
+	if current == commit {
+		log.Info().Str("tag", tag).Str("commit", commit).Msg("Tag already points at this commit")
+		return 0
+	}
+
+	published, err := remoteHasTag(*remote, tag)
+	if err != nil && !*force {
+		log.Error().Err(err).Str("remote", *remote).Msg("unable to check the remote, use --force to move the tag anyway")
+		return 1
+	}
+	if published && !*force {
+		log.Error().Str("tag", tag).Str("remote", *remote).Msg("tag already exists on the remote, use --force to move it anyway")
+		return 1
+	}
+
+	// git tag --force replaces the ref in one step so the tag is never missing
+	if err := copyTag(tag, tag, commit, true); err != nil {
+		log.Error().Err(err).Str("tag", tag).Msg("unable to move tag")
+		return 1
+	}
+	log.Info().Str("tag", tag).Str("from", current).Str("to", commit).Msg("Tag moved")
+
+	if *push {
+		if err := pushRefspec(pushTarget{Remote: *remote}, "+refs/tags/"+tag+":refs/tags/"+tag); err != nil {
+			log.Error().Err(err).Str("tag", tag).Msg("unable to push moved tag")
+			return 1
+		}
+		log.Info().Str("tag", tag).Str("remote", *remote).Msg("Moved tag pushed")
+	} else if published {
+		log.Warn().Str("tag", tag).Str("remote", *remote).Msg("the remote still has the old tag, run again with --push to update it")
+	}
+	return 0
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/rs/zerolog/log"
+)
+
+// Function to report whether a commit is reachable from any local or
+// remote-tracking branch
+func isReachable(commit string) (bool, error) {
+	out, err := gitOutput("for-each-ref", "--contains", commit, "--count=1", "--format=%(refname)", "refs/heads", "refs/remotes")
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+// Function to warn when the commit behind the current version tag of a
+// module has been dropped from every branch, usually by a force-push, since
+// ranges computed from that tag no longer describe the branch history
+func checkHistoryRewrite(idx *tagIndex, moduleName string, channels []string) {
+	for _, channel := range channels {
+		version, ok := idx.latest[moduleName][channel]
+		if !ok {
+			continue
+		}
+		tag := formatTag(moduleName, channel, version)
+		commit, err := resolveCommit("refs/tags/" + tag)
+		if err != nil {
+			log.Warn().Err(err).Str("tag", tag).Msg("unable to resolve commit behind tag")
+			continue
+		}
+		reachable, err := isReachable(commit)
+		if err != nil {
+			log.Warn().Err(err).Str("tag", tag).Msg("unable to check whether tag is reachable")
+			continue
+		}
+		if !reachable {
+			log.Warn().Str("tag", tag).Str("commit", commit).
+				Msg("HISTORY REWRITE DETECTED: the commit behind this tag is not reachable from any branch; ranges computed from it will be misleading")
+		}
+	}
+}
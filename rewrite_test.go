@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveTargetsWarnsAboutRewrittenHistory(t *testing.T) {
+	_, first := newTestRepo(t)
+	runGit(t, "tag", "app/prod/v1.0.0", first)
+	// The second release is tagged on a commit then dropped from main, as
+	// by a force-push
+	second := commitFile(t, "app/main.go", "package app\n")
+	runGit(t, "tag", "app/prod/v1.1.0", second)
+	runGit(t, "reset", "-q", "--hard", first)
+	// A branch named like the tag must not be taken for it
+	runGit(t, "branch", "app/prod/v1.1.0", first)
+
+	idx, err := buildTagIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := captureLog(t)
+	if _, _, err := resolveTargets(idx, "app", "prod"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), "HISTORY REWRITE DETECTED") || !strings.Contains(logs.String(), second) {
+		t.Fatalf("no history rewrite warning for %s, logs:\n%s", second, logs)
+	}
+}
+
+func TestResolveTargetsReachableTag(t *testing.T) {
+	_, commit := newTestRepo(t)
+	runGit(t, "tag", "app/prod/v1.0.0", commit)
+
+	idx, err := buildTagIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := captureLog(t)
+	if _, _, err := resolveTargets(idx, "app", "prod"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(logs.String(), "HISTORY REWRITE DETECTED") {
+		t.Fatalf("history rewrite reported for a reachable tag, logs:\n%s", logs)
+	}
+}
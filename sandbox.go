@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+var useSandbox bool
+
+// sandbox is a throwaway clone of the repository, with a throwaway bare
+// repository standing in for its origin remote
+type sandbox struct {
+	dir        string
+	clone      string
+	remote     string
+	tags       map[string]string
+	remoteTags map[string]string
+}
+
+// Function to list the tags of a repository with the object each points at
+func readTagRefs(dir string) map[string]string {
+	previous := repoDir
+	repoDir = dir
+	defer func() { repoDir = previous }()
+
+	tags := make(map[string]string)
+	out, _ := gitOutput("for-each-ref", "--format=%(refname:strip=2) %(objectname)", "refs/tags")
+	for _, line := range strings.Split(out, "\n") {
+		if tag, object, ok := strings.Cut(line, " "); ok {
+			tags[tag] = object
+		}
+	}
+	return tags
+}
+
+// Function to clone the repository into a temporary directory, check out the
+// current commit there and point every later git command at the clone. Its
+// origin is a temporary bare repository so pushes never leave the sandbox.
+func startSandbox() (*sandbox, error) {
+	source, err := gitOutput("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	head, err := resolveCommit("HEAD")
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "version-sandbox-")
+	if err != nil {
+		return nil, err
+	}
+	s := &sandbox{dir: dir, clone: filepath.Join(dir, "repo"), remote: filepath.Join(dir, "origin.git")}
+
+	steps := [][]string{
+		{"clone", "--quiet", "--bare", source, s.remote},
+		{"clone", "--quiet", "--no-checkout", source, s.clone},
+		{"-C", s.clone, "remote", "set-url", "origin", s.remote},
+		{"-C", s.clone, "checkout", "--quiet", "--detach", head},
+	}
+	for _, step := range steps {
+		if _, err := gitOutput(step...); err != nil {
+			os.RemoveAll(dir)
+			return nil, err
+		}
+	}
+	// Carry over uncommitted configuration so the sandbox behaves the same
+	if data, err := os.ReadFile(filepath.Join(source, configFileName)); err == nil {
+		os.WriteFile(filepath.Join(s.clone, configFileName), data, 0o644)
+	}
+
+	repoDir = s.clone
+	s.tags, s.remoteTags = readTagRefs(s.clone), readTagRefs(s.remote)
+	log.Info().Str("sandbox", s.clone).Msg("Running in a sandbox, the repository and its remotes are left untouched")
+	return s, nil
+}
+
+// Function to describe how the tags of a repository changed
+func tagChanges(before, after map[string]string) []string {
+	var changes []string
+	for tag, object := range after {
+		if old, ok := before[tag]; !ok {
+			changes = append(changes, "created "+tag)
+		} else if old != object {
+			changes = append(changes, "moved "+tag)
+		}
+	}
+	for tag := range before {
+		if _, ok := after[tag]; !ok {
+			changes = append(changes, "deleted "+tag)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return strings.Fields(changes[i])[1] < strings.Fields(changes[j])[1]
+	})
+	return changes
+}
+
+// Function to report what happened in the sandbox and remove it
+func (s *sandbox) finish() {
+	defer os.RemoveAll(s.dir)
+	report := func(where string, changes []string) {
+		if len(changes) == 0 {
+			fmt.Fprintf(os.Stderr, "Sandbox: no tag changes %s\n", where)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Sandbox: tag changes %s\n", where)
+		for _, change := range changes {
+			fmt.Fprintf(os.Stderr, "  %s\n", change)
+		}
+	}
+	report("in the repository", tagChanges(s.tags, readTagRefs(s.clone)))
+	report("on the remote", tagChanges(s.remoteTags, readTagRefs(s.remote)))
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxSearchResults caps how many matching commits are offered to pick from
+const maxSearchResults = 20
+
+var searchQuery string
+
+// Function to search the history of the commit to tag for commits whose
+// message contains the query, such as a ticket ID, and ask which one to tag
+func searchCommit(query, rev string) (string, error) {
+	out, err := gitOutput("log", "--regexp-ignore-case", "--fixed-strings", "--grep="+query,
+		fmt.Sprintf("--max-count=%d", maxSearchResults), "--format=%H%x09%h %as %s", rev)
+	if err != nil {
+		return "", err
+	}
+	if out == "" {
+		return "", fmt.Errorf("no commit message in %s contains %q", rev, query)
+	}
+
+	var commits, labels []string
+	for _, line := range strings.Split(out, "\n") {
+		commit, label, _ := strings.Cut(line, "\t")
+		commits = append(commits, commit)
+		labels = append(labels, label)
+	}
+	if nonInteractive {
+		if len(commits) > 1 {
+			return "", fmt.Errorf("%d commits match %q, narrow the search when running non-interactively", len(commits), query)
+		}
+		return commits[0], nil
+	}
+	answer := promptChoice("commit", "commits", commits, labels)
+	if answer == "" {
+		return "", fmt.Errorf("no commit selected")
+	}
+	return answer, nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const sessionFile = "session.json"
+
+// session remembers the tags created by the last tagging run so they can be
+// pushed without touching any other tag in the repository
+type session struct {
+	Time time.Time    `json:"time"`
+	Tags []sessionTag `json:"tags"`
+}
+
+type sessionTag struct {
+	Tag    string `json:"tag"`
+	Pushed bool   `json:"pushed"`
+	// Aliases moved with the tag are force-pushed along with it
+	Aliases []string `json:"aliases,omitempty"`
+	// GoTag created with the tag is pushed along with it
+	GoTag string `json:"go_tag,omitempty"`
+}
+
+// Function to read the last session, returning an empty one if none exists
+func loadSession() (session, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return session{}, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, sessionFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return session{}, nil
+	}
+	if err != nil {
+		return session{}, err
+	}
+	var s session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return session{}, err
+	}
+	return s, nil
+}
+
+// Function to store the tags of a run as the current session
+func saveSession(s session) error {
+	dir, err := stateDir()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, sessionFile), data, 0o644)
+}
+
+// Function to record the results of a run as the current session
+func recordSession(results []tagResult) {
+	s := session{Time: time.Now().UTC()}
+	for _, r := range results {
+		s.Tags = append(s.Tags, sessionTag{Tag: r.Tag, Pushed: r.Pushed, Aliases: r.Aliases, GoTag: r.GoTag})
+	}
+	// Under the lock, so a push marking the tags of the previous session
+	// cannot write it back over this one
+	err := withStateLock(func(string) error {
+		return saveSession(s)
+	})
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to record session")
+	}
+}
+
+// Function to push tags to the primary remote one refspec at a time, logging
+// the outcome of each, and returning the set of tags that were pushed
+func pushToRemote(remote string, tags []string) map[string]bool {
+	failures := pushTags(pushTarget{Remote: remote}, tags)
+	pushed := make(map[string]bool)
+	for _, tag := range tags {
+		if err, failed := failures[tag]; failed {
+			log.Error().Err(err).Str("remote", remote).Str("tag", tag).Msg("Pushing tag failed")
+			continue
+		}
+		pushed[tag] = true
+		log.Info().Str("remote", remote).Str("tag", tag).Msg("Tag pushed")
+	}
+	return pushed
+}
+
+// Function to handle `version push`, pushing the tags created by the last
+// run that have not been pushed yet
+func runPush(args []string) int {
+	fs := flag.NewFlagSet("push", flag.ExitOnError)
+	remote := fs.String("remote", "origin", "remote to push the tags to")
+	fs.StringVar(&mirrorRemote, "mirror", "", "secondary remote (name or URL) to mirror pushed tags to")
+	fs.StringVar(&mirrorSSHCommand, "mirror-ssh-command", "", "ssh command used only for the mirror remote (default $VERSION_MIRROR_SSH_COMMAND)")
+	fs.Parse(args)
+
+	s, err := loadSession()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read session")
+		return 1
+	}
+	var pending []string
+	aliases := make(map[string][]string)
+	goTags := make(map[string]string)
+	for _, t := range s.Tags {
+		if !t.Pushed {
+			pending = append(pending, t.Tag)
+			aliases[t.Tag] = t.Aliases
+			goTags[t.Tag] = t.GoTag
+		}
+	}
+	if len(pending) == 0 {
+		log.Info().Msg("No unpushed tags from the last run")
+		return 0
+	}
+
+	pushed := pushToRemote(*remote, pending)
+	var moved, created []string
+	for _, tag := range pending {
+		if pushed[tag] {
+			moved = append(moved, aliases[tag]...)
+			if goTags[tag] != "" {
+				created = append(created, goTags[tag])
+			}
+		}
+	}
+	aliasesOK := pushAliases(pushTarget{Remote: *remote}, moved) && pushGoTags(pushTarget{Remote: *remote}, created)
+	// Another run may have recorded its own session while pushing, which
+	// then is left alone
+	err = withStateLock(func(string) error {
+		current, err := loadSession()
+		if err != nil || !current.Time.Equal(s.Time) {
+			return err
+		}
+		for i := range current.Tags {
+			if pushed[current.Tags[i].Tag] {
+				current.Tags[i].Pushed = true
+			}
+		}
+		return saveSession(current)
+	})
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to record session")
+	}
+
+	var mirrored []string
+	for _, tag := range pending {
+		if pushed[tag] {
+			mirrored = append(mirrored, tag)
+		}
+	}
+	mirrorOK := mirrorTags(mirrored)
+	if mirrorRemote != "" {
+		mirrorOK = pushAliases(mirrorTarget(), moved) && pushGoTags(mirrorTarget(), created) && mirrorOK
+	}
+
+	log.Info().Int("pushed", len(pushed)).Int("failed", len(pending)-len(pushed)).Msg("Push finished")
+	if len(pushed) != len(pending) || !mirrorOK || !aliasesOK {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordSessionWaitsForStateLock(t *testing.T) {
+	newTestRepo(t)
+
+	done := make(chan struct{})
+	err := withStateLock(func(string) error {
+		go func() {
+			recordSession([]tagResult{{Tag: "app/prod/v1.0.0"}})
+			close(done)
+		}()
+		select {
+		case <-done:
+			t.Error("session recorded while another run held the state lock")
+		case <-time.After(200 * time.Millisecond):
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	s, err := loadSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Tags) != 1 || s.Tags[0].Tag != "app/prod/v1.0.0" {
+		t.Fatalf("session tags = %+v, want app/prod/v1.0.0", s.Tags)
+	}
+}
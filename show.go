@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Function to describe the signature of an annotated tag
+func signatureStatus(tag string) string {
+	signature, _ := gitOutput("for-each-ref", "--format=%(contents:signature)", "refs/tags/"+tag)
+	if signature == "" {
+		return "unsigned"
+	}
+	if _, err := gitOutput("verify-tag", tag); err != nil {
+		return "signed, not verified"
+	}
+	return "signed, good signature"
+}
+
+// Function to handle `version show <tag>`, printing everything known about a
+// release
+func runShow(args []string) int {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	remote := fs.String("remote", "origin", "remote used for release and compare links")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: version show [flags] <tag>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	tag := fs.Arg(0)
+
+	format := strings.Join([]string{
+		"%(objecttype)", "%(objectname)", "%(*objectname)",
+		"%(taggername) %(taggeremail)", "%(committername) %(committeremail)", "%(creatordate:iso-strict)",
+	}, "%09")
+	out, err := gitOutput("for-each-ref", "--format="+format, "refs/tags/"+tag)
+	fields := strings.Split(out, "\t")
+	if err != nil || len(fields) != 6 {
+		log.Error().Str("tag", tag).Msg("no such tag")
+		return 1
+	}
+	annotated := fields[0] == "tag"
+	commit, tagger := fields[1], fields[4]
+	if annotated {
+		commit, tagger = fields[2], fields[3]
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Tag:\t%s\n", tag)
+	if module, channel, version, ok := parseTag(tag); ok {
+		fmt.Fprintf(tw, "Module:\t%s\n", module)
+		fmt.Fprintf(tw, "Channel:\t%s\n", channel)
+		fmt.Fprintf(tw, "Version:\t%s\n", version)
+		if versions, err := channelVersions(module, channel); err == nil {
+			var previous string
+			for _, v := range versions {
+				if compareVersions(v, version) < 0 {
+					previous = formatTag(module, channel, v)
+				}
+			}
+			if previous != "" {
+				fmt.Fprintf(tw, "Previous:\t%s\n", previous)
+				if link := compareURL(*remote, previous, tag); link != "" {
+					fmt.Fprintf(tw, "Compare:\t%s\n", link)
+				}
+			}
+		}
+	}
+	fmt.Fprintf(tw, "Commit:\t%s\n", commit)
+	if subject, err := gitOutput("log", "-1", "--format=%s", commit); err == nil {
+		fmt.Fprintf(tw, "Subject:\t%s\n", subject)
+	}
+	fmt.Fprintf(tw, "Tagger:\t%s\n", tagger)
+	fmt.Fprintf(tw, "Date:\t%s\n", fields[5])
+	if annotated {
+		fmt.Fprintf(tw, "Signature:\t%s\n", signatureStatus(tag))
+	} else {
+		fmt.Fprintf(tw, "Signature:\tnone, lightweight tag\n")
+	}
+	if module, _, version, ok := parseTag(tag); ok {
+		if built, ok := snapshotTime(version); ok {
+			fmt.Fprintf(tw, "Snapshot:\tof %s, built %s\n", version.Release(), built.Format(time.RFC3339))
+		}
+		if config, err := loadConfig(); err == nil {
+			if versions, window, ok := supportWindowOf(config, module, version); ok {
+				status, _ := supportStatus(window, time.Now(), 0)
+				fmt.Fprintf(tw, "Support:\t%s, end of life %s (%s)\n", status, window.EOL, versions)
+			}
+		}
+	}
+	if r, ok := loadReproduction(tag); ok {
+		fmt.Fprintf(tw, "Reproducible:\t%s, artifacts: %d, checked %s\n", r.Status, len(r.Artifacts), r.Checked.Format(time.RFC3339))
+	}
+	if link := releaseURL(*remote, tag); link != "" {
+		fmt.Fprintf(tw, "Release:\t%s\n", link)
+	}
+	tw.Flush()
+
+	if annotated {
+		if message, err := gitOutput("for-each-ref", "--format=%(contents:subject)%0a%0a%(contents:body)", "refs/tags/"+tag); err == nil && message != "" {
+			fmt.Printf("\nMessage:\n%s\n", indent(message))
+		}
+	}
+	if notes, err := gitOutput("notes", "show", commit); err == nil && notes != "" {
+		fmt.Printf("\nNotes:\n%s\n", indent(notes))
+	}
+	return 0
+}
+
+// Function to indent every line of a block of text
+func indent(text string) string {
+	return "    " + strings.ReplaceAll(text, "\n", "\n    ")
+}
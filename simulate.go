@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Function to handle `version simulate`, printing the tags the next releases
+// of a module would get without creating anything
+func runSimulate(args []string) int {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	fs.StringVar(&moduleName, "m", "", "module name")
+	fs.StringVar(&releaseChannel, "r", "", "release channel")
+	count := fs.Int("count", 5, "number of releases to simulate")
+	bump := fs.String("bump", "patch", "part of the version to bump: patch, minor or major, or build with the fourpart scheme")
+	scheme := fs.String("scheme", "", "version scheme to simulate instead of the configured one: semver, rollover, calver or fourpart")
+	fs.Parse(args)
+	moduleName, releaseChannel = impliedNames(moduleName, releaseChannel)
+
+	if moduleName == "" || releaseChannel == "" {
+		log.Error().Msg("both -m and -r are required")
+		return 2
+	}
+	if *count < 1 {
+		log.Error().Int("count", *count).Msg("count must be at least 1")
+		return 2
+	}
+
+	idx, err := scanTagIndex()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	if _, err := loadConfig(); err != nil {
+		log.Error().Err(err).Msg("unable to read configuration")
+		return 1
+	}
+	if *scheme == "" {
+		*scheme = schemeOf(moduleName)
+	} else if err := validateScheme(*scheme); err != nil {
+		log.Error().Err(err).Msg("invalid scheme")
+		return 2
+	}
+
+	version := parseCurrentVersion(idx, moduleName, []string{releaseChannel})
+	for i := 0; i < *count; i++ {
+		if version, err = bumpVersion(version, *bump, *scheme); err != nil {
+			log.Error().Err(err).Msg("unable to simulate")
+			return 2
+		}
+		fmt.Println(formatTag(moduleName, releaseChannel, version))
+	}
+	return 0
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// sinkTimeout bounds how long delivering a summary to a sink may take
+const sinkTimeout = 30 * time.Second
+
+// outputs are the sinks given with --output, receiving the summary instead
+// of stdout
+var outputs stringList
+
+// configuredOutputs are the sinks of the configuration, receiving the
+// summary of every run that tags, set when the configuration is loaded
+var configuredOutputs []OutputConfig
+
+// outputSink delivers the rendered summary of a run to where downstream
+// automation reads it
+type outputSink interface {
+	deliver(data []byte, format string) error
+}
+
+// stdoutSink prints the summary
+type stdoutSink struct{}
+
+func (stdoutSink) deliver(data []byte, _ string) error {
+	_, err := os.Stdout.Write(data)
+	return err
+}
+
+// fileSink writes the summary to a local file, replacing it
+type fileSink struct {
+	path string
+}
+
+func (s fileSink) deliver(data []byte, _ string) error {
+	return writeFile(s.path, data, 0o644)
+}
+
+// httpSink posts the summary to a URL, with headers whose ${VARIABLES} are
+// expanded from the environment so tokens stay out of the configuration
+type httpSink struct {
+	url     string
+	headers map[string]string
+}
+
+func (s httpSink) deliver(data []byte, format string) error {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", summaryContentType(format))
+	for name, value := range s.headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+	client := &http.Client{Timeout: sinkTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("POST %s: %s: %s", s.url, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// objectSink uploads the summary to an S3 or GCS object with the cloud's
+// command line tool, which brings its own credentials
+type objectSink struct {
+	command []string
+}
+
+func (s objectSink) deliver(data []byte, _ string) error {
+	cmd := exec.Command(s.command[0], s.command[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", strings.Join(s.command, " "), err)
+	}
+	return nil
+}
+
+// Function to create the sink an output names: - for stdout, an http(s)://
+// URL, an s3:// or gs:// object, or else a file path, optionally written as
+// file://path
+func newOutputSink(o OutputConfig) (outputSink, error) {
+	to := o.To
+	var sink outputSink
+	switch {
+	case to == "":
+		return nil, fmt.Errorf("output has no destination")
+	case to == "-" || to == "stdout":
+		sink = stdoutSink{}
+	case strings.HasPrefix(to, "http://") || strings.HasPrefix(to, "https://"):
+		sink = httpSink{url: to, headers: o.Headers}
+	case strings.HasPrefix(to, "s3://"):
+		sink = objectSink{command: []string{"aws", "s3", "cp", "-", to}}
+	case strings.HasPrefix(to, "gs://"):
+		sink = objectSink{command: []string{"gcloud", "storage", "cp", "-", to}}
+	case strings.HasPrefix(to, "file://"):
+		sink = fileSink{path: strings.TrimPrefix(to, "file://")}
+	case strings.Contains(to, "://"):
+		return nil, fmt.Errorf("output %q: unknown scheme, expected http, https, s3, gs or file", to)
+	default:
+		sink = fileSink{path: to}
+	}
+	if _, ok := sink.(httpSink); !ok && len(o.Headers) > 0 {
+		return nil, fmt.Errorf("output %q: headers are only sent to http outputs", to)
+	}
+	return sink, nil
+}
+
+// Function to check an output of the configuration
+func validateOutput(o OutputConfig) error {
+	if _, err := newOutputSink(o); err != nil {
+		return err
+	}
+	if o.Format != "" && !validFormat(o.Format) {
+		return fmt.Errorf("output %q: unknown format %q, expected table, json, yaml or markdown", o.To, o.Format)
+	}
+	return nil
+}
+
+// Function to tell the content type of a summary format
+func summaryContentType(format string) string {
+	switch format {
+	case formatJSON:
+		return "application/json"
+	case formatYAML:
+		return "application/yaml"
+	case formatMarkdown:
+		return "text/markdown"
+	}
+	return "text/plain"
+}
+
+// Function to list where the summary of a run goes: the --output sinks, or
+// stdout unless the summary is turned off, then the configured sinks unless
+// nothing was tagged for real. Outputs without a format take --format, or
+// JSON for configured ones.
+func summaryOutputs() []OutputConfig {
+	var sinks []OutputConfig
+	for _, to := range outputs {
+		sinks = append(sinks, OutputConfig{To: to, Format: summaryFormat})
+	}
+	if len(outputs) == 0 && !noSummary {
+		sinks = append(sinks, OutputConfig{To: "-", Format: summaryFormat})
+	}
+	if dryRun {
+		return sinks
+	}
+	for _, o := range configuredOutputs {
+		if o.Format == "" {
+			o.Format = formatJSON
+		}
+		sinks = append(sinks, o)
+	}
+	return sinks
+}
+
+// Function to render the summary of a run for an output and deliver it
+func deliverSummary(o OutputConfig, results []tagResult) error {
+	sink, err := newOutputSink(o)
+	if err != nil {
+		return err
+	}
+	if _, ok := sink.(stdoutSink); !ok {
+		if err := checkWritable(); err != nil {
+			return err
+		}
+	}
+	var data bytes.Buffer
+	if err := renderSummary(&data, o.Format, results); err != nil {
+		return err
+	}
+	if err := sink.deliver(data.Bytes(), o.Format); err != nil {
+		return err
+	}
+	if _, ok := sink.(stdoutSink); !ok {
+		log.Info().Str("output", o.To).Str("format", o.Format).Msg("Summary delivered")
+	}
+	return nil
+}
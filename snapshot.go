@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// channelSnapshot is the type of release channels tagging nightly builds as
+// prereleases of the next planned version stamped with the time they were
+// built, such as app/nightly/v1.5.0-20240610T0300Z. Later snapshots of the
+// same minute get a counter, as in app/nightly/v1.5.0-20240610T0300Z.2.
+const channelSnapshot = "snapshot"
+
+// snapshotLayout is the UTC timestamp ending the version of a snapshot
+const snapshotLayout = "20060102T1504Z"
+
+// snapshotChannels are the release channels of the snapshot type, set when
+// the configuration is loaded
+var snapshotChannels map[string]bool
+
+// Function to tell whether a release channel tags timestamped snapshots
+func isSnapshotChannel(channel string) bool {
+	return snapshotChannels[channel]
+}
+
+// Function to tell when a snapshot version was built, for versions whose
+// prerelease is a snapshot timestamp
+func snapshotTime(v Version) (time.Time, bool) {
+	if v.Counter || v.Prerelease == "" {
+		return time.Time{}, false
+	}
+	stamp, _ := splitPrerelease(v.Prerelease)
+	t, err := time.Parse(snapshotLayout, stamp)
+	return t, err == nil
+}
+
+// Function to compute the snapshot of a module on a channel built at a
+// moment: the next version planned from its releases on the other channels,
+// stamped with the time in UTC. The snapshot is a prerelease of that version,
+// so it sorts below its release and snapshots of it sort by time. A stamp
+// already taken on the channel gets the next counter, which sorts after it.
+func nextSnapshot(idx *tagIndex, module, channel, part string, now time.Time) Version {
+	var channels []string
+	for channel := range idx.latest[module] {
+		if !isSnapshotChannel(channel) && !isCounterChannel(channel) {
+			channels = append(channels, channel)
+		}
+	}
+	slices.Sort(channels)
+	planned, _ := bumpVersion(parseCurrentVersion(idx, module, channels), part, schemeOf(module))
+	stamp := now.UTC().Format(snapshotLayout)
+	planned.Prerelease = stamp
+	planned.Build = ""
+	if latest, ok := idx.latest[module][channel]; ok && compareVersions(latest.Release(), planned.Release()) == 0 {
+		if taken, n := splitPrerelease(latest.Prerelease); taken == stamp {
+			planned.Prerelease = fmt.Sprintf("%s.%d", stamp, max(n, 1)+1)
+		}
+	}
+	return planned
+}
+
+// Function to check that the bump selected on the command line applies to
+// the snapshot channels among the given ones
+func checkSnapshotBump(channels []string) error {
+	for _, channel := range channels {
+		if !isSnapshotChannel(channel) {
+			continue
+		}
+		switch {
+		case explicitVersion != nil:
+			return fmt.Errorf("%s is a snapshot channel, whose versions follow the next release and take no --set", channel)
+		case prereleaseName != "":
+			return fmt.Errorf("%s is a snapshot channel, whose prerelease is the build time", channel)
+		}
+	}
+	return nil
+}
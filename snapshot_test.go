@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextSnapshotCountsWithinMinute(t *testing.T) {
+	previous := snapshotChannels
+	snapshotChannels = map[string]bool{"nightly": true}
+	t.Cleanup(func() { snapshotChannels = previous })
+
+	now := time.Date(2024, time.June, 10, 3, 0, 42, 0, time.UTC)
+	idx := newTagIndex()
+	idx.add("app", "prod", Version{Major: 1, Minor: 4, Patch: 2})
+
+	want := []string{"1.4.3-20240610T0300Z", "1.4.3-20240610T0300Z.2", "1.4.3-20240610T0300Z.3"}
+	for _, w := range want {
+		next := nextSnapshot(idx, "app", "nightly", "patch", now)
+		if got := next.String(); got != w {
+			t.Fatalf("nextSnapshot = %s, want %s", got, w)
+		}
+		if built, ok := snapshotTime(next); !ok || !built.Equal(now.Truncate(time.Minute)) {
+			t.Fatalf("snapshotTime(%s) = %s (%v), want %s", next, built, ok, now.Truncate(time.Minute))
+		}
+		idx.add("app", "nightly", next)
+	}
+
+	// A new minute, or a new planned version, starts without a counter
+	if got := nextSnapshot(idx, "app", "nightly", "patch", now.Add(time.Minute)).String(); got != "1.4.3-20240610T0301Z" {
+		t.Fatalf("nextSnapshot a minute later = %s, want 1.4.3-20240610T0301Z", got)
+	}
+	if got := nextSnapshot(idx, "app", "nightly", "minor", now).String(); got != "1.5.0-20240610T0300Z" {
+		t.Fatalf("nextSnapshot of a minor = %s, want 1.5.0-20240610T0300Z", got)
+	}
+}
+
+func TestTagRunCreatesNoTagsWhenOneIsTaken(t *testing.T) {
+	newTestRepo(t)
+	runGit(t, "tag", "api/prod/v1.0.0")
+	runGit(t, "tag", "api/dev/v1.0.0")
+	runGit(t, "tag", "api/dev/v2.0.0")
+	t.Cleanup(func() { nonInteractive, explicitVersion = false, nil })
+
+	if code := runBump([]string{"-m", "api", "-r", "prod,dev", "--set", "2.0.0"}); code == 0 {
+		t.Fatal("bump succeeded with api/dev/v2.0.0 taken")
+	}
+	if _, err := resolveCommit("refs/tags/api/prod/v2.0.0"); err == nil {
+		t.Fatal("api/prod/v2.0.0 was created although api/dev/v2.0.0 is taken")
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// sortItem is a version or version tag given to `version sort`, with the
+// version it is ordered by
+type sortItem struct {
+	Text    string
+	Version Version
+}
+
+// Function to read the version of a line given to `version sort`, either a
+// version or a tag of the repository layout
+func parseSortItem(text string) (sortItem, error) {
+	if _, _, v, ok := parseTag(text); ok {
+		return sortItem{Text: text, Version: v}, nil
+	}
+	v, err := parseVersion(text)
+	return sortItem{Text: text, Version: v}, err
+}
+
+// Function to handle `version sort`, printing versions or tags given as
+// arguments or on stdin in semver precedence order, which sort -V gets
+// wrong for prereleases
+func runSort(args []string) int {
+	fs := flag.NewFlagSet("sort", flag.ExitOnError)
+	reverse := fs.Bool("reverse", false, "print the highest version first")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: version sort [--reverse] [version or tag...]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	lines := fs.Args()
+	if len(lines) == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Error().Err(err).Msg("unable to read stdin")
+			return 1
+		}
+	}
+	var items []sortItem
+	for _, line := range lines {
+		item, err := parseSortItem(line)
+		if err != nil {
+			log.Error().Err(err).Msg("invalid version")
+			return 2
+		}
+		items = append(items, item)
+	}
+	// Versions of equal precedence, such as ones differing by build
+	// metadata, keep the order they were given in
+	slices.SortStableFunc(items, func(a, b sortItem) int {
+		if *reverse {
+			return compareVersions(b.Version, a.Version)
+		}
+		return compareVersions(a.Version, b.Version)
+	})
+	for _, item := range items {
+		fmt.Println(item.Text)
+	}
+	return 0
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// stateLockFile is locked while a state file is read and written back, so
+// simultaneous runs on the same repository do not lose each other's changes
+const stateLockFile = "state.lock"
+
+// Function to run fn holding the lock of the state directory. Runs wait
+// for each other rather than fail, since updates only take a moment. In
+// read-only mode nothing is written, so nothing is locked.
+func withStateLock(fn func(dir string) error) error {
+	dir, err := stateDir()
+	if err != nil {
+		return err
+	}
+	if readOnly {
+		return fn(dir)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, stateLockFile), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := lockFile(f); err != nil {
+		return err
+	}
+	defer unlockFile(f)
+	return fn(dir)
+}
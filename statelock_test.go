@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestWithStateLockSerializesUpdates(t *testing.T) {
+	newTestRepo(t)
+
+	const runs = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, runs)
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- withStateLock(func(dir string) error {
+				file := filepath.Join(dir, "counter")
+				data, err := os.ReadFile(file)
+				if err != nil && !os.IsNotExist(err) {
+					return err
+				}
+				n, _ := strconv.Atoi(string(data))
+				return writeFile(file, []byte(strconv.Itoa(n+1)), 0o644)
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir, err := stateDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "counter"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != strconv.Itoa(runs) {
+		t.Fatalf("counter = %s after %d locked updates", data, runs)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Function to find the paths whose commits belong to a module: its
+// configured paths, else the directory named after it when the commit has
+// one. The commit is looked at rather than the working tree, which may
+// leave the directory out in a sparse checkout. No paths means the whole
+// repository.
+func modulePaths(config *Config, module, commit string) []string {
+	if paths := config.Modules[module].Paths; len(paths) > 0 {
+		return paths
+	}
+	if kind, err := gitOutput("cat-file", "-t", commit+":"+module); err == nil && kind == "tree" {
+		return []string{module}
+	}
+	return nil
+}
+
+// Function to count the commits touching paths that are reachable from
+// commit but not from tag
+func countUntagged(tag, commit string, paths []string) (int, error) {
+	args := []string{"rev-list", "--count", "--no-merges", "refs/tags/" + tag + ".." + commit}
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+	out, err := gitOutput(args...)
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	_, err = fmt.Sscan(strings.TrimSpace(out), &count)
+	return count, err
+}
+
+// Function to handle `version status`, printing for every module and release
+// channel how many commits touching the module landed since its latest tag
+func runStatus(args []string) int {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	modulePattern := fs.String("m", "*", "glob of modules to show")
+	fs.StringVar(&commitRef, "c", "HEAD", "commit to compare the tags with")
+	fs.Var(&excludes, "exclude", "glob of modules or release channels to skip, may be repeated")
+	fs.Parse(args)
+
+	idx, err := scanTagIndex()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	if len(idx.latest) == 0 {
+		log.Info().Msg("No version tags found")
+		return 0
+	}
+	config, err := loadConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read configuration")
+		return 1
+	}
+	commit, err := resolveCommit(commitRef)
+	if err != nil {
+		log.Error().Err(err).Str("commit", commitRef).Msg("unable to resolve commit")
+		return 1
+	}
+	modules, err := expandModules(*modulePattern, idx)
+	if err != nil {
+		log.Error().Err(err).Msg("invalid module pattern entered")
+		return 1
+	}
+	modules = applyExcludes(activeModules(config, modules))
+
+	seen := make(map[string]bool)
+	var channels []string
+	for _, module := range modules {
+		for channel := range idx.latest[module] {
+			if !seen[channel] && !isExcluded(channel) {
+				seen[channel] = true
+				channels = append(channels, channel)
+			}
+		}
+	}
+	sort.Strings(channels)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, "MODULE")
+	for _, channel := range channels {
+		fmt.Fprintf(tw, "\t%s", channel)
+	}
+	fmt.Fprintln(tw)
+	for _, module := range modules {
+		paths := modulePaths(config, module, commit)
+		fmt.Fprint(tw, module)
+		for _, channel := range channels {
+			version, ok := idx.latest[module][channel]
+			if !ok {
+				fmt.Fprint(tw, "\t-")
+				continue
+			}
+			count, err := countUntagged(formatTag(module, channel, version), commit, paths)
+			if err != nil {
+				log.Warn().Err(err).Str("module", module).Str("channel", channel).Msg("unable to count commits")
+				fmt.Fprint(tw, "\t?")
+				continue
+			}
+			fmt.Fprintf(tw, "\t%d", count)
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+	return 0
+}
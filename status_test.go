@@ -0,0 +1,42 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestModulePathsInSparseCheckout(t *testing.T) {
+	newTestRepo(t)
+	commitFile(t, "api/main.go", "package main\n")
+	commit := commitFile(t, "web/index.html", "<html></html>\n")
+	runGit(t, "sparse-checkout", "set", "web")
+
+	config := &Config{Modules: map[string]ModuleConfig{"batch": {Paths: []string{"jobs/batch"}}}}
+	for module, want := range map[string][]string{
+		"api":   {"api"},
+		"web":   {"web"},
+		"batch": {"jobs/batch"},
+		"docs":  nil,
+	} {
+		if got := modulePaths(config, module, commit); !slices.Equal(got, want) {
+			t.Errorf("paths of %s = %v, want %v", module, got, want)
+		}
+	}
+}
+
+func TestCountUntaggedScopedToModule(t *testing.T) {
+	_, first := newTestRepo(t)
+	runGit(t, "tag", "api/prod/v1.0.0", first)
+	commitFile(t, "api/main.go", "package main\n")
+	commitFile(t, "web/index.html", "<html></html>\n")
+	commit := commitFile(t, "web/style.css", "body {}\n")
+	runGit(t, "sparse-checkout", "set", "web")
+
+	count, err := countUntagged("api/prod/v1.0.0", commit, modulePaths(&Config{}, "api", commit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("untagged commits of api = %d, want 1", count)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+var noSummary bool
+
+// tagResult describes a single tag handled during a run
+type tagResult struct {
+	Module  string  `json:"module" yaml:"module"`
+	Channel string  `json:"channel" yaml:"channel"`
+	Old     Version `json:"old" yaml:"old"`
+	New     Version `json:"new" yaml:"new"`
+	Tag     string  `json:"tag" yaml:"tag"`
+	// Previous is the tag this one follows on the same channel, if any
+	Previous string `json:"previous,omitempty" yaml:"previous,omitempty"`
+	// VersionSource is where the version came from, when several channels
+	// were tagged together or it followed another channel
+	VersionSource string `json:"version_source,omitempty" yaml:"version_source,omitempty"`
+	Commit        string `json:"commit" yaml:"commit"`
+	Pushed        bool   `json:"pushed,omitempty" yaml:"pushed,omitempty"`
+	// Aliases are the floating alias tags moved to the commit, such as
+	// app/prod/v1 and app/prod/v1.4
+	Aliases []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	// GoTag is the tag Go tooling finds the version by, such as
+	// services/api/v1.4.2, for modules configured with go_tags
+	GoTag string `json:"go_tag,omitempty" yaml:"go_tag,omitempty"`
+}
+
+// Function to print a compact table of the tags handled during a run
+func printSummary(w io.Writer, results []tagResult) {
+	if len(results) == 0 {
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODULE\tCHANNEL\tOLD\tNEW\tTAG\tPUSHED")
+	for _, r := range results {
+		pushed := "no"
+		if r.Pushed {
+			pushed = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Module, r.Channel, r.Old, r.New, r.Tag, pushed)
+	}
+	tw.Flush()
+
+	for _, r := range results {
+		if link := compareURL(pushRemote, r.Previous, r.Tag); link != "" {
+			fmt.Fprintf(w, "Compare %s: %s\n", r.Tag, link)
+		}
+	}
+}
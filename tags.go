@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxTagLength caps the size of a single ref name read from git, so a
+// malformed ref cannot make the scanner grow its buffer without bound.
+const maxTagLength = 64 * 1024
+
+// Function to tell whether a discovered module or channel name is offered,
+// which it is when it is a name the tool accepts when tagging, whatever
+// tag_pattern captured
+func discoverable(name string) bool {
+	return validateName("name", name) == nil
+}
+
+// tagIndex keeps only the highest version seen for every module and release
+// channel, so memory grows with the number of module/channel pairs instead of
+// the number of tags in the repository.
+type tagIndex struct {
+	latest map[string]map[string]Version
+}
+
+func newTagIndex() *tagIndex {
+	return &tagIndex{latest: make(map[string]map[string]Version)}
+}
+
+// Function to record a version, keeping it only if it is the highest so far
+func (idx *tagIndex) add(module, channel string, version Version) {
+	channels, ok := idx.latest[module]
+	if !ok {
+		channels = make(map[string]Version)
+		idx.latest[module] = channels
+	}
+	if current, ok := channels[channel]; !ok || compareVersions(current, version) < 0 {
+		channels[channel] = version
+	}
+}
+
+// Function to list discovered module names in sorted order
+func (idx *tagIndex) modules() []string {
+	var modules []string
+	for module, channels := range idx.latest {
+		if !discoverable(module) {
+			continue
+		}
+		for channel := range channels {
+			if discoverable(channel) {
+				modules = append(modules, module)
+				break
+			}
+		}
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// Function to list every module in the index, including names outside the
+// discovery pattern, in sorted order
+func (idx *tagIndex) allModules() []string {
+	modules := make([]string, 0, len(idx.latest))
+	for module := range idx.latest {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// Function to list discovered release channels in sorted order
+func (idx *tagIndex) channels() []string {
+	seen := make(map[string]bool)
+	var channels []string
+	for module, versions := range idx.latest {
+		if !discoverable(module) {
+			continue
+		}
+		for channel := range versions {
+			if discoverable(channel) && !seen[channel] {
+				seen[channel] = true
+				channels = append(channels, channel)
+			}
+		}
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// Function to find the highest version of a module across the given channels
+func (idx *tagIndex) latestFor(module string, channels []string) (Version, bool) {
+	version, _, found := idx.latestTag(module, channels)
+	return version, found
+}
+
+// Function to find the highest version of a module across the given channels
+// together with the channel it was tagged on
+func (idx *tagIndex) latestTag(module string, channels []string) (Version, string, bool) {
+	var latest Version
+	var latestChannel string
+	found := false
+	for _, channel := range channels {
+		version, ok := idx.latest[module][channel]
+		if !ok {
+			continue
+		}
+		if !found || compareVersions(latest, version) < 0 {
+			latest = version
+			latestChannel = channel
+			found = true
+		}
+	}
+	return latest, latestChannel, found
+}
+
+// Function to parse a tag name into its module, channel and version, following
+// the tag template of the repository. Ignored tags, tombstones and yank
+// markers do not parse.
+func parseTag(tag string) (string, string, Version, bool) {
+	if isIgnoredTag(tag) || isTombstone(tag) || isYankMarker(tag) {
+		return "", "", Version{}, false
+	}
+	return layout.parse(tag)
+}
+
+// Function to stream tag names from git one at a time without buffering the
+// full ref list in memory
+func streamTags(fn func(tag string)) error {
+	cmd := gitCommand("for-each-ref", "--format=%(refname:strip=2)", "refs/tags")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 4096), maxTagLength)
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+	scanErr := scanner.Err()
+	if scanErr != nil {
+		// git may still be writing refs after the one the scanner gave up
+		// on, and would block on the full pipe before exiting
+		io.Copy(io.Discard, stdout)
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("%s: %w: %s", cmd.String(), err, strings.TrimSpace(stderr.String()))
+	}
+	return scanErr
+}
+
+// Function to build the tag index by streaming all tags in the repository.
+// The index is cached under the git directory together with a fingerprint
+// of the refs, so runs in a row skip the scan until a tag changes.
+func scanTagIndex() (*tagIndex, error) {
+	key, keyErr := refsFingerprint()
+	if keyErr == nil {
+		if idx := loadCachedIndex(key); idx != nil {
+			return idx, nil
+		}
+	}
+
+	idx, err := buildTagIndex()
+	if err != nil {
+		return nil, err
+	}
+	if keyErr == nil {
+		if err := saveCachedIndex(key, idx); err != nil {
+			log.Debug().Err(err).Msg("unable to cache the tag index")
+		}
+	}
+	return idx, nil
+}
+
+// Function to build the tag index from every tag in the repository, without
+// looking at the cache
+func buildTagIndex() (*tagIndex, error) {
+	idx := newTagIndex()
+	err := streamTags(func(tag string) {
+		if module, channel, version, ok := parseTag(tag); ok {
+			idx.add(module, channel, version)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
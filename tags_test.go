@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// manyTags is the number of tags of the large repositories the index is
+// tested against
+const manyTags = 100_000
+
+// Function to name manyTags tags spread over 100 modules and two channels,
+// in the sorted order packed-refs keeps them in
+func largeTagSet() []string {
+	tags := make([]string, 0, manyTags)
+	for i := 0; i < manyTags; i++ {
+		module := fmt.Sprintf("svc%02d", i%100)
+		channel := "prod"
+		if i%2 == 1 {
+			channel = "dev"
+		}
+		tags = append(tags, fmt.Sprintf("%s/%s/v1.%d.%d", module, channel, i/1000, i%1000))
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Function to create a repository with manyTags tags
+func newLargeRepo(tb testing.TB) {
+	tb.Helper()
+	_, commit := newTestRepo(tb)
+	packTags(tb, commit, largeTagSet())
+}
+
+func TestScanTagIndexLargeRepository(t *testing.T) {
+	newLargeRepo(t)
+
+	idx, err := scanTagIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(idx.modules()); got != 100 {
+		t.Fatalf("modules = %d, want 100", got)
+	}
+	if got := idx.channels(); strings.Join(got, ",") != "dev,prod" {
+		t.Fatalf("channels = %v, want [dev prod]", got)
+	}
+	// The last tags of svc00 are on prod, numbered 99900, 99800, ...
+	version, channel, ok := idx.latestTag("svc00", []string{"dev", "prod"})
+	if !ok || channel != "prod" || version.String() != "1.99.900" {
+		t.Fatalf("latest svc00 = %s on %s (%v), want 1.99.900 on prod", version, channel, ok)
+	}
+	version, ok = idx.latestFor("svc01", []string{"dev"})
+	if !ok || version.String() != "1.99.901" {
+		t.Fatalf("latest svc01 on dev = %s (%v), want 1.99.901", version, ok)
+	}
+
+	// A second scan reads the cached index and agrees with the first
+	cached, err := scanTagIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, ok := cached.latestFor("svc00", []string{"prod"}); !ok || version.String() != "1.99.900" {
+		t.Fatalf("cached latest svc00 = %s (%v), want 1.99.900", version, ok)
+	}
+}
+
+func TestBuildTagIndexMemory(t *testing.T) {
+	newLargeRepo(t)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	idx, err := buildTagIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(idx)
+
+	// Holding every tag name would take several megabytes; the index only
+	// keeps the 200 module/channel pairs
+	const limit = 512 * 1024
+	if retained := int64(after.HeapAlloc) - int64(before.HeapAlloc); retained > limit {
+		t.Fatalf("index of %d tags retains %d bytes, want at most %d", manyTags, retained, limit)
+	}
+}
+
+func TestStreamTagsLineTooLong(t *testing.T) {
+	_, commit := newTestRepo(t)
+	// The oversized ref sorts first, so git still has every other ref to
+	// write when the scanner gives up on it
+	tags := append([]string{"a" + strings.Repeat("x", maxTagLength)}, largeTagSet()...)
+	packTags(t, commit, tags)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- streamTags(func(string) {})
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("streamTags succeeded on a ref longer than maxTagLength")
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("streamTags did not return after a ref longer than maxTagLength")
+	}
+}
+
+func BenchmarkBuildTagIndex(b *testing.B) {
+	newLargeRepo(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := buildTagIndex(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkScanTagIndex(b *testing.B) {
+	newLargeRepo(b)
+	if _, err := scanTagIndex(); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := scanTagIndex(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseTag(b *testing.B) {
+	tags := largeTagSet()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, ok := parseTag(tags[i%len(tags)]); !ok {
+			b.Fatalf("%s does not parse", tags[i%len(tags)])
+		}
+	}
+}
+
+func BenchmarkTagIndexLatest(b *testing.B) {
+	idx := newTagIndex()
+	for _, tag := range largeTagSet() {
+		if module, channel, version, ok := parseTag(tag); ok {
+			idx.add(module, channel, version)
+		}
+	}
+	modules := idx.allModules()
+	channels := []string{"dev", "prod"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, ok := idx.latestTag(modules[i%len(modules)], channels); !ok {
+			b.Fatalf("no version of %s", modules[i%len(modules)])
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultTagTemplate is the module/channel/vX.Y.Z layout of tags
+const defaultTagTemplate = "{{.Module}}/{{.Channel}}/v{{.Version}}"
+
+// versionPattern matches a version, with an optional build number as fourth
+// segment, an optional prerelease such as -rc.1 and optional build metadata
+// such as +abc1234
+const versionPattern = `\d+\.\d+\.\d+(?:\.\d+)?(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?`
+
+// counterPattern matches the build counter of a counter channel, such as
+// b1042, with optional build metadata
+const counterPattern = `b\d+(?:\+[0-9A-Za-z.-]+)?`
+
+// plainTagTemplate is the layout of ordinary v1.2.3 tags, naming neither
+// module nor channel, for repositories releasing a single module
+const plainTagTemplate = "v{{.Version}}"
+
+// versionV matches the v written before the version in a template, which
+// counters go without, as in app/ci/b1042
+var versionV = regexp.MustCompile(`(^|[^}])v(\{\{\s*\.Version\s*\}\})`)
+
+// implicitChannel is the only release channel when the tag template names
+// none, as in releases/{{.Module}}/{{.Version}}
+const implicitChannel = "default"
+
+// implicitModule is the only module when the tag template names none, as in
+// v{{.Version}}
+const implicitModule = "default"
+
+// templateField matches a field of a tag template, such as {{.Module}}
+var templateField = regexp.MustCompile(`\{\{\s*\.(\w+)\s*\}\}`)
+
+// tagLayout is how tags are named, from a template placing the module,
+// release channel and version
+type tagLayout struct {
+	text string
+	// hasModule is false when tags do not name a module, every tag being
+	// of implicitModule
+	hasModule bool
+	// hasChannel is false when tags do not name a channel, every tag being
+	// on implicitChannel
+	hasChannel bool
+	// pattern matches a tag, capturing its module, channel and version
+	pattern *regexp.Regexp
+	// nearMiss matches the start of names that look like tags, up to the
+	// first digit of the version, which may still fail pattern
+	nearMiss *regexp.Regexp
+	// counterText is the template naming the builds of counter channels,
+	// without the v before the version
+	counterText string
+	// counter matches the tags of counter channels
+	counter *regexp.Regexp
+}
+
+// layout is the tag layout of the repository, set by loadTagLayout
+var layout = mustTagLayout(defaultTagTemplate)
+
+// Function to compile a tag template. The version must appear once, and so
+// must the module and channel unless tags have none, with some text between
+// them so a tag can be split back.
+func parseTagLayout(text string) (*tagLayout, error) {
+	l, err := compileTagLayout(text, versionPattern)
+	if err != nil {
+		return nil, err
+	}
+	l.counterText = versionV.ReplaceAllString(text, "$1$2")
+	counter, err := compileTagLayout(l.counterText, counterPattern)
+	if err != nil {
+		return nil, err
+	}
+	l.counter = counter.pattern
+	return l, nil
+}
+
+// Function to compile a tag template whose version matches the given
+// expression
+func compileTagLayout(text, version string) (*tagLayout, error) {
+	var pattern, nearMiss strings.Builder
+	pattern.WriteString("^")
+	nearMiss.WriteString("^")
+	seen := make(map[string]bool)
+	last := 0
+	for _, loc := range templateField.FindAllStringSubmatchIndex(text, -1) {
+		literal := text[last:loc[0]]
+		name := text[loc[2]:loc[3]]
+		switch {
+		case strings.Contains(literal, "{{"):
+			return nil, fmt.Errorf("invalid tag template %q, only {{.Module}}, {{.Channel}} and {{.Version}} can be used", text)
+		case name != "Module" && name != "Channel" && name != "Version":
+			return nil, fmt.Errorf("invalid tag template %q, unknown field {{.%s}}", text, name)
+		case seen[name]:
+			return nil, fmt.Errorf("invalid tag template %q, {{.%s}} appears twice", text, name)
+		case last > 0 && literal == "":
+			return nil, fmt.Errorf("invalid tag template %q, fields must be separated by some text", text)
+		}
+
+		pattern.WriteString(regexp.QuoteMeta(literal))
+		if name == "Version" {
+			pattern.WriteString(`(?P<version>` + version + `)`)
+		} else {
+			pattern.WriteString(`(?P<` + strings.ToLower(name) + `>[^/]+?)`)
+		}
+		// Hand made tags often miss the v before the version or capitalise
+		// it, so the near miss pattern stops at the first digit
+		if !seen["Version"] {
+			if name == "Version" {
+				prefix, _ := strings.CutSuffix(literal, "v")
+				nearMiss.WriteString(regexp.QuoteMeta(prefix) + `[vV]?\d`)
+			} else {
+				nearMiss.WriteString(regexp.QuoteMeta(literal) + `[^/]+`)
+			}
+		}
+		seen[name] = true
+		last = loc[1]
+	}
+	if !seen["Version"] {
+		return nil, fmt.Errorf("invalid tag template %q, {{.Version}} is missing", text)
+	}
+	if strings.Contains(text[last:], "{{") {
+		return nil, fmt.Errorf("invalid tag template %q, only {{.Module}}, {{.Channel}} and {{.Version}} can be used", text)
+	}
+	pattern.WriteString(regexp.QuoteMeta(text[last:]) + "$")
+	return &tagLayout{
+		text:       text,
+		hasModule:  seen["Module"],
+		hasChannel: seen["Channel"],
+		pattern:    regexp.MustCompile(pattern.String()),
+		nearMiss:   regexp.MustCompile(nearMiss.String()),
+	}, nil
+}
+
+// Function to compile a tag template known to be valid
+func mustTagLayout(text string) *tagLayout {
+	l, err := parseTagLayout(text)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// Function to name the tag of a version of a module on a channel
+func (l *tagLayout) format(module, channel, version string) string {
+	return expandTemplate(l.text, module, channel, version)
+}
+
+// Function to name the tag of a build of a module on a counter channel
+func (l *tagLayout) formatCounter(module, channel, counter string) string {
+	return expandTemplate(l.counterText, module, channel, counter)
+}
+
+// Function to fill in the fields of a tag template
+func expandTemplate(text, module, channel, version string) string {
+	return templateField.ReplaceAllStringFunc(text, func(field string) string {
+		switch templateField.FindStringSubmatch(field)[1] {
+		case "Module":
+			return module
+		case "Channel":
+			return channel
+		}
+		return version
+	})
+}
+
+// Function to build a ref glob matching the tags of a module on a channel,
+// an empty channel matching every channel. Going without the v before the
+// version, it matches the tags of counter channels too.
+func (l *tagLayout) glob(module, channel string) string {
+	if channel == "" {
+		channel = "*"
+	}
+	return "refs/tags/" + l.formatCounter(module, channel, "*")
+}
+
+// Function to discover tags with a regular expression of its own instead of
+// the one derived from the template. It must capture the version, and the
+// module and channel when the template names them, in named groups, and
+// match the tags the template names.
+func (l *tagLayout) withPattern(expr string) (*tagLayout, error) {
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag pattern: %w", err)
+	}
+	groups := []string{"version"}
+	if l.hasModule {
+		groups = append(groups, "module")
+	}
+	if l.hasChannel {
+		groups = append(groups, "channel")
+	}
+	for _, group := range groups {
+		if pattern.SubexpIndex(group) < 0 {
+			return nil, fmt.Errorf("invalid tag pattern %q, it must capture (?P<%s>...)", expr, group)
+		}
+	}
+	module, channel := "api", "prod"
+	if !l.hasModule {
+		module = implicitModule
+	}
+	if !l.hasChannel {
+		channel = implicitChannel
+	}
+	sample := l.format(module, channel, "1.2.3")
+	custom := *l
+	custom.pattern = pattern
+	if m, _, version, ok := custom.parse(sample); !ok || m != module || version.String() != "1.2.3" {
+		return nil, fmt.Errorf("tag pattern %q does not read back %s, named by the tag template", expr, sample)
+	}
+	return &custom, nil
+}
+
+// Function to split a tag into its module, release channel and version
+func (l *tagLayout) parse(tag string) (string, string, Version, bool) {
+	pattern := l.pattern
+	matches := pattern.FindStringSubmatch(tag)
+	if matches == nil && l.counter != nil {
+		pattern = l.counter
+		matches = pattern.FindStringSubmatch(tag)
+	}
+	if matches == nil {
+		return "", "", Version{}, false
+	}
+	version, err := parseVersion(matches[pattern.SubexpIndex("version")])
+	if err != nil {
+		return "", "", Version{}, false
+	}
+	module, channel := implicitModule, implicitChannel
+	if l.hasModule {
+		module = matches[pattern.SubexpIndex("module")]
+	}
+	if l.hasChannel {
+		channel = matches[pattern.SubexpIndex("channel")]
+	}
+	return module, channel, version, true
+}
+
+// Function to check that tags can be named for a module, which is only the
+// implicit one when the template names no module
+func (l *tagLayout) checkModule(module string) error {
+	if !l.hasModule && module != implicitModule {
+		return fmt.Errorf("tags named %s have no module, use %s instead of %s", l.text, implicitModule, module)
+	}
+	return nil
+}
+
+// Function to check that tags can be named for a release channel, which
+// is only the implicit one when the template names no channel
+func (l *tagLayout) checkChannel(channel string) error {
+	if !l.hasChannel && channel != implicitChannel {
+		return fmt.Errorf("tags named %s have no release channel, use %s instead of %s", l.text, implicitChannel, channel)
+	}
+	return nil
+}
+
+// Function to fill in the module and release channel when the tag template
+// names none, so -m and -r can be left out
+func impliedNames(module, channel string) (string, string) {
+	if module == "" && !layout.hasModule {
+		module = implicitModule
+	}
+	if channel == "" && !layout.hasChannel {
+		channel = implicitChannel
+	}
+	return module, channel
+}
+
+// Function to read the tag template of the repository before any tag is
+// named or parsed. Only the tag_template and tag_pattern keys are looked at,
+// so a mistake elsewhere in the configuration is reported by the commands
+// reading it.
+func loadTagLayout() error {
+	path, err := configPath()
+	if err != nil {
+		// Outside a repository there are no tags to name
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var config struct {
+		TagTemplate string `yaml:"tag_template"`
+		TagPattern  string `yaml:"tag_pattern"`
+	}
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	l, err := configuredLayout(config.TagTemplate, config.TagPattern)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	layout = l
+	return nil
+}
+
+// Function to build the tag layout from the tag_template and tag_pattern
+// settings, either of which may be empty
+func configuredLayout(template, pattern string) (*tagLayout, error) {
+	l := mustTagLayout(defaultTagTemplate)
+	if template != "" {
+		var err error
+		if l, err = parseTagLayout(template); err != nil {
+			return nil, err
+		}
+	}
+	if pattern == "" {
+		return l, nil
+	}
+	return l.withPattern(pattern)
+}
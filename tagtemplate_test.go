@@ -0,0 +1,84 @@
+package main
+
+import "testing"
+
+// Function to name and read tags with the given template until the test ends
+func withLayout(t *testing.T, template string) {
+	t.Helper()
+	l, err := parseTagLayout(template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := layout
+	layout = l
+	t.Cleanup(func() { layout = previous })
+}
+
+func TestTagTemplateRoundTrip(t *testing.T) {
+	tests := []struct {
+		template        string
+		module, channel string
+		version         string
+		tag             string
+	}{
+		{defaultTagTemplate, "api", "prod", "1.4.2", "api/prod/v1.4.2"},
+		{defaultTagTemplate, "payments.eu", "pre-prod", "2.0.0-rc.1+abc1234", "payments.eu/pre-prod/v2.0.0-rc.1+abc1234"},
+		{defaultTagTemplate, "app", "prod", "1.4.9.41", "app/prod/v1.4.9.41"},
+		{defaultTagTemplate, "app", "ci", "b1042", "app/ci/b1042"},
+		{plainTagTemplate, implicitModule, implicitChannel, "1.3.0", "v1.3.0"},
+		{plainTagTemplate, implicitModule, implicitChannel, "1.3.0-beta.2", "v1.3.0-beta.2"},
+		// The module last, after the version
+		{"{{.Channel}}/v{{.Version}}/{{.Module}}", "api", "prod", "1.4.2", "prod/v1.4.2/api"},
+		{"{{.Channel}}/v{{.Version}}/{{.Module}}", "api", "prod", "1.4.2-rc.1", "prod/v1.4.2-rc.1/api"},
+		{"{{.Channel}}/v{{.Version}}/{{.Module}}", "api", "ci", "b7", "ci/b7/api"},
+		{"{{.Channel}}-{{.Module}}-{{.Version}}", "app", "prod", "0.1.1", "prod-app-0.1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			withLayout(t, tt.template)
+			version, err := parseVersion(tt.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tag := formatTag(tt.module, tt.channel, version)
+			if tag != tt.tag {
+				t.Fatalf("formatTag = %s, want %s", tag, tt.tag)
+			}
+			module, channel, parsed, ok := parseTag(tag)
+			if !ok {
+				t.Fatalf("%s does not parse with %s", tag, tt.template)
+			}
+			if module != tt.module || channel != tt.channel || parsed.String() != tt.version {
+				t.Fatalf("parseTag(%s) = %s, %s, %s, want %s, %s, %s", tag, module, channel, parsed, tt.module, tt.channel, tt.version)
+			}
+		})
+	}
+}
+
+func TestTagTemplateRejectsAmbiguous(t *testing.T) {
+	for _, template := range []string{
+		// Adjacent fields cannot be split back
+		"{{.Module}}{{.Channel}}/v{{.Version}}",
+		"{{.Module}}/{{.Channel}}{{.Version}}",
+		// A field named twice
+		"{{.Module}}/{{.Module}}/v{{.Version}}",
+		"v{{.Version}}/{{.Version}}",
+		// No version, or fields that cannot be read back
+		"{{.Module}}/{{.Channel}}",
+		"{{.Module}}/{{.Branch}}/v{{.Version}}",
+		"{{.Module}}/{{ .Channel | lower }}/v{{.Version}}",
+	} {
+		if _, err := parseTagLayout(template); err == nil {
+			t.Errorf("parseTagLayout(%q) succeeded, want an error", template)
+		}
+	}
+}
+
+func TestTagTemplateIgnoresOtherLayouts(t *testing.T) {
+	withLayout(t, "{{.Channel}}/v{{.Version}}/{{.Module}}")
+	for _, tag := range []string{"api/prod/v1.4.2", "v1.4.2", "prod/v1.4/api", "prod/v1.4.2/api/extra"} {
+		if module, channel, version, ok := parseTag(tag); ok {
+			t.Errorf("parseTag(%s) = %s, %s, %s, want no match", tag, module, channel, version)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// stringList is a flag value that collects every occurrence of a repeatable
+// flag, also accepting comma separated values
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+var excludes stringList
+
+// Function to report whether a name matches any of the exclude globs
+func isExcluded(name string) bool {
+	for _, pattern := range excludes {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Function to drop every name matching an exclude glob
+func applyExcludes(names []string) []string {
+	var kept []string
+	for _, name := range names {
+		if !isExcluded(name) {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
+
+// Function to report whether a module argument is a glob pattern
+func isPattern(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// Function to expand a module glob against every module found in the tag
+// index, returning the matches in sorted order
+func expandModules(pattern string, idx *tagIndex) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid module pattern %q: %w", pattern, err)
+	}
+	var matches []string
+	for _, module := range idx.allModules() {
+		if ok, _ := path.Match(pattern, module); ok {
+			matches = append(matches, module)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no modules match pattern %q", pattern)
+	}
+	return matches, nil
+}
+
+// Function to resolve every plain module name in a comma separated module
+// argument to its known spelling, warning about probable typos
+func resolveModuleArg(moduleArg string, modules []string) string {
+	names := strings.Split(moduleArg, ",")
+	for i, name := range names {
+		if isPattern(name) {
+			continue
+		}
+		names[i] = resolveName(name, modules)
+		suggestExisting("module", names[i], modules)
+	}
+	return strings.Join(names, ",")
+}
+
+// Function to turn the module and release channel arguments, both of which
+// may be comma separated lists, into the modules and channels to operate on,
+// expanding module globs, applying excludes and validating every name. The
+// current versions of the targets are checked for rewritten history.
+func resolveTargets(idx *tagIndex, moduleArg, channelArg string) ([]string, []string, error) {
+	var targets []string
+	for _, arg := range strings.Split(moduleArg, ",") {
+		if !isPattern(arg) {
+			if !slices.Contains(targets, arg) {
+				targets = append(targets, arg)
+			}
+			continue
+		}
+		matches, err := expandModules(arg, idx)
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Info().Str("pattern", arg).Strs("modules", matches).Msg("Module pattern expanded")
+		for _, m := range matches {
+			if !slices.Contains(targets, m) {
+				targets = append(targets, m)
+			}
+		}
+	}
+	if len(excludes) > 0 {
+		targets = applyExcludes(targets)
+		if len(targets) == 0 {
+			return nil, nil, fmt.Errorf("every module was excluded by %s", excludes.String())
+		}
+		log.Info().Strs("modules", targets).Msg("Modules after exclusions")
+	}
+	for _, m := range targets {
+		if err := validateModule(m); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	releases := idx.channels()
+	channels := strings.Split(channelArg, ",")
+	for i, r := range channels {
+		r = resolveName(r, releases)
+		channels[i] = r
+		if err := validateChannel(r); err != nil {
+			return nil, nil, err
+		}
+		if len(channels) > 1 {
+			suggestExisting("release channel", r, releases)
+		}
+	}
+	if len(excludes) > 0 {
+		channels = applyExcludes(channels)
+		if len(channels) == 0 {
+			return nil, nil, fmt.Errorf("every release channel was excluded by %s", excludes.String())
+		}
+	}
+	for _, m := range targets {
+		checkHistoryRewrite(idx, m, channels)
+	}
+	return targets, channels, nil
+}
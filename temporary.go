@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultTemporaryCommits recognise the commits of common merge queues when
+// the configuration does not list any patterns
+var defaultTemporaryCommits = TemporaryCommits{
+	Authors:  []string{"*merge-queue*", "bors*"},
+	Messages: []string{`gh-readonly-queue/`, `^Try #\d+`},
+}
+
+var allowTemporary bool
+
+// Function to describe why a commit looks like it was created by a merge
+// queue or bot, or return an empty string when it does not
+func temporaryReason(rules TemporaryCommits, commit string) (string, error) {
+	out, err := gitOutput("log", "-1", "--format=%an%x00%ae%x00%B", commit)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.SplitN(out, "\x00", 3)
+	if len(fields) != 3 {
+		return "", fmt.Errorf("unable to read commit %s", commit)
+	}
+	name, email, message := fields[0], fields[1], fields[2]
+
+	for _, pattern := range rules.Authors {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return "", fmt.Errorf("invalid author pattern %q: %w", pattern, err)
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return "authored by " + name, nil
+		}
+		if ok, _ := path.Match(pattern, email); ok {
+			return "authored by " + email, nil
+		}
+	}
+	for _, pattern := range rules.Messages {
+		re, err := regexp.Compile("(?m)" + pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid message pattern %q: %w", pattern, err)
+		}
+		if re.MatchString(message) {
+			return "message matches " + pattern, nil
+		}
+	}
+	return "", nil
+}
+
+// Function to warn about, or refuse, tagging a commit created by a merge
+// queue or bot, since such commits may be rebased away and leave the tag
+// dangling
+func checkTemporaryCommit(config *Config, commit string) error {
+	rules := config.TemporaryCommits
+	if rules == nil {
+		rules = &defaultTemporaryCommits
+	}
+	reason, err := temporaryReason(*rules, commit)
+	if err != nil || reason == "" {
+		return err
+	}
+	if !rules.Refuse || allowTemporary {
+		log.Warn().Str("commit", commit).Str("reason", reason).Msg("commit looks like a temporary merge queue commit and may be rebased away")
+		return nil
+	}
+	return fmt.Errorf("commit %s looks like a temporary merge queue commit (%s), use --allow-temporary to tag it anyway", shortHash(commit), reason)
+}
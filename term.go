@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+
+	"github.com/mattn/go-colorable"
+	"github.com/mattn/go-isatty"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Function to report whether a file is an interactive terminal, including
+// Cygwin and MSYS terminals on Windows
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// Function to configure console logging to the given file. Colors are only used on terminals,
+// and on Windows the output goes through a writer that translates ANSI
+// sequences for consoles that do not understand them.
+func setupLogging(out *os.File) {
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	log.Logger = log.Output(zerolog.ConsoleWriter{
+		Out:        colorable.NewColorable(out),
+		TimeFormat: "15:04:05",
+		NoColor:    !isTerminal(out) || os.Getenv("NO_COLOR") != "",
+	})
+	zerolog.SetGlobalLevel(zerolog.TraceLevel)
+}
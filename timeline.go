@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// tagEntry describes a version tag together with its creation details
+type tagEntry struct {
+	Tag     string
+	Module  string
+	Channel string
+	Version Version
+	Commit  string
+	Tagger  string
+	Date    time.Time
+}
+
+// Function to read every version tag with its commit, tagger and creation
+// date, oldest first. Lightweight tags use the committer as tagger.
+func readTagEntries(keep func(module, channel string) bool) ([]tagEntry, error) {
+	format := strings.Join([]string{
+		"%(refname:strip=2)", "%(objecttype)", "%(objectname)", "%(*objectname)",
+		"%(taggername)", "%(committername)", "%(creatordate:iso-strict)",
+	}, "%09")
+	out, err := gitOutput("for-each-ref", "--sort=creatordate", "--format="+format, "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []tagEntry
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			continue
+		}
+		module, channel, version, ok := parseTag(fields[0])
+		if !ok || !keep(module, channel) {
+			continue
+		}
+		entry := tagEntry{Tag: fields[0], Module: module, Channel: channel, Version: version, Commit: fields[2], Tagger: fields[5]}
+		if fields[1] == "tag" {
+			entry.Commit, entry.Tagger = fields[3], fields[4]
+		}
+		entry.Date, _ = time.Parse(time.RFC3339, fields[6])
+		entries = append(entries, entry)
+	}
+	// Tags created within the same second keep their version order
+	slices.SortStableFunc(entries, func(a, b tagEntry) int {
+		if c := a.Date.Compare(b.Date); c != 0 {
+			return c
+		}
+		return compareVersions(a.Version, b.Version)
+	})
+	return entries, nil
+}
+
+// Function to handle `version history`, listing the tags of a module in the
+// order they were created
+func runHistory(args []string) int {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	modulePattern := fs.String("m", "", "module name or glob")
+	channelArg := fs.String("r", "", "release channel or comma separated list (default all)")
+	fs.Var(&excludes, "exclude", "glob of modules or release channels to skip, may be repeated")
+	fs.Parse(args)
+
+	if *modulePattern == "" {
+		log.Error().Msg("-m is required")
+		return 2
+	}
+	var channels []string
+	if *channelArg != "" {
+		channels = strings.Split(*channelArg, ",")
+	}
+
+	entries, err := readTagEntries(func(module, channel string) bool {
+		if ok, _ := path.Match(*modulePattern, module); !ok || isExcluded(module) || isExcluded(channel) {
+			return false
+		}
+		return len(channels) == 0 || slices.Contains(channels, channel)
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("unable to read tags")
+		return 1
+	}
+	if len(entries) == 0 {
+		log.Info().Str("module", *modulePattern).Msg("No version tags found")
+		return 0
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATE\tVERSION\tTAG\tCOMMIT\tTAGGER")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.Date.Local().Format(time.DateTime), e.Version, e.Tag, shortHash(e.Commit), e.Tagger)
+	}
+	tw.Flush()
+	return 0
+}
+
+// Function to abbreviate a commit hash for display
+func shortHash(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// tombstonePrefix names the tags left in place of soft-deleted tags, so
+// api/prod/v1.2.3 becomes deleted/api/prod/v1.2.3
+const tombstonePrefix = "deleted/"
+
+// Function to tell whether a tag is the tombstone of a soft-deleted tag
+func isTombstone(tag string) bool {
+	return strings.HasPrefix(tag, tombstonePrefix)
+}
+
+// Function to replace a tag with a tombstone: an annotated tag of the
+// original tag object, keeping its commit, message and signature, that
+// records why, when and by whom it was deleted
+func createTombstone(tag, reason string) (string, error) {
+	tombstone := tombstonePrefix + tag
+	if _, err := gitOutput("rev-parse", "--verify", "--quiet", "refs/tags/"+tombstone); err == nil {
+		return "", fmt.Errorf("%s already exists, delete it first to soft-delete %s again", tombstone, tag)
+	}
+	commit, err := resolveCommit("refs/tags/" + tag)
+	if err != nil {
+		return "", err
+	}
+	name, _ := gitOutput("config", "user.name")
+	email, _ := gitOutput("config", "user.email")
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "Deleted %s\n\n", tag)
+	if reason != "" {
+		fmt.Fprintf(&message, "Reason: %s\n", reason)
+	}
+	fmt.Fprintf(&message, "Deleted-By: %s <%s>\n", name, email)
+	fmt.Fprintf(&message, "Deleted-At: %s\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(&message, "Commit: %s\n", commit)
+	if _, err := gitOutputWithInput(message.String(), "-c", "advice.nestedTag=false", "tag", "--annotate", "--cleanup=verbatim", "--file=-", tombstone, "refs/tags/"+tag); err != nil {
+		return "", err
+	}
+	return tombstone, nil
+}
+
+// Function to print the tombstones of soft-deleted tags with the reason
+// they were deleted
+func listTombstones() error {
+	out, err := gitOutput("for-each-ref", "--sort=-creatordate", "--format=%(refname:strip=2)%09%(creatordate:short)%09%(taggername)%09%(contents:body)", "refs/tags/"+strings.TrimSuffix(tombstonePrefix, "/"))
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TAG\tDELETED\tBY\tREASON")
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		var reason string
+		for _, trailer := range strings.Split(fields[3], "\n") {
+			if value, ok := strings.CutPrefix(trailer, "Reason: "); ok {
+				reason = value
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", strings.TrimPrefix(fields[0], tombstonePrefix), fields[1], fields[2], reason)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// tutorial makes the tagging flow explain each of its steps
+var tutorial bool
+
+// tutorialSteps are the explanations printed before each step of the
+// tagging flow in tutorial mode
+var tutorialSteps = map[string]string{
+	"intro": `Welcome to the version tutorial. You are about to create and push a real
+release tag, in a throwaway copy of this repository: nothing you do here
+changes the repository or its remotes.
+
+Tags are named <module>/<release channel>/v<major>.<minor>.<patch>, for
+example api/prod/v1.4.2. Each module is versioned on its own, and each
+release channel of a module has its own line of versions.`,
+	"module": `Step 1: pick the module to release. The list shows the modules that
+already have tags or are declared in .version.yaml. Type a name from the
+list; a new name starts a new module at v0.0.1.`,
+	"channel": `Step 2: pick the release channel, such as dev, staging or prod. Several
+channels can be tagged at once by separating them with commas, and all of
+them get the same version.`,
+	"new": `This name is not used yet. Confirming creates it; answer no if it was a
+typo.`,
+	"tag": `Step 3: the next version is computed from the latest tag of the module
+on the channel, bumping the patch number by default (use --minor, --major
+or --set outside the tutorial). The tag is created on the commit checked
+out, HEAD by default, or on the commit given with -c.`,
+	"push": `Step 4: the tags are pushed to the remote so CI and your teammates see
+them. Outside the tutorial use --push, or run 'version push' later.`,
+	"done": `That's it. Run 'version --sandbox' to rehearse a release of your own
+with every option, and 'version --help' to see all the flags. The
+sandbox report below lists what the tutorial changed in the throwaway copy.`,
+}
+
+// Function to print the explanation of a step of the tagging flow when
+// running the tutorial
+func explain(step string) {
+	if !tutorial {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\n%s\n\n", strings.TrimSpace(tutorialSteps[step]))
+}
+
+// Function to handle `version tutorial`, walking through a release in a
+// sandbox with an explanation at each step
+func runTutorial(args []string) int {
+	fs := flag.NewFlagSet("tutorial", flag.ExitOnError)
+	registerTagFlags(fs)
+	fs.Parse(args)
+	if nonInteractive || dryRun {
+		log.Error().Msg("the tutorial is interactive, run it without --yes and --dry-run")
+		return 2
+	}
+	if plainPrompts {
+		setupPlainLogging()
+	}
+
+	if !useSandbox {
+		sandbox, err := startSandbox()
+		if err != nil {
+			log.Error().Err(err).Msg("unable to create sandbox")
+			return 1
+		}
+		defer sandbox.finish()
+	}
+
+	tutorial = true
+	// The sandbox's origin is a throwaway repository, so pushing is safe and
+	// shows the whole release
+	pushCreated, pushRemote = true, "origin"
+	explain("intro")
+	code := run(fs)
+	if code == 0 {
+		explain("done")
+	}
+	return code
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const maxNameLength = 64
+
+var ignoreCase bool
+
+var (
+	slugSeparators = regexp.MustCompile(`[\s/]+`)
+	slugInvalid    = regexp.MustCompile(`[^a-z0-9._-]`)
+	// namePattern is what module and release channel names may be made of,
+	// as described by nameRules. Dots only separate parts of a name, since
+	// git refuses refs with .. in them or ending with a dot.
+	namePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*(\.[a-z0-9_-]+)*$`)
+	// reservedNames cannot be used as module or channel names because they
+	// carry special meaning for tooling built around the tags, or name the
+	// tombstones of soft-deleted tags and the markers of yanked ones
+	reservedNames = []string{"latest", "stable", "head", "all", "deleted", "yanked"}
+)
+
+// nameRules describes namePattern in the errors about invalid names
+const nameRules = "names use lowercase letters, digits, dashes, underscores and single dots between them and start with a letter"
+
+// Function to validate a module or release channel name, telling what is
+// wrong with it and what names may be made of
+func validateName(kind, name string) error {
+	switch {
+	case len(name) == 0:
+		return fmt.Errorf("%s name is empty", kind)
+	case len(name) > maxNameLength:
+		return fmt.Errorf("%s name %q is longer than %d characters", kind, name, maxNameLength)
+	case slices.Contains(reservedNames, name):
+		return fmt.Errorf("%s name %q is reserved, %s cannot be used", kind, name, strings.Join(reservedNames, ", "))
+	case strings.HasSuffix(name, ".lock"):
+		return fmt.Errorf("invalid %s name %q: git does not allow names ending with .lock in tags", kind, name)
+	case namePattern.MatchString(name):
+		return nil
+	}
+	var problem string
+	if i := slugInvalid.FindStringIndex(name); i != nil {
+		problem = fmt.Sprintf("%q is not allowed", name[i[0]:i[1]])
+		if lower := strings.ToLower(name); lower != name && namePattern.MatchString(lower) {
+			problem += ", use " + lower
+		}
+	} else if name[0] < 'a' || name[0] > 'z' {
+		problem = "it must start with a letter"
+	} else {
+		problem = "dots must sit between other characters"
+	}
+	return fmt.Errorf("invalid %s name %q: %s; %s", kind, name, problem, nameRules)
+}
+
+// Function to validate a module name, which must also be one the tag
+// template can name
+func validateModule(name string) error {
+	if err := validateName("module", name); err != nil {
+		return err
+	}
+	return layout.checkModule(name)
+}
+
+// Function to validate a release channel name, which must also be one the
+// tag template can name
+func validateChannel(name string) error {
+	if err := validateName("release channel", name); err != nil {
+		return err
+	}
+	return layout.checkChannel(name)
+}
+
+// Function to normalize user input into slug form: trimmed, lowercased, with
+// separators turned into dashes and any other invalid characters dropped
+func slugify(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = slugSeparators.ReplaceAllString(name, "-")
+	name = slugInvalid.ReplaceAllString(name, "")
+	return strings.Trim(name, "-.")
+}
+
+// Function to resolve user input to a known name when case-insensitive
+// matching is enabled, so the stored canonical spelling is always reused
+func resolveName(name string, known []string) string {
+	if !ignoreCase {
+		return name
+	}
+	slug := slugify(name)
+	for _, candidate := range known {
+		if strings.EqualFold(candidate, name) || slugify(candidate) == slug {
+			return candidate
+		}
+	}
+	return slug
+}
+
+// Function to find the closest known name to a probable typo, returning an
+// empty string when nothing is close enough to be a useful suggestion
+func suggestName(name string, known []string) string {
+	best, bestDistance := "", -1
+	for _, candidate := range known {
+		d := editDistance(name, candidate)
+		if bestDistance < 0 || d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	threshold := max(2, len(name)/3)
+	if bestDistance < 0 || bestDistance > threshold {
+		return ""
+	}
+	return best
+}
+
+// Function to compute the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
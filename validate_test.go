@@ -0,0 +1,56 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"api", "user-service", "api_v2", "web2", "payments.eu", "a.b.c"} {
+		if err := validateName("module", name); err != nil {
+			t.Errorf("validateName(%q) = %v, want nil", name, err)
+		}
+	}
+	for _, name := range []string{"", "Api", "2api", "-api", "api..eu", "api.", "api.lock", "api/eu", "api eu", "latest", "deleted"} {
+		if err := validateName("module", name); err == nil {
+			t.Errorf("validateName(%q) = nil, want an error", name)
+		}
+	}
+}
+
+func TestDiscoveredNamesCanBeTagged(t *testing.T) {
+	previous := layout
+	t.Cleanup(func() { layout = previous })
+	l, err := configuredLayout("", `^(?P<module>[A-Za-z0-9.-]+)/(?P<channel>[a-z]+)/v(?P<version>\d+\.\d+\.\d+)$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layout = l
+
+	idx := newTagIndex()
+	for _, tag := range []string{"payments.eu/prod/v1.0.0", "user-service/prod/v1.2.0", "Legacy/prod/v0.1.0", "api..v2/prod/v1.0.0"} {
+		module, channel, version, ok := parseTag(tag)
+		if !ok {
+			t.Fatalf("%s does not parse", tag)
+		}
+		idx.add(module, channel, version)
+	}
+
+	modules := idx.modules()
+	if want := []string{"payments.eu", "user-service"}; !slices.Equal(modules, want) {
+		t.Fatalf("discovered modules = %v, want %v", modules, want)
+	}
+	for _, module := range modules {
+		if err := validateModule(module); err != nil {
+			t.Errorf("discovered module %s cannot be tagged: %v", module, err)
+		}
+	}
+}
+
+func TestSlugifyKeepsDots(t *testing.T) {
+	for input, want := range map[string]string{"Payments.EU": "payments.eu", " User Service ": "user-service", "api/v2.": "api-v2"} {
+		if got := slugify(input); got != want {
+			t.Errorf("slugify(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
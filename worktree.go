@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/rs/zerolog/log"
+)
+
+var verifyCommand string
+
+// Function to run fn inside a temporary worktree checked out at commit. Every
+// call gets its own directory, so concurrent runs never share a checkout, and
+// the worktree is removed again once fn returns.
+func withWorktree(commit string, fn func(dir string) error) error {
+	dir, err := os.MkdirTemp("", "version-worktree-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := gitOutput("worktree", "add", "--detach", "--quiet", dir, commit); err != nil {
+		return err
+	}
+	defer func() {
+		if _, err := gitOutput("worktree", "remove", "--force", dir); err != nil {
+			log.Warn().Err(err).Str("dir", dir).Msg("unable to remove temporary worktree")
+		}
+	}()
+
+	return fn(dir)
+}
+
+// Function to prepare a user supplied command line to run through the shell
+// of the platform
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
+// Function to run the verify command against a commit. The command runs in
+// the current checkout when it already is that commit, and in a temporary
+// worktree otherwise.
+func runVerify(command, moduleName, commit string) error {
+	run := func(dir string) error {
+		cmd := shellCommand(command)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "VERSION_MODULE="+moduleName, "VERSION_COMMIT="+commit)
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		log.Info().Str("command", command).Str("module", moduleName).Str("commit", commit).Msg("Running verify command")
+		return cmd.Run()
+	}
+
+	head, err := resolveCommit("HEAD")
+	if err == nil && head == commit {
+		return run(repoDir)
+	}
+	return withWorktree(commit, run)
+}
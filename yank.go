@@ -0,0 +1,211 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// yankPrefix names the tags marking yanked versions, so yanking
+// api/prod/v1.3.2 creates yanked/api/prod/v1.3.2 next to it
+const yankPrefix = "yanked/"
+
+// includeYanked lists yanked versions along with the others
+var includeYanked bool
+
+// Function to tell whether a tag marks a yanked version
+func isYankMarker(tag string) bool {
+	return strings.HasPrefix(tag, yankPrefix)
+}
+
+// Function to mark a tag as yanked: an annotated tag of the original tag
+// object recording why, when and by whom. The tag itself stays, so builds
+// pinned to it keep working and its version is never handed out again.
+func createYankMarker(tag, reason string) (string, error) {
+	marker := yankPrefix + tag
+	if _, err := gitOutput("rev-parse", "--verify", "--quiet", "refs/tags/"+marker); err == nil {
+		return "", fmt.Errorf("%s is already yanked", tag)
+	}
+	commit, err := resolveCommit("refs/tags/" + tag)
+	if err != nil {
+		return "", err
+	}
+	name, _ := gitOutput("config", "user.name")
+	email, _ := gitOutput("config", "user.email")
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "Yanked %s\n\n", tag)
+	if reason != "" {
+		fmt.Fprintf(&message, "Reason: %s\n", reason)
+	}
+	fmt.Fprintf(&message, "Yanked-By: %s <%s>\n", name, email)
+	fmt.Fprintf(&message, "Yanked-At: %s\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(&message, "Commit: %s\n", commit)
+	if _, err := gitOutputWithInput(message.String(), "-c", "advice.nestedTag=false", "tag", "--annotate", "--cleanup=verbatim", "--file=-", marker, "refs/tags/"+tag); err != nil {
+		return "", err
+	}
+	return marker, nil
+}
+
+// yankRecord is a yanked tag with the reason it was yanked
+type yankRecord struct {
+	Tag    string
+	Date   string
+	By     string
+	Reason string
+}
+
+// Function to read the yanked tags, keyed by tag name
+func loadYanked() (map[string]yankRecord, error) {
+	out, err := gitOutput("for-each-ref", "--sort=-creatordate", "--format=%(refname:strip=2)%09%(creatordate:short)%09%(taggername)%09%(contents:body)", "refs/tags/"+strings.TrimSuffix(yankPrefix, "/"))
+	if err != nil {
+		return nil, err
+	}
+	yanked := make(map[string]yankRecord)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		r := yankRecord{Tag: strings.TrimPrefix(fields[0], yankPrefix), Date: fields[1], By: fields[2]}
+		for _, trailer := range strings.Split(fields[3], "\n") {
+			if value, ok := strings.CutPrefix(trailer, "Reason: "); ok {
+				r.Reason = value
+			}
+		}
+		yanked[r.Tag] = r
+	}
+	return yanked, nil
+}
+
+// Function to leave the yanked versions out of a tag index, for listings
+// pointing users at versions to use. Computing the next version keeps the
+// full index, so a yanked version is never tagged again.
+func withoutYanked(idx *tagIndex) (*tagIndex, error) {
+	if includeYanked {
+		return idx, nil
+	}
+	yanked, err := loadYanked()
+	if err != nil || len(yanked) == 0 {
+		return idx, err
+	}
+	entries, err := readTagEntries(func(string, string) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	released := newTagIndex()
+	for _, e := range entries {
+		if _, ok := yanked[e.Tag]; !ok {
+			released.add(e.Module, e.Channel, e.Version)
+		}
+	}
+	return released, nil
+}
+
+// Function to print the yanked tags with the reason they were yanked
+func listYanked() error {
+	yanked, err := loadYanked()
+	if err != nil {
+		return err
+	}
+	entries, err := readTagEntries(func(string, string) bool { return true })
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TAG\tYANKED\tBY\tREASON")
+	for i := len(entries) - 1; i >= 0; i-- {
+		if r, ok := yanked[entries[i].Tag]; ok {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Tag, r.Date, r.By, r.Reason)
+		}
+	}
+	return tw.Flush()
+}
+
+// Function to handle `version yank -m <module> -r <channel> <version>`,
+// retracting a released version so listings and queries skip it
+func runYank(args []string) int {
+	fs := flag.NewFlagSet("yank", flag.ExitOnError)
+	fs.StringVar(&moduleName, "m", "", "module name")
+	fs.StringVar(&releaseChannel, "r", "", "release channel")
+	reason := fs.String("reason", "", "why the version is yanked, such as \"bad migration\"")
+	undo := fs.Bool("undo", false, "restore a yanked version")
+	list := fs.Bool("list", false, "list the yanked tags")
+	push := fs.Bool("push", false, "push the change to the remote")
+	remote := fs.String("remote", "origin", "remote to push to")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: version yank -m <module> -r <channel> [flags] <version>")
+		fs.PrintDefaults()
+	}
+	// Allow the version before or after the flags
+	fs.Parse(args)
+	var versionArg string
+	if fs.NArg() > 0 {
+		versionArg = fs.Arg(0)
+		fs.Parse(fs.Args()[1:])
+	}
+	moduleName, releaseChannel = impliedNames(moduleName, releaseChannel)
+
+	if *list {
+		if err := listYanked(); err != nil {
+			log.Error().Err(err).Msg("unable to read tags")
+			return 1
+		}
+		return 0
+	}
+	if moduleName == "" || releaseChannel == "" || versionArg == "" {
+		fs.Usage()
+		return 2
+	}
+	if *undo && *reason != "" {
+		log.Error().Msg("--reason cannot be combined with --undo")
+		return 2
+	}
+	version, err := parseVersion(versionArg)
+	if err != nil {
+		log.Error().Err(err).Msg("invalid version")
+		return 2
+	}
+	tag := formatTag(moduleName, releaseChannel, version)
+	if _, err := gitOutput("rev-parse", "--verify", "--quiet", "refs/tags/"+tag); err != nil {
+		log.Error().Str("tag", tag).Msg("no such tag")
+		return 1
+	}
+	if err := checkWritable(); err != nil {
+		log.Error().Err(err).Msg("unable to yank")
+		return 1
+	}
+
+	marker := yankPrefix + tag
+	if *undo {
+		if _, err := gitOutput("tag", "--delete", marker); err != nil {
+			log.Error().Err(err).Str("tag", tag).Msg("tag is not yanked")
+			return 1
+		}
+		if *push {
+			if err := pushRefspec(pushTarget{Remote: *remote}, ":refs/tags/"+marker); err != nil {
+				log.Error().Err(err).Str("tag", marker).Str("remote", *remote).Msg("unable to delete remote yank marker")
+				return 1
+			}
+		}
+		log.Info().Str("tag", tag).Msg("Version restored")
+		return 0
+	}
+	if _, err := createYankMarker(tag, *reason); err != nil {
+		log.Error().Err(err).Str("tag", tag).Msg("unable to yank")
+		return 1
+	}
+	if *push {
+		if err := pushRefspec(pushTarget{Remote: *remote}, "refs/tags/"+marker+":refs/tags/"+marker); err != nil {
+			log.Error().Err(err).Str("tag", marker).Str("remote", *remote).Msg("unable to push yank marker")
+			return 1
+		}
+	}
+	log.Info().Str("tag", tag).Str("marker", marker).Msg("Version yanked")
+	return 0
+}